@@ -0,0 +1,463 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"monitor-profile-switcher/internal/switcher"
+)
+
+// options are the settings shared by the commands of one invocation.
+type options struct {
+	debug          bool
+	noIDMatch      bool
+	virtualInject  bool
+	plan           bool
+	strict         bool
+	monitors       bool
+	canonical      bool
+	names          bool
+	json           bool
+	strategies     []string
+	confirmTimeout time.Duration
+	waitTimeout    time.Duration
+	recordPath     string
+	replayPath     string
+	// warnings are problems with the command line that do not stop it.
+	warnings []string
+}
+
+type command struct {
+	kind string
+	args []string
+}
+
+// arg returns argument i, or "" when there are fewer.
+func (c command) arg(i int) string {
+	if i < len(c.args) {
+		return c.args[i]
+	}
+	return ""
+}
+
+// errHelp means help was printed and the invocation is done.
+var errHelp = errors.New("help printed")
+
+// subcommand describes one command of the subcommand form,
+// "monitor-switcher {name} [options] {args}".
+type subcommand struct {
+	name    string
+	args    string
+	summary string
+	// minArgs and maxArgs bound the number of arguments.
+	minArgs, maxArgs int
+	// options are the names in optionFlags the command accepts besides
+	// globalOptions.
+	options []string
+}
+
+func (s subcommand) usage() string {
+	return strings.TrimSpace("monitor-switcher " + s.name + " [options] " + s.args)
+}
+
+var subcommands = []subcommand{
+	{name: "save", args: "{file}", summary: "save the current monitor configuration to a profile", minArgs: 1, maxArgs: 1,
+		options: []string{"monitors", "canonical", "names"}},
+	{name: "load", args: "{file}", summary: "apply a saved profile", minArgs: 1, maxArgs: 1,
+		options: []string{"noidmatch", "v", "plan", "strategies", "strict", "wait", "confirm"}},
+	{name: "print", args: "[{file}]", summary: "print a summary of the current configuration, or of a saved profile", maxArgs: 1},
+	{name: "list", args: "[{dir}]", summary: "list the saved profiles", maxArgs: 1},
+	{name: "auto", args: "[{dir}]", summary: "apply the saved profile that best fits the connected monitors", maxArgs: 1,
+		options: []string{"noidmatch", "v", "plan", "strategies", "strict", "wait", "confirm"}},
+	{name: "status", args: "[{dir}]", summary: "show which saved profile matches the current layout", maxArgs: 1},
+	{name: "diff", args: "{a} [{b}]", summary: "compare two profiles, or a profile with the current configuration", minArgs: 1, maxArgs: 2},
+	{name: "fingerprint", summary: "print an ID for the set of connected monitors"},
+	{name: "validate", args: "{file}", summary: "check a profile's structure without applying it", minArgs: 1, maxArgs: 1},
+	{name: "hash", args: "{file}", summary: "print the content hash of a profile's layout", minArgs: 1, maxArgs: 1},
+	{name: "convert", args: "{in} [{out}]", summary: "convert a profile between encodings (by extension) or from MonitorSwitcher XML", minArgs: 1, maxArgs: 2},
+	{name: "migrate", args: "{file}", summary: "upgrade a profile to the current schema (keeps a .bak)", minArgs: 1, maxArgs: 1},
+	{name: "confirm", summary: "confirm a load that is waiting in another window"},
+	{name: "help", args: "[{command}]", summary: "show help for a command", maxArgs: 1},
+}
+
+// globalOptions are accepted by every command.
+var globalOptions = []string{"debug", "format", "json", "record", "replay"}
+
+// optionFlags defines each option on a command's flag set.
+var optionFlags = map[string]func(fs *flag.FlagSet, o *options){
+	"debug": func(fs *flag.FlagSet, o *options) {
+		fs.BoolVar(&o.debug, "debug", false, "print debug output")
+	},
+	"format": func(fs *flag.FlagSet, o *options) {
+		fs.Func("format", "print `text` (default) or json: one JSON document per command", func(value string) error {
+			switch strings.ToLower(value) {
+			case formatText:
+				o.json = false
+			case formatJSON:
+				o.json = true
+			default:
+				return fmt.Errorf("use json or text, not %q", value)
+			}
+			return nil
+		})
+	},
+	"json": func(fs *flag.FlagSet, o *options) {
+		fs.BoolVar(&o.json, "json", false, "same as -format json")
+	},
+	"record": func(fs *flag.FlagSet, o *options) {
+		fs.StringVar(&o.recordPath, "record", "", "write every display API call and result to `trace`")
+	},
+	"replay": func(fs *flag.FlagSet, o *options) {
+		fs.StringVar(&o.replayPath, "replay", "", "run against a recorded `trace` instead of the real displays")
+	},
+	"monitors": func(fs *flag.FlagSet, o *options) {
+		fs.BoolVar(&o.monitors, "monitors", false, "write the editable per-monitor format")
+	},
+	"canonical": func(fs *flag.FlagSet, o *options) {
+		fs.BoolVar(&o.canonical, "canonical", false, "write a diff-friendly profile (see README)")
+	},
+	"names": func(fs *flag.FlagSet, o *options) {
+		fs.BoolVar(&o.names, "names", false, "write rotation, scaling, flags and other enums by name")
+	},
+	"noidmatch": func(fs *flag.FlagSet, o *options) {
+		fs.BoolVar(&o.noIDMatch, "noidmatch", false, "disable matching of adapter IDs")
+	},
+	"v": func(fs *flag.FlagSet, o *options) {
+		fs.BoolVar(&o.virtualInject, "v", false, "enable virtual desktop injection (advanced)")
+	},
+	"plan": func(fs *flag.FlagSet, o *options) {
+		fs.BoolVar(&o.plan, "plan", false, "validate without applying and print the plan")
+	},
+	"strategies": func(fs *flag.FlagSet, o *options) {
+		fs.Func("strategies", "strategies to try, in order (e.g. identity,adapter-id)", func(value string) error {
+			names, err := switcher.ParseStrategies(value)
+			o.strategies = names
+			return err
+		})
+	},
+	"strict": func(fs *flag.FlagSet, o *options) {
+		fs.BoolVar(&o.strict, "strict", false, "exit with code 2 if Windows applied the profile differently")
+	},
+	"wait": func(fs *flag.FlagSet, o *options) {
+		fs.Func("wait", "wait up to `timeout` for the profile's monitors to connect", func(value string) error {
+			timeout, err := parseTimeout(value)
+			o.waitTimeout = timeout
+			return err
+		})
+	},
+	"confirm": func(fs *flag.FlagSet, o *options) {
+		fs.Func("confirm", "revert unless confirmed within `timeout` (e.g. 15s)", func(value string) error {
+			timeout, err := parseTimeout(value)
+			o.confirmTimeout = timeout
+			return err
+		})
+	},
+}
+
+func findSubcommand(name string) (subcommand, bool) {
+	for _, sub := range subcommands {
+		if sub.name == name {
+			return sub, true
+		}
+	}
+	return subcommand{}, false
+}
+
+// parseArgs reads either form of the command line. The subcommand form is
+// used when the first argument that is not an option names a command; the
+// options before it are read as that command's. Anything else is the
+// legacy -key:value form.
+//
+// A command name that is the only argument besides options and also names
+// an existing profile keeps its legacy meaning, so shortcuts written
+// before the subcommands existed still load their profile. A warning says
+// how to run the command instead.
+func parseArgs(args []string) (options, []command, error) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		sub, ok := findSubcommand(arg)
+		if !ok {
+			break
+		}
+		rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+		if path, ok := legacyProfile(arg, rest); ok {
+			o, commands, err := parseLegacy(args)
+			warning := fmt.Sprintf("%q is also a command; loading the profile %s", arg, path)
+			if sub.minArgs == 0 {
+				warning += fmt.Sprintf(" (use %s to run the command)", legacyFlag(sub))
+			}
+			o.warnings = append(o.warnings, warning)
+			return o, commands, err
+		}
+		return parseSubcommand(sub, rest)
+	}
+	return parseLegacy(args)
+}
+
+// legacyProfile returns the profile a bare name loads in the legacy form,
+// if it exists and rest holds nothing but options.
+func legacyProfile(name string, rest []string) (string, bool) {
+	for _, arg := range rest {
+		if !strings.HasPrefix(arg, "-") || arg == "--" {
+			return "", false
+		}
+	}
+	path, err := switcher.ResolveProfilePath(name, false)
+	if err != nil {
+		return "", false
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", false
+	}
+	return path, true
+}
+
+// legacyFlag is the legacy form of a command that takes no arguments.
+func legacyFlag(sub subcommand) string {
+	if sub.name == "help" {
+		return "-h"
+	}
+	return "-" + sub.name
+}
+
+func isHelpArg(arg string) bool {
+	switch arg {
+	case "-h", "-help", "--help", "-?", "/?":
+		return true
+	}
+	return false
+}
+
+// newFlagSet defines the options sub accepts on o.
+func newFlagSet(sub subcommand, o *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(sub.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, name := range append(append([]string{}, sub.options...), globalOptions...) {
+		optionFlags[name](fs, o)
+	}
+	return fs
+}
+
+// parseSubcommand reads the options and arguments of sub. Options may
+// come before, between or after the arguments; everything after "--" is an
+// argument.
+func parseSubcommand(sub subcommand, args []string) (options, []command, error) {
+	var o options
+	fs := newFlagSet(sub, &o)
+	args = colonToEquals(fs, args)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				printCommandHelp(os.Stdout, sub)
+				return o, nil, errHelp
+			}
+			return o, nil, fmt.Errorf("%s: %w (run 'monitor-switcher help %s' for usage)", sub.name, err, sub.name)
+		}
+		rest := fs.Args()
+		consumed := len(args) - len(rest)
+		if consumed > 0 && args[consumed-1] == "--" || len(rest) == 0 {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	if len(positional) < sub.minArgs || len(positional) > sub.maxArgs {
+		return o, nil, fmt.Errorf("%s: expected %s, got %d (usage: %s)", sub.name, argCount(sub), len(positional), sub.usage())
+	}
+	if sub.name == "help" {
+		if len(positional) == 0 {
+			printUsage(os.Stdout)
+			return o, nil, errHelp
+		}
+		topic, ok := findSubcommand(positional[0])
+		if !ok {
+			return o, nil, fmt.Errorf("help: unknown command %q", positional[0])
+		}
+		printCommandHelp(os.Stdout, topic)
+		return o, nil, errHelp
+	}
+	return o, []command{{kind: sub.name, args: positional}}, nil
+}
+
+// colonToEquals rewrites options given in the legacy -key:value form, such
+// as -format:json, to -key=value.
+func colonToEquals(fs *flag.FlagSet, args []string) []string {
+	out := make([]string, len(args))
+	copy(out, args)
+	for i, arg := range out {
+		if arg == "--" {
+			break
+		}
+		key, value, ok := strings.Cut(arg, ":")
+		if !ok || !strings.HasPrefix(key, "-") || strings.Contains(key, "=") {
+			continue
+		}
+		if fs.Lookup(strings.TrimLeft(key, "-")) != nil {
+			out[i] = key + "=" + value
+		}
+	}
+	return out
+}
+
+func argCount(sub subcommand) string {
+	switch {
+	case sub.maxArgs == 0:
+		return "no arguments"
+	case sub.minArgs == sub.maxArgs && sub.minArgs == 1:
+		return "1 argument"
+	case sub.minArgs == sub.maxArgs:
+		return fmt.Sprintf("%d arguments", sub.minArgs)
+	}
+	return fmt.Sprintf("%d to %d arguments", sub.minArgs, sub.maxArgs)
+}
+
+// Whether a legacy command flag takes a value.
+const (
+	valueNone = iota
+	valueOptional
+	valueRequired
+)
+
+// legacyCommands are the -key:value flags that run a command.
+var legacyCommands = map[string]int{
+	"-save": valueRequired, "-load": valueRequired, "-print": valueOptional,
+	"-auto": valueOptional, "-diff": valueRequired, "-status": valueOptional, "-list": valueOptional,
+	"-fingerprint": valueNone, "-migrate": valueRequired, "-hash": valueRequired,
+	"-validate": valueRequired, "-convert": valueRequired,
+}
+
+// legacySwitches are the legacy flags that take no value.
+var legacySwitches = map[string]func(o *options){
+	"-debug":     func(o *options) { o.debug = true },
+	"-noidmatch": func(o *options) { o.noIDMatch = true },
+	"-v":         func(o *options) { o.virtualInject = true },
+	"-monitors":  func(o *options) { o.monitors = true },
+	"-canonical": func(o *options) { o.canonical = true },
+	"-names":     func(o *options) { o.names = true },
+	"-json":      func(o *options) { o.json = true },
+	"-strict":    func(o *options) { o.strict = true },
+	"-plan":      func(o *options) { o.plan = true },
+}
+
+// parseLegacy reads the original command line: -key:value flags, applied
+// in order, where a bare file name means -load:{file}. Unknown flags and
+// missing or unexpected values are errors.
+func parseLegacy(args []string) (options, []command, error) {
+	var o options
+	var commands []command
+	for i, arg := range args {
+		if isHelpArg(arg) {
+			printUsage(os.Stdout)
+			return o, nil, errHelp
+		}
+		if arg == "--" {
+			for _, file := range args[i+1:] {
+				commands = append(commands, command{kind: "load", args: []string{file}})
+			}
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			commands = append(commands, command{kind: "load", args: []string{arg}})
+			continue
+		}
+		key, value := splitArg(arg)
+		key = strings.ToLower(key)
+		if arity, ok := legacyCommands[key]; ok {
+			switch {
+			case arity == valueRequired && value == "":
+				return o, nil, fmt.Errorf("%s needs a value, e.g. %s:{file}", key, key)
+			case arity == valueNone && value != "":
+				return o, nil, fmt.Errorf("%s does not take a value", key)
+			}
+			commands = append(commands, command{kind: strings.TrimPrefix(key, "-"), args: legacyArgs(key, value)})
+			continue
+		}
+		if set, ok := legacySwitches[key]; ok {
+			if value != "" {
+				return o, nil, fmt.Errorf("%s does not take a value", key)
+			}
+			set(&o)
+			continue
+		}
+		if key == "-confirm" && value == "" {
+			commands = append(commands, command{kind: "confirm"})
+			continue
+		}
+
+		if value == "" {
+			switch key {
+			case "-format", "-strategies", "-wait", "-record", "-replay":
+				return o, nil, fmt.Errorf("%s needs a value", key)
+			}
+		}
+		var err error
+		switch key {
+		case "-format":
+			switch strings.ToLower(value) {
+			case formatText:
+				o.json = false
+			case formatJSON:
+				o.json = true
+			default:
+				err = fmt.Errorf("use json or text, not %q", value)
+			}
+		case "-strategies":
+			o.strategies, err = switcher.ParseStrategies(value)
+		case "-confirm":
+			o.confirmTimeout, err = parseTimeout(value)
+		case "-wait":
+			o.waitTimeout, err = parseTimeout(value)
+		case "-record":
+			o.recordPath = value
+		case "-replay":
+			o.replayPath = value
+		default:
+			return o, nil, fmt.Errorf("unknown option %s (run 'monitor-switcher help' for usage)", arg)
+		}
+		if err != nil {
+			return o, nil, fmt.Errorf("invalid %s argument: %w", key, err)
+		}
+	}
+	return o, commands, nil
+}
+
+// legacyArgs splits the value of a legacy command flag into arguments;
+// -diff and -convert take two separated by a comma.
+func legacyArgs(key, value string) []string {
+	if value == "" {
+		return nil
+	}
+	if key == "-diff" || key == "-convert" {
+		if a, b, ok := strings.Cut(value, ","); ok {
+			return []string{a, b}
+		}
+	}
+	return []string{value}
+}
+
+// printCommandHelp prints the usage and options of sub.
+func printCommandHelp(w io.Writer, sub subcommand) {
+	fmt.Fprintf(w, "Usage: %s\n\n", sub.usage())
+	fmt.Fprintf(w, "%s%s.\n", strings.ToUpper(sub.summary[:1]), sub.summary[1:])
+	if sub.name == "help" {
+		return
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Options:")
+	fs := newFlagSet(sub, &options{})
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
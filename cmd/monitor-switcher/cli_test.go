@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// profileHome points the profile directory at a temporary home holding
+// the named profiles.
+func profileHome(t *testing.T, names ...string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	dir := filepath.Join(home, "Monitor Profiles")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name+".monitorprofile"), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// commandList formats commands as "kind arg,arg" for comparison.
+func commandList(commands []command) []string {
+	var out []string
+	for _, cmd := range commands {
+		out = append(out, strings.TrimSpace(cmd.kind+" "+strings.Join(cmd.args, ",")))
+	}
+	return out
+}
+
+func TestParseArgs(t *testing.T) {
+	profileHome(t)
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		check   func(o options) bool
+		wantErr string
+	}{
+		{name: "nothing"},
+		{name: "subcommand", args: []string{"load", "Home"}, want: []string{"load Home"}},
+		{
+			name:  "options around the arguments",
+			args:  []string{"-debug", "load", "Home", "-wait", "30s", "-strict"},
+			want:  []string{"load Home"},
+			check: func(o options) bool { return o.debug && o.strict && o.waitTimeout == 30*time.Second },
+		},
+		{
+			name:  "colon form in a subcommand",
+			args:  []string{"status", "-format:json"},
+			want:  []string{"status"},
+			check: func(o options) bool { return o.json },
+		},
+		{name: "everything after -- is an argument", args: []string{"load", "--", "-odd"}, want: []string{"load -odd"}},
+		{name: "option of another command", args: []string{"save", "Home", "-strict"}, wantErr: "save: flag provided but not defined: -strict"},
+		{name: "too many arguments", args: []string{"load", "a", "b"}, wantErr: "load: expected 1 argument, got 2"},
+		{name: "missing argument", args: []string{"save"}, wantErr: "save: expected 1 argument, got 0"},
+		{
+			name:  "legacy",
+			args:  []string{"-save:Home", "-monitors", "-load:Work", "Desk", "-diff:a,b", "-list"},
+			want:  []string{"save Home", "load Work", "load Desk", "diff a,b", "list"},
+			check: func(o options) bool { return o.monitors },
+		},
+		{
+			name: "legacy -- loads the rest",
+			args: []string{"-debug", "--", "-odd", "list"},
+			want: []string{"load -odd", "load list"},
+		},
+		{name: "legacy command without its value", args: []string{"-load"}, wantErr: "-load needs a value"},
+		{name: "legacy switch with a value", args: []string{"-strict:yes"}, wantErr: "-strict does not take a value"},
+		{name: "unknown legacy option", args: []string{"-bogus"}, wantErr: "unknown option -bogus"},
+		{name: "invalid timeout", args: []string{"-wait:soon", "Home"}, wantErr: "invalid -wait argument"},
+		{name: "command name without a profile", args: []string{"list"}, want: []string{"list"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, commands, err := parseArgs(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseArgs(%q) = %v, want error containing %q", tt.args, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseArgs(%q) = %v", tt.args, err)
+			}
+			if got := commandList(commands); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("commands = %q, want %q", got, tt.want)
+			}
+			if tt.check != nil && !tt.check(o) {
+				t.Errorf("options = %+v", o)
+			}
+			if len(o.warnings) != 0 {
+				t.Errorf("warnings = %q", o.warnings)
+			}
+		})
+	}
+}
+
+func TestParseArgsCommandNamedProfile(t *testing.T) {
+	dir := profileHome(t, "status", "list", "load")
+	tests := []struct {
+		name string
+		args []string
+		want []string
+		// wantWarning is part of the warning, or "" for none.
+		wantWarning string
+	}{
+		{
+			name:        "bare name loads the profile",
+			args:        []string{"status"},
+			want:        []string{"load status"},
+			wantWarning: `"status" is also a command; loading the profile ` + filepath.Join(dir, "status.monitorprofile") + " (use -status to run the command)",
+		},
+		{
+			name:        "with legacy options",
+			args:        []string{"-debug", "list"},
+			want:        []string{"load list"},
+			wantWarning: "(use -list to run the command)",
+		},
+		{
+			name:        "command that needs arguments",
+			args:        []string{"load"},
+			want:        []string{"load load"},
+			wantWarning: `"load" is also a command; loading the profile`,
+		},
+		{name: "command with arguments", args: []string{"status", dir}, want: []string{"status " + dir}},
+		{name: "other profile", args: []string{"print"}, want: []string{"print"}},
+		{name: "explicit load", args: []string{"-load:status"}, want: []string{"load status"}},
+		{name: "legacy flag", args: []string{"-status"}, want: []string{"status"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, commands, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseArgs(%q) = %v", tt.args, err)
+			}
+			if got := commandList(commands); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("commands = %q, want %q", got, tt.want)
+			}
+			switch {
+			case tt.wantWarning == "" && len(o.warnings) != 0:
+				t.Errorf("warnings = %q, want none", o.warnings)
+			case tt.wantWarning != "" && (len(o.warnings) != 1 || !strings.Contains(o.warnings[0], tt.wantWarning)):
+				t.Errorf("warnings = %q, want one containing %q", o.warnings, tt.wantWarning)
+			}
+		})
+	}
+}
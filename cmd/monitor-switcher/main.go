@@ -0,0 +1,650 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdtrace"
+	"monitor-profile-switcher/internal/confirm"
+	"monitor-profile-switcher/internal/profile"
+	"monitor-profile-switcher/internal/switcher"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	// exitDrift means the profile was applied but -strict found differences.
+	exitDrift = 2
+	// exitAccessDenied means Windows refused display changes from this
+	// session (ERROR_ACCESS_DENIED).
+	exitAccessDenied = 3
+	// exitNotSupported means the display driver does not support the CCD
+	// API (ERROR_NOT_SUPPORTED).
+	exitNotSupported = 4
+	// exitRejected means the configuration itself was rejected
+	// (ERROR_INVALID_PARAMETER, ERROR_GEN_FAILURE, ERROR_BAD_CONFIGURATION).
+	exitRejected = 5
+	// exitWaitTimeout means -wait expired before the profile's monitors
+	// were connected; nothing was applied.
+	exitWaitTimeout = 6
+	// exitNoMatch means -auto found no profile whose monitors are all
+	// connected, or -status found no profile matching the current layout.
+	exitNoMatch = 7
+	// exitAmbiguous means -auto found several equally good profiles.
+	exitAmbiguous = 8
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	opts, commands, err := parseArgs(args)
+	if errors.Is(err, errHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	for _, warning := range opts.warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+
+	out := &output{json: opts.json}
+	// info receives messages meant for a person, which must not mix with
+	// the JSON on stdout.
+	info := io.Writer(os.Stdout)
+	if out.json {
+		info = os.Stderr
+	}
+	if opts.debug {
+		fmt.Fprintln(info, "Debug output enabled")
+		if opts.noIDMatch {
+			fmt.Fprintln(info, "Disabled matching of adapter IDs")
+		}
+		if opts.virtualInject {
+			fmt.Fprintln(info, "Enabled virtual desktop injection")
+		}
+		if opts.plan {
+			fmt.Fprintln(info, "Plan mode: profiles are validated, not applied")
+		}
+	}
+
+	if len(commands) == 0 {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+
+	if offlineOnly(commands) {
+		return runCommands(nil, commands, switcher.SaveOptions{}, switcher.LoadOptions{}, out)
+	}
+
+	backend := ccd.System
+	var replayer *ccdtrace.Replayer
+	if opts.replayPath != "" {
+		trace, err := ccdtrace.Load(opts.replayPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid replay argument:", err)
+			return exitFailure
+		}
+		replayer = ccdtrace.NewReplayer(trace)
+		backend = replayer
+		if opts.debug {
+			fmt.Fprintf(info, "Replaying %d recorded CCD calls from: %s\n", len(trace.Calls), opts.replayPath)
+		}
+		if len(trace.Profiles) > 0 {
+			dir, err := os.MkdirTemp("", "monitor-switcher-replay-")
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Replay failed:", err)
+				return exitFailure
+			}
+			defer os.RemoveAll(dir)
+			replaced, err := replayProfiles(trace, commands, dir)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Replay failed:", err)
+				return exitFailure
+			}
+			if opts.debug {
+				for _, arg := range replaced {
+					fmt.Fprintf(info, "Replay: using the profile %s recorded in the trace\n", arg)
+				}
+			}
+		}
+	} else if runtime.GOOS != "windows" {
+		fmt.Fprintln(info, "monitor-switcher is supported on Windows only (use -replay:{trace} to run against a recorded trace).")
+		return exitFailure
+	}
+
+	var recorder *ccdtrace.Recorder
+	if opts.recordPath != "" {
+		recorder = ccdtrace.NewRecorder(backend, args)
+		backend = recorder
+		for _, cmd := range commands {
+			arg, ok := profileArg(cmd)
+			if !ok {
+				continue
+			}
+			// A profile that cannot be read fails its command instead.
+			if path, err := switcher.ResolveProfilePath(arg, false); err == nil {
+				_ = recorder.AddProfile(arg, path)
+			}
+		}
+	}
+
+	loadOpts := switcher.LoadOptions{
+		Debug:         opts.debug,
+		NoIDMatch:     opts.noIDMatch,
+		VirtualInject: opts.virtualInject,
+		Plan:          opts.plan,
+		Wait:          opts.waitTimeout,
+		Confirm:       opts.confirmTimeout,
+		Strict:        opts.strict,
+		Strategies:    opts.strategies,
+	}
+	saveOpts := switcher.SaveOptions{
+		Debug:     opts.debug,
+		Monitors:  opts.monitors,
+		Canonical: opts.canonical,
+		Names:     opts.names,
+	}
+	if out.json {
+		loadOpts.Output = os.Stderr
+		loadOpts.Confirmer = confirm.Waiter{Input: os.Stdin, Output: os.Stderr, Dir: os.TempDir()}
+		loadOpts.DebugOutput, loadOpts.Warnings = os.Stderr, out.warn
+		saveOpts.DebugOutput, saveOpts.Warnings = os.Stderr, out.warn
+	}
+	code := runCommands(switcher.New(backend), commands, saveOpts, loadOpts, out)
+
+	if replayer != nil && opts.debug {
+		for _, call := range replayer.Unmatched() {
+			fmt.Fprintf(info, "Replay: %s (flags 0x%X) was not in the trace\n", call.API, call.Flags)
+		}
+	}
+	if recorder != nil {
+		if err := ccdtrace.Save(opts.recordPath, recorder.Trace()); err != nil {
+			fmt.Fprintln(os.Stderr, "Record failed:", err)
+			if code == exitOK {
+				code = exitFailure
+			}
+		} else if opts.debug {
+			fmt.Fprintln(info, "CCD trace written to:", opts.recordPath)
+		}
+	}
+	return code
+}
+
+func runCommands(sw *switcher.Switcher, commands []command, saveOpts switcher.SaveOptions, loadOpts switcher.LoadOptions, out *output) int {
+	for _, cmd := range commands {
+		out.begin(cmd.kind)
+		code := runCommand(sw, cmd, saveOpts, loadOpts, out)
+		out.end(code)
+		if code != exitOK {
+			return code
+		}
+	}
+	return exitOK
+}
+
+func runCommand(sw *switcher.Switcher, cmd command, saveOpts switcher.SaveOptions, loadOpts switcher.LoadOptions, out *output) int {
+	switch cmd.kind {
+	case "save":
+		path, err := switcher.ResolveProfilePath(cmd.arg(0), true)
+		if err != nil {
+			return out.fail("Invalid save argument:", err)
+		}
+		out.doc.Profile = path
+		if err := sw.SaveProfile(path, saveOpts); err != nil {
+			return out.fail("Save failed:", err)
+		}
+		if out.json {
+			if out.doc.Monitors, err = switcher.ProfileMonitors(path); err != nil {
+				return out.fail("Save failed:", err)
+			}
+		}
+	case "load":
+		path, err := switcher.ResolveProfilePath(cmd.arg(0), false)
+		if err != nil {
+			return out.fail("Invalid load argument:", err)
+		}
+		out.doc.Profile = path
+		report, err := sw.LoadProfileWithReport(path, loadOpts)
+		out.doc.Result = report
+		if err != nil {
+			return out.fail("Load failed:", err)
+		}
+		return out.currentMonitors(sw, "Load failed:")
+	case "confirm":
+		if err := confirm.Accept(os.TempDir()); err != nil {
+			return out.fail("Confirm failed:", err)
+		}
+		out.println("Display settings confirmed.")
+	case "migrate":
+		path, err := switcher.ResolveProfilePath(cmd.arg(0), false)
+		if err != nil {
+			return out.fail("Invalid migrate argument:", err)
+		}
+		out.doc.Profile = path
+		version, backup, err := profile.Migrate(path)
+		if err != nil {
+			return out.fail("Migrate failed:", err)
+		}
+		out.doc.Result = migrateResult{From: version, To: profile.SchemaVersion, Backup: backup}
+		if backup == "" {
+			out.printf("%s is already at schema version %d\n", path, version)
+		} else {
+			out.printf("Migrated %s from schema version %d to %d (original kept as %s)\n", path, version, profile.SchemaVersion, backup)
+		}
+	case "hash":
+		path, err := switcher.ResolveProfilePath(cmd.arg(0), false)
+		if err != nil {
+			return out.fail("Invalid hash argument:", err)
+		}
+		out.doc.Profile = path
+		prof, err := profile.Load(path)
+		if err != nil {
+			return out.fail("Hash failed:", err)
+		}
+		hash := profile.LayoutHash(prof)
+		out.doc.Result = hashResult{LayoutHash: hash}
+		out.println(hash)
+	case "validate":
+		path, err := switcher.ResolveProfilePath(cmd.arg(0), false)
+		if err != nil {
+			return out.fail("Invalid validate argument:", err)
+		}
+		out.doc.Profile = path
+		prof, err := profile.Load(path)
+		if err != nil {
+			return out.fail("Validate failed:", err)
+		}
+		issues := profile.Validate(prof)
+		out.doc.Result = validateResult{Issues: append([]profile.Issue{}, issues...)}
+		if len(issues) == 0 {
+			out.printf("%s is valid\n", path)
+			return exitOK
+		}
+		for _, issue := range issues {
+			out.println(issue)
+		}
+		message := fmt.Sprintf("%s has %d issue(s)", path, len(issues))
+		if out.json {
+			out.doc.Error = message
+		} else {
+			fmt.Fprintln(os.Stderr, message)
+		}
+		return exitFailure
+	case "convert":
+		in, outPath, err := convertPaths(cmd.args)
+		if err != nil {
+			return out.fail("Invalid convert argument:", err)
+		}
+		out.doc.Profile = outPath
+		if err := profile.Convert(in, outPath); err != nil {
+			return out.fail("Convert failed:", err)
+		}
+		out.doc.Result = convertResult{In: in, Out: outPath}
+		out.printf("Converted %s to %s\n", in, outPath)
+	case "auto":
+		dir := cmd.arg(0)
+		if dir == "" {
+			var err error
+			if dir, err = switcher.ProfileDir(); err != nil {
+				return out.fail("Invalid auto argument:", err)
+			}
+		}
+		result, err := sw.AutoLoad(dir, loadOpts)
+		out.doc.Profile = result.Applied
+		out.doc.Result = result
+		if err != nil {
+			return out.fail("Auto failed:", err)
+		}
+		return out.currentMonitors(sw, "Auto failed:")
+	case "diff":
+		a, b, err := diffPaths(cmd.args)
+		if err != nil {
+			return out.fail("Invalid diff argument:", err)
+		}
+		out.doc.Profile = a
+		var diff switcher.ProfileDiff
+		if b == "" {
+			diff, err = sw.DiffCurrent(a)
+		} else {
+			diff, err = switcher.DiffProfiles(a, b)
+		}
+		if err != nil {
+			return out.fail("Diff failed:", err)
+		}
+		out.doc.Result = diff
+		if !out.json {
+			if err := diff.Write(os.Stdout); err != nil {
+				return out.fail("Diff failed:", err)
+			}
+		}
+	case "status":
+		dir := cmd.arg(0)
+		if dir == "" {
+			var err error
+			if dir, err = switcher.ProfileDir(); err != nil {
+				return out.fail("Invalid status argument:", err)
+			}
+		}
+		report, err := sw.Status(dir)
+		if err != nil {
+			return out.fail("Status failed:", err)
+		}
+		out.doc.Result = report
+		if match, ok := report.Match(); ok {
+			out.doc.Profile = match.Path
+		}
+		if !out.json {
+			if err := report.Write(os.Stdout); err != nil {
+				return out.fail("Status failed:", err)
+			}
+		}
+		if code := out.currentMonitors(sw, "Status failed:"); code != exitOK {
+			return code
+		}
+		if _, ok := report.Match(); !ok {
+			return exitNoMatch
+		}
+	case "list":
+		dir := cmd.arg(0)
+		if dir == "" {
+			var err error
+			if dir, err = switcher.ProfileDir(); err != nil {
+				return out.fail("Invalid list argument:", err)
+			}
+		}
+		paths, err := switcher.ListProfiles(dir)
+		if err != nil {
+			return out.fail("List failed:", err)
+		}
+		out.doc.Result = listResult{Dir: dir, Profiles: append([]string{}, paths...)}
+		if len(paths) == 0 {
+			out.printf("No profiles in %s\n", dir)
+		}
+		for _, path := range paths {
+			out.println(filepath.Base(path))
+		}
+	case "fingerprint":
+		fp, err := sw.Fingerprint()
+		if err != nil {
+			return out.fail("Fingerprint failed:", err)
+		}
+		out.doc.Result = fp
+		out.println(fp.ID)
+		if saveOpts.Debug {
+			for _, monitor := range fp.Monitors {
+				out.println("  " + monitor)
+			}
+		}
+	case "print":
+		if len(cmd.args) == 0 {
+			if !out.json {
+				if err := sw.PrintSummary(os.Stdout); err != nil {
+					return out.fail("Print failed:", err)
+				}
+				return exitOK
+			}
+			return out.currentMonitors(sw, "Print failed:")
+		}
+		path, err := switcher.ResolveProfilePath(cmd.arg(0), false)
+		if err != nil {
+			return out.fail("Invalid print argument:", err)
+		}
+		out.doc.Profile = path
+		if out.json {
+			if out.doc.Monitors, err = switcher.ProfileMonitors(path); err != nil {
+				return out.fail("Print failed:", err)
+			}
+			return exitOK
+		}
+		summary, err := switcher.SummarizeProfile(path)
+		if err != nil {
+			return out.fail("Print failed:", err)
+		}
+		out.printf("%s", summary)
+	}
+	return exitOK
+}
+
+// reportFailure prints err with the usual causes of its Win32 code and
+// returns the exit code for its class.
+func reportFailure(prefix string, err error) int {
+	fmt.Fprintln(os.Stderr, prefix, err)
+	var code ccd.Errno
+	if errors.As(err, &code) && code.Explanation() != "" {
+		fmt.Fprintf(os.Stderr, "%v: %s\n", code, code.Explanation())
+	}
+	return exitCodeFor(err)
+}
+
+// explain returns the usual causes of err's Win32 code, or "".
+func explain(err error) string {
+	var code ccd.Errno
+	if !errors.As(err, &code) {
+		return ""
+	}
+	return code.Explanation()
+}
+
+// exitCodeFor returns the exit code for err's class.
+func exitCodeFor(err error) int {
+	var drift *switcher.DriftError
+	if errors.As(err, &drift) {
+		return exitDrift
+	}
+	if errors.Is(err, switcher.ErrWaitTimeout) {
+		return exitWaitTimeout
+	}
+	if errors.Is(err, switcher.ErrNoProfileMatch) {
+		return exitNoMatch
+	}
+	if errors.Is(err, switcher.ErrAmbiguousProfile) {
+		return exitAmbiguous
+	}
+	var code ccd.Errno
+	if !errors.As(err, &code) {
+		return exitFailure
+	}
+	switch code {
+	case ccd.ErrAccessDenied:
+		return exitAccessDenied
+	case ccd.ErrNotSupported:
+		return exitNotSupported
+	case ccd.ErrInvalidParameter, ccd.ErrGenFailure, ccd.ErrBadConfiguration:
+		return exitRejected
+	}
+	return exitFailure
+}
+
+// offlineCommands only work on files or other processes and never touch the
+// displays.
+var offlineCommands = map[string]bool{
+	"confirm":  true,
+	"migrate":  true,
+	"convert":  true,
+	"validate": true,
+	"hash":     true,
+	"list":     true,
+}
+
+// offlineOnly reports whether no command touches the displays, so they can
+// run on any OS. Printing a file and diffing two files are offline too.
+func offlineOnly(commands []command) bool {
+	for _, cmd := range commands {
+		if cmd.kind == "print" && len(cmd.args) > 0 || cmd.kind == "diff" && len(cmd.args) == 2 {
+			continue
+		}
+		if !offlineCommands[cmd.kind] {
+			return false
+		}
+	}
+	return true
+}
+
+// profileArg returns the argument naming the profile a command reads while
+// it drives the displays; traces embed that profile.
+func profileArg(cmd command) (string, bool) {
+	if cmd.kind == "load" || cmd.kind == "diff" && len(cmd.args) == 1 {
+		return cmd.args[0], true
+	}
+	return "", false
+}
+
+// replayProfiles writes the profiles embedded in a trace to dir and points
+// the commands that read them there, so a replay sends the recorded input
+// whatever the local files hold. It returns the arguments it replaced;
+// commands naming a profile the trace does not have read the local file.
+func replayProfiles(trace ccdtrace.Trace, commands []command, dir string) ([]string, error) {
+	var replaced []string
+	for i, cmd := range commands {
+		arg, ok := profileArg(cmd)
+		if !ok {
+			continue
+		}
+		p, ok := trace.Profile(arg)
+		if !ok {
+			continue
+		}
+		// One directory per command keeps the recorded file name, whose
+		// extension selects the encoding.
+		path := filepath.Join(dir, strconv.Itoa(i), filepath.Base(p.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return replaced, fmt.Errorf("write recorded profile: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(p.Data), 0644); err != nil {
+			return replaced, fmt.Errorf("write recorded profile: %w", err)
+		}
+		commands[i].args[0] = path
+		replaced = append(replaced, arg)
+	}
+	return replaced, nil
+}
+
+// convertPaths resolves the {in} [{out}] arguments of convert. Without
+// {out} the input's extension is replaced with .monitorprofile.
+func convertPaths(args []string) (string, string, error) {
+	in, err := switcher.ResolveProfilePath(args[0], false)
+	if err != nil {
+		return "", "", err
+	}
+	if len(args) < 2 {
+		out := strings.TrimSuffix(in, filepath.Ext(in)) + ".monitorprofile"
+		if out == in {
+			return "", "", fmt.Errorf("%s would be overwritten; name an output file", in)
+		}
+		return in, out, nil
+	}
+	out, err := switcher.ResolveProfilePath(args[1], true)
+	if err != nil {
+		return "", "", err
+	}
+	return in, out, nil
+}
+
+// diffPaths resolves the {a} [{b}] arguments of diff. Without {b} the
+// second path is empty and {a} is compared with the current configuration.
+func diffPaths(args []string) (string, string, error) {
+	a, err := switcher.ResolveProfilePath(args[0], false)
+	if err != nil || len(args) < 2 {
+		return a, "", err
+	}
+	b, err := switcher.ResolveProfilePath(args[1], false)
+	if err != nil {
+		return "", "", err
+	}
+	return a, b, nil
+}
+
+// parseTimeout accepts a Go duration ("15s", "1m") or a number of seconds.
+func parseTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		value = strconv.Itoa(seconds) + "s"
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be positive: %s", value)
+	}
+	return timeout, nil
+}
+
+func splitArg(arg string) (string, string) {
+	parts := strings.SplitN(arg, ":", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Monitor Profile Switcher (Go CLI)")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  monitor-switcher {command} [options] [arguments]")
+	fmt.Fprintln(w, "  monitor-switcher [-key:value ...] [{file} ...]   (legacy form, see below)")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Commands:")
+	for _, sub := range subcommands {
+		fmt.Fprintf(w, "  %-22s%s\n", strings.TrimSpace(sub.name+" "+sub.args), sub.summary)
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Run 'monitor-switcher help {command}' or 'monitor-switcher {command} -h' for its options.")
+	fmt.Fprintln(w, "Options go before or after the arguments; everything after -- is an argument.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Legacy parameters (a bare {file} is the same as -load:{file}; a bare command name")
+	fmt.Fprintln(w, "loads the profile of that name when one exists and nothing else is given):")
+	fmt.Fprintln(w, "  -save:{file}        save current monitor configuration to file")
+	fmt.Fprintln(w, "  -load:{file}        load and apply monitor configuration from file")
+	fmt.Fprintln(w, "  -monitors           with -save, write the editable per-monitor format")
+	fmt.Fprintln(w, "  -canonical          with -save, write a diff-friendly profile (see README)")
+	fmt.Fprintln(w, "  -names              with -save, write rotation, scaling, flags and other enums by name")
+	fmt.Fprintln(w, "  -debug              enable debug output")
+	fmt.Fprintln(w, "  -format:{format}    print text (default) or json: one JSON document per command")
+	fmt.Fprintln(w, "  -json               same as -format:json")
+	fmt.Fprintln(w, "  -noidmatch          disable matching of adapter IDs")
+	fmt.Fprintln(w, "  -v                  enable virtual desktop injection (advanced)")
+	fmt.Fprintln(w, "  -print[:{file}]     print a summary of the current configuration, or of a saved profile")
+	fmt.Fprintln(w, "  -auto[:{dir}]       apply the saved profile that best fits the connected monitors")
+	fmt.Fprintln(w, "  -diff:{a}[,{b}]     compare two profiles, or a profile with the current configuration")
+	fmt.Fprintln(w, "  -status[:{dir}]     show which saved profile matches the current layout")
+	fmt.Fprintln(w, "  -list[:{dir}]       list the saved profiles")
+	fmt.Fprintln(w, "  -fingerprint        print an ID for the set of connected monitors")
+	fmt.Fprintln(w, "  -plan               validate -load without applying and print the plan")
+	fmt.Fprintln(w, "  -wait:{timeout}     wait for the profile's monitors to connect before -load")
+	fmt.Fprintln(w, "  -confirm:{timeout}  revert -load unless confirmed within the timeout (e.g. 15s)")
+	fmt.Fprintln(w, "  -strategies:{list}  strategies -load tries, in order (e.g. identity,adapter-id)")
+	fmt.Fprintln(w, "  -strict             exit with code 2 if Windows applied -load differently")
+	fmt.Fprintln(w, "  -confirm            confirm a -load that is waiting in another window")
+	fmt.Fprintln(w, "  -migrate:{file}     upgrade a profile to the current schema (keeps a .bak)")
+	fmt.Fprintln(w, "  -hash:{file}        print the content hash of a profile's layout")
+	fmt.Fprintln(w, "  -validate:{file}    check a profile's structure without applying it")
+	fmt.Fprintln(w, "  -convert:{in}[,{out}] convert a profile between encodings (by extension) or from MonitorSwitcher XML")
+	fmt.Fprintln(w, "  -record:{trace}     write every display API call and result to a trace file")
+	fmt.Fprintln(w, "  -replay:{trace}     run against a recorded trace instead of the real displays")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "If {file} is a filename (no path), it is stored under:")
+	fmt.Fprintf(w, "  %%USERPROFILE%%\\Monitor Profiles\n")
+	fmt.Fprintln(w, "If {file} has no extension, .monitorprofile is added.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  monitor-switcher.exe save Profile.json")
+	fmt.Fprintln(w, "  monitor-switcher.exe load -confirm 15s Profile.json")
+	fmt.Fprintln(w, "  monitor-switcher.exe load -plan -debug Profile.json")
+	fmt.Fprintln(w, "  monitor-switcher.exe -save:Profile.json")
+	fmt.Fprintln(w, "  monitor-switcher.exe -debug -load:Profile.json")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdtrace"
+	"monitor-profile-switcher/internal/switcher"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("open profile: file not found"), exitFailure},
+		{&switcher.DriftError{Changes: []string{"refresh"}}, exitDrift},
+		{fmt.Errorf("load: %w", switcher.ErrWaitTimeout), exitWaitTimeout},
+		{switcher.ErrNoProfileMatch, exitNoMatch},
+		{fmt.Errorf("%w: a and b", switcher.ErrAmbiguousProfile), exitAmbiguous},
+		{fmt.Errorf("every strategy failed, last: %w", ccd.ErrAccessDenied), exitAccessDenied},
+		{ccd.ErrNotSupported, exitNotSupported},
+		{ccd.ErrInvalidParameter, exitRejected},
+		{ccd.ErrBadConfiguration, exitRejected},
+		{fmt.Errorf("every strategy failed, last: %w", ccd.ErrGenFailure), exitRejected},
+		{fmt.Errorf("every strategy failed, last: %w", ccd.ErrNotRecorded), exitFailure},
+		{ccd.Errno(1234), exitFailure},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.err); got != tt.want {
+			t.Errorf("exitCodeFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestReplayProfiles(t *testing.T) {
+	trace := ccdtrace.Trace{Profiles: []ccdtrace.Profile{
+		{Arg: "Home", Name: "Home.monitorprofile", Data: `{"pathInfo": []}`},
+		{Arg: "desk.yaml", Name: "../desk.yaml", Data: "monitors: []\n"},
+	}}
+	commands := []command{
+		{kind: "load", args: []string{"Home"}},
+		{kind: "diff", args: []string{"desk.yaml"}},
+		{kind: "diff", args: []string{"Home", "desk.yaml"}},
+		{kind: "load", args: []string{"Work"}},
+		{kind: "validate", args: []string{"Home"}},
+	}
+	dir := t.TempDir()
+	replaced, err := replayProfiles(trace, commands, dir)
+	if err != nil {
+		t.Fatalf("replayProfiles() = %v", err)
+	}
+	if fmt.Sprint(replaced) != "[Home desk.yaml]" {
+		t.Errorf("replaced %q, want Home and desk.yaml", replaced)
+	}
+	for i, want := range trace.Profiles {
+		path := commands[i].args[0]
+		if filepath.Dir(filepath.Dir(path)) != dir || filepath.Base(path) != filepath.Base(want.Name) {
+			t.Errorf("command %d reads %s, want %s in %s", i, path, filepath.Base(want.Name), dir)
+			continue
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != want.Data {
+			t.Errorf("%s = %q, %v; want the recorded profile", path, data, err)
+		}
+	}
+	// Only commands that drive the displays read recorded profiles.
+	if got := commandList(commands[2:]); fmt.Sprint(got) != "[diff Home,desk.yaml load Work validate Home]" {
+		t.Errorf("other commands = %q, want them unchanged", got)
+	}
+}
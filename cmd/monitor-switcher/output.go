@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"monitor-profile-switcher/internal/profile"
+	"monitor-profile-switcher/internal/switcher"
+)
+
+// Output formats selected with -format.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// output prints what commands report: text as it goes, or with -format:json
+// one switcher.Document per command on stdout. In JSON mode everything
+// meant for a person (debug lines, prompts, progress) goes to stderr.
+type output struct {
+	json bool
+	doc  switcher.Document
+}
+
+func (o *output) begin(kind string) {
+	o.doc = switcher.Document{Version: switcher.DocumentVersion, Command: kind}
+}
+
+// end prints the document of the finished command in JSON mode.
+func (o *output) end(code int) {
+	if !o.json {
+		return
+	}
+	o.doc.ExitCode = code
+	o.doc.OK = code == exitOK
+	if err := o.doc.Write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func (o *output) warn(message string) {
+	o.doc.Warnings = append(o.doc.Warnings, message)
+}
+
+// printf and println write text output; the document carries the same
+// information in JSON mode.
+func (o *output) printf(format string, args ...any) {
+	if !o.json {
+		fmt.Printf(format, args...)
+	}
+}
+
+func (o *output) println(args ...any) {
+	if !o.json {
+		fmt.Println(args...)
+	}
+}
+
+// fail reports err, to stderr or in the document, and returns its exit
+// code.
+func (o *output) fail(prefix string, err error) int {
+	if !o.json {
+		return reportFailure(prefix, err)
+	}
+	o.doc.Error = prefix + " " + err.Error()
+	if explanation := explain(err); explanation != "" {
+		o.doc.Error += " (" + explanation + ")"
+	}
+	return exitCodeFor(err)
+}
+
+// currentMonitors adds the active configuration to the document.
+func (o *output) currentMonitors(sw *switcher.Switcher, prefix string) int {
+	if !o.json {
+		return exitOK
+	}
+	monitors, err := sw.CurrentMonitors()
+	if err != nil {
+		return o.fail(prefix, err)
+	}
+	o.doc.Monitors = monitors
+	return exitOK
+}
+
+// Results of commands that have no type of their own in switcher.
+type (
+	migrateResult struct {
+		From   int    `json:"from"`
+		To     int    `json:"to"`
+		Backup string `json:"backup,omitempty"`
+	}
+	hashResult struct {
+		LayoutHash string `json:"layoutHash"`
+	}
+	validateResult struct {
+		Issues []profile.Issue `json:"issues"`
+	}
+	convertResult struct {
+		In  string `json:"in"`
+		Out string `json:"out"`
+	}
+	listResult struct {
+		Dir      string   `json:"dir"`
+		Profiles []string `json:"profiles"`
+	}
+)
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+	"monitor-profile-switcher/internal/switcher"
+)
+
+// captureStdout returns what run writes to os.Stdout.
+func captureStdout(t *testing.T, run func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = saved }()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	run()
+	w.Close()
+	return <-done
+}
+
+// documents decodes the JSON documents in data, one per command, keeping
+// the raw fields so absent and null values can be told apart.
+func documents(t *testing.T, data []byte) []map[string]json.RawMessage {
+	t.Helper()
+	var docs []map[string]json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var doc map[string]json.RawMessage
+		if err := decoder.Decode(&doc); err != nil {
+			t.Fatalf("decode output: %v\n%s", err, data)
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// checkDocument compares the common fields of doc with want, given as
+// compact JSON; error only has to start with its wanted value. A field
+// missing from want must be absent from doc.
+func checkDocument(t *testing.T, doc map[string]json.RawMessage, want map[string]string) {
+	t.Helper()
+	for _, field := range []string{"version", "command", "ok", "exitCode", "error", "warnings", "profile"} {
+		raw, ok := doc[field]
+		wantValue, wantOK := want[field]
+		if ok != wantOK {
+			t.Errorf("%s present: %v, want %v", field, ok, wantOK)
+			continue
+		}
+		var got bytes.Buffer
+		if ok && json.Compact(&got, raw) != nil {
+			t.Errorf("%s = %s, not JSON", field, raw)
+			continue
+		}
+		if field == "error" && !strings.HasPrefix(got.String(), wantValue) || field != "error" && got.String() != wantValue {
+			t.Errorf("%s = %s, want %s", field, got.String(), wantValue)
+		}
+	}
+}
+
+// dockMachine returns a machine with two DELL monitors side by side on
+// targets 100 and 200.
+func dockMachine() *ccdsim.Machine {
+	adapter := ccd.LUID{LowPart: 1}
+	var targets []ccdsim.Target
+	for i, serial := range []string{"UID4352", "UID4353"} {
+		targets = append(targets, ccdsim.Target{ID: uint32(i+1) * 100, Present: true, OutputTechnology: ccd.DisplayConfigVideoOutputTechnologyDisplayPortExt, Monitor: ccdsim.Monitor{
+			ManufactureID: 0x10AC,
+			ProductCodeID: 0x4123,
+			FriendlyName:  "DELL P2419H",
+			DevicePath:    `\\?\DISPLAY#DEL4123#5&1a&0&` + serial + `#{e6f07b5f-ee97-4a90-b076-33f57bf4eaa7}`,
+		}})
+	}
+	m := ccdsim.New(ccdsim.Adapter{ID: adapter, Sources: []uint32{0, 1}, Targets: targets})
+	for i, target := range targets {
+		var path ccd.DisplayConfigPathInfo
+		path.Flags = uint32(ccd.DisplayConfigFlagPathActive)
+		path.SourceInfo = ccd.DisplayConfigPathSourceInfo{AdapterID: adapter, ID: uint32(i)}
+		path.TargetInfo.AdapterID, path.TargetInfo.ID = adapter, target.ID
+		path.TargetInfo.Rotation = ccd.DisplayConfigRotationIdentity
+		path.TargetInfo.Scaling = ccd.DisplayConfigScalingPreferred
+		path.TargetInfo.RefreshRate = ccd.DisplayConfigRational{Numerator: 60, Denominator: 1}
+		var mode ccd.DisplayConfigTargetMode
+		mode.TargetVideoSignalInfo.ActiveSize = ccd.DisplayConfig2DRegion{Cx: 1920, Cy: 1080}
+		mode.TargetVideoSignalInfo.TotalSize = mode.TargetVideoSignalInfo.ActiveSize
+		mode.TargetVideoSignalInfo.VSyncFreq = path.TargetInfo.RefreshRate
+		m.Activate(ccdsim.ActivePath{
+			Path:   path,
+			Source: ccd.DisplayConfigSourceMode{Width: 1920, Height: 1080, PixelFormat: ccd.DisplayConfigPixelFormat32Bpp, Position: ccd.PointL{X: int32(i) * 1920}},
+			Target: mode,
+		})
+	}
+	return m
+}
+
+func TestJSONDocumentOffline(t *testing.T) {
+	dir := profileHome(t, "Home")
+	var code int
+	data := captureStdout(t, func() {
+		code = run([]string{"-format:json", "-list", "-hash:Missing", "-list"})
+	})
+	if code != exitFailure {
+		t.Errorf("run() = %d, want %d", code, exitFailure)
+	}
+	docs := documents(t, data)
+	if len(docs) != 2 {
+		t.Fatalf("%d documents, want one per command up to the failure:\n%s", len(docs), data)
+	}
+	checkDocument(t, docs[0], map[string]string{
+		"version": "1", "command": `"list"`, "ok": "true", "exitCode": "0", "warnings": "[]",
+	})
+	var list listResult
+	if err := json.Unmarshal(docs[0]["result"], &list); err != nil || list.Dir != dir || len(list.Profiles) != 1 {
+		t.Errorf("list result = %s, want the Home profile in %s", docs[0]["result"], dir)
+	}
+	missing, _ := json.Marshal(filepath.Join(dir, "Missing.monitorprofile"))
+	checkDocument(t, docs[1], map[string]string{
+		"version": "1", "command": `"hash"`, "ok": "false", "exitCode": "1", "warnings": "[]",
+		"profile": string(missing), "error": `"Hash failed: read profile:`,
+	})
+	if _, ok := docs[1]["result"]; ok {
+		t.Errorf("failed hash has a result: %s", docs[1]["result"])
+	}
+}
+
+func TestJSONDocumentLoad(t *testing.T) {
+	m := dockMachine()
+	path := filepath.Join(t.TempDir(), "dock.monitorprofile")
+	if err := switcher.New(m).SaveProfile(path, switcher.SaveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	load := func(m *ccdsim.Machine) (int, []map[string]json.RawMessage) {
+		t.Helper()
+		out := &output{json: true}
+		loadOpts := switcher.LoadOptions{Output: io.Discard, DebugOutput: io.Discard, Warnings: out.warn}
+		var code int
+		data := captureStdout(t, func() {
+			code = runCommands(switcher.New(m), []command{{kind: "load", args: []string{path}}}, switcher.SaveOptions{}, loadOpts, out)
+		})
+		return code, documents(t, data)
+	}
+	quoted, _ := json.Marshal(path)
+
+	// The second monitor was unplugged since the save.
+	m.SetPresent(ccd.LUID{LowPart: 1}, 200, false)
+	code, docs := load(m)
+	if code != exitOK || len(docs) != 1 {
+		t.Fatalf("load = %d with %d documents, want %d with one", code, len(docs), exitOK)
+	}
+	checkDocument(t, docs[0], map[string]string{
+		"version": "1", "command": `"load"`, "ok": "true", "exitCode": "0", "profile": string(quoted),
+		"warnings": `["monitor DELL P2419H (target id 200) is not connected; skipping it"]`,
+	})
+	if _, ok := docs[0]["monitors"]; !ok {
+		t.Error("successful load has no monitors")
+	}
+
+	m = dockMachine()
+	m.FailSetDisplayConfig(ccd.ErrorAccessDenied)
+	code, docs = load(m)
+	if code != exitAccessDenied || len(docs) != 1 {
+		t.Fatalf("load = %d with %d documents, want %d with one", code, len(docs), exitAccessDenied)
+	}
+	checkDocument(t, docs[0], map[string]string{
+		"version": "1", "command": `"load"`, "ok": "false", "exitCode": "3", "profile": string(quoted), "warnings": "[]",
+		"error": `"Load failed:`,
+	})
+	if explanation := ccd.ErrAccessDenied.Explanation(); !strings.Contains(string(docs[0]["error"]), explanation) {
+		t.Errorf("error = %s, want the explanation %q", docs[0]["error"], explanation)
+	}
+	if _, ok := docs[0]["result"]; !ok {
+		t.Error("failed load has no report")
+	}
+}
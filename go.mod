@@ -2,4 +2,8 @@ module monitor-profile-switcher
 
 go 1.24.3
 
-require golang.org/x/sys v0.40.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/sys v0.40.0
+	gopkg.in/yaml.v3 v3.0.1
+)
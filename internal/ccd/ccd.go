@@ -0,0 +1,129 @@
+package ccd
+
+import (
+	"unsafe"
+)
+
+// Win32 status codes returned by the CCD entry points.
+const (
+	ErrorSuccess            uint32 = 0
+	ErrorAccessDenied       uint32 = 5
+	ErrorGenFailure         uint32 = 31
+	ErrorNotSupported       uint32 = 50
+	ErrorInvalidParameter   uint32 = 87
+	ErrorInsufficientBuffer uint32 = 122
+	ErrorBadConfiguration   uint32 = 1610
+)
+
+// DisplayBackend is the set of user32 display configuration functions the
+// tool depends on. Each method mirrors its Win32 counterpart and returns the
+// raw status code. System calls into user32; other implementations simulate
+// or replay a machine so the switcher logic can run anywhere.
+type DisplayBackend interface {
+	GetDisplayConfigBufferSizes(flags QueryDisplayFlags, numPaths *uint32, numModes *uint32) uint32
+	QueryDisplayConfig(flags QueryDisplayFlags, numPaths *uint32, paths []DisplayConfigPathInfo, numModes *uint32, modes []DisplayConfigModeInfo) uint32
+	DisplayConfigGetDeviceInfo(request *DisplayConfigDeviceInfoHeader) uint32
+	SetDisplayConfig(paths []DisplayConfigPathInfo, modes []DisplayConfigModeInfo, flags SdcFlags) uint32
+}
+
+// System is the backend for the running machine.
+var System DisplayBackend = systemBackend{}
+
+func SetDisplayConfig(backend DisplayBackend, paths []DisplayConfigPathInfo, modes []DisplayConfigModeInfo, flags SdcFlags) error {
+	if r1 := backend.SetDisplayConfig(paths, modes, flags); r1 != ErrorSuccess {
+		return callError("SetDisplayConfig", r1)
+	}
+	return nil
+}
+
+func GetDisplaySettings(backend DisplayBackend, activeOnly bool) ([]DisplayConfigPathInfo, []DisplayConfigModeInfo, []MonitorAdditionalInfo, error) {
+	flags := QueryDisplayFlagsAllPaths
+	if activeOnly {
+		flags = QueryDisplayFlagsOnlyActivePaths
+	}
+	return GetDisplaySettingsWithFlags(backend, flags)
+}
+
+// queryAttempts bounds how often GetDisplaySettingsWithFlags sizes and
+// queries the buffers when the configuration changes in between.
+const queryAttempts = 3
+
+func GetDisplaySettingsWithFlags(backend DisplayBackend, flags QueryDisplayFlags) ([]DisplayConfigPathInfo, []DisplayConfigModeInfo, []MonitorAdditionalInfo, error) {
+	var pathInfo []DisplayConfigPathInfo
+	var modeInfo []DisplayConfigModeInfo
+	for attempt := 1; ; attempt++ {
+		var numPaths uint32
+		var numModes uint32
+		if r1 := backend.GetDisplayConfigBufferSizes(flags, &numPaths, &numModes); r1 != ErrorSuccess {
+			return nil, nil, nil, callError("GetDisplayConfigBufferSizes", r1)
+		}
+
+		pathInfo = make([]DisplayConfigPathInfo, numPaths)
+		modeInfo = make([]DisplayConfigModeInfo, numModes)
+		r1 := backend.QueryDisplayConfig(flags, &numPaths, pathInfo, &numModes, modeInfo)
+		if r1 == ErrorSuccess {
+			pathInfo = pathInfo[:numPaths]
+			modeInfo = modeInfo[:numModes]
+			break
+		}
+		// ERROR_INSUFFICIENT_BUFFER means paths or modes were added after
+		// the buffers were sized; size them again.
+		if r1 != ErrorInsufficientBuffer || attempt == queryAttempts {
+			return nil, nil, nil, callError("QueryDisplayConfig", r1)
+		}
+	}
+
+	filteredModes := make([]DisplayConfigModeInfo, 0, len(modeInfo))
+	for _, mode := range modeInfo {
+		if mode.InfoType != DisplayConfigModeInfoTypeZero {
+			filteredModes = append(filteredModes, mode)
+		}
+	}
+	modeInfo = filteredModes
+
+	filteredPaths := make([]DisplayConfigPathInfo, 0, len(pathInfo))
+	for _, path := range pathInfo {
+		if path.TargetInfo.TargetAvailable != 0 {
+			filteredPaths = append(filteredPaths, path)
+		}
+	}
+	pathInfo = filteredPaths
+
+	additional := make([]MonitorAdditionalInfo, len(modeInfo))
+	for i := range modeInfo {
+		if modeInfo[i].InfoType == DisplayConfigModeInfoTypeTarget {
+			info, err := GetMonitorAdditionalInfo(backend, modeInfo[i].AdapterID, modeInfo[i].ID)
+			if err == nil {
+				additional[i] = info
+			} else {
+				additional[i] = MonitorAdditionalInfo{Valid: false}
+			}
+		}
+	}
+
+	return pathInfo, modeInfo, additional, nil
+}
+
+func GetMonitorAdditionalInfo(backend DisplayBackend, adapterID LUID, targetID uint32) (MonitorAdditionalInfo, error) {
+	var result MonitorAdditionalInfo
+
+	deviceName := DisplayConfigTargetDeviceName{}
+	deviceName.Header.Type = DisplayConfigDeviceInfoTypeGetTargetName
+	deviceName.Header.Size = uint32(unsafe.Sizeof(deviceName))
+	deviceName.Header.AdapterID = adapterID
+	deviceName.Header.ID = targetID
+
+	if r1 := backend.DisplayConfigGetDeviceInfo(&deviceName.Header); r1 != ErrorSuccess {
+		return result, callError("DisplayConfigGetDeviceInfo", r1)
+	}
+
+	result.Valid = true
+	result.ManufactureID = deviceName.EdidManufactureID
+	result.ProductCodeID = deviceName.EdidProductCodeID
+	result.MonitorDevicePath = deviceName.DevicePath()
+	result.MonitorFriendlyDevice = deviceName.FriendlyName()
+	result.ConnectorInstance = deviceName.ConnectorInstance
+	result.OutputTechnology = deviceName.OutputTechnology
+
+	return result, nil
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package ccd
+
+// systemBackend reports ERROR_NOT_SUPPORTED for every call on platforms
+// without the Windows display configuration APIs.
+type systemBackend struct{}
+
+func (systemBackend) SetDisplayConfig(paths []DisplayConfigPathInfo, modes []DisplayConfigModeInfo, flags SdcFlags) uint32 {
+	return ErrorNotSupported
+}
+
+func (systemBackend) DisplayConfigGetDeviceInfo(request *DisplayConfigDeviceInfoHeader) uint32 {
+	return ErrorNotSupported
+}
+
+func (systemBackend) GetDisplayConfigBufferSizes(flags QueryDisplayFlags, numPaths *uint32, numModes *uint32) uint32 {
+	return ErrorNotSupported
+}
+
+func (systemBackend) QueryDisplayConfig(flags QueryDisplayFlags, numPaths *uint32, paths []DisplayConfigPathInfo, numModes *uint32, modes []DisplayConfigModeInfo) uint32 {
+	return ErrorNotSupported
+}
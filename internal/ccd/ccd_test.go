@@ -0,0 +1,76 @@
+package ccd
+
+import (
+	"errors"
+	"testing"
+)
+
+// changingBackend answers the first queries with ERROR_INSUFFICIENT_BUFFER,
+// as Windows does when monitors are connected between sizing the buffers
+// and querying them, and then reports one available path.
+type changingBackend struct {
+	changes int
+	// queryStatus replaces ERROR_INSUFFICIENT_BUFFER when set.
+	queryStatus uint32
+	sized       int
+	queried     int
+}
+
+func (b *changingBackend) GetDisplayConfigBufferSizes(flags QueryDisplayFlags, numPaths *uint32, numModes *uint32) uint32 {
+	b.sized++
+	*numPaths, *numModes = 1, 0
+	return ErrorSuccess
+}
+
+func (b *changingBackend) QueryDisplayConfig(flags QueryDisplayFlags, numPaths *uint32, paths []DisplayConfigPathInfo, numModes *uint32, modes []DisplayConfigModeInfo) uint32 {
+	b.queried++
+	if b.queried <= b.changes {
+		if b.queryStatus != ErrorSuccess {
+			return b.queryStatus
+		}
+		return ErrorInsufficientBuffer
+	}
+	paths[0].TargetInfo.ID = 100
+	paths[0].TargetInfo.TargetAvailable = 1
+	*numPaths, *numModes = 1, 0
+	return ErrorSuccess
+}
+
+func (b *changingBackend) DisplayConfigGetDeviceInfo(request *DisplayConfigDeviceInfoHeader) uint32 {
+	return ErrorNotSupported
+}
+
+func (b *changingBackend) SetDisplayConfig(paths []DisplayConfigPathInfo, modes []DisplayConfigModeInfo, flags SdcFlags) uint32 {
+	return ErrorNotSupported
+}
+
+func TestGetDisplaySettingsRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		backend     changingBackend
+		wantErr     error
+		wantQueries int
+	}{
+		{name: "unchanged", wantQueries: 1},
+		{name: "changed once", backend: changingBackend{changes: 1}, wantQueries: 2},
+		{name: "settles on the last attempt", backend: changingBackend{changes: queryAttempts - 1}, wantQueries: queryAttempts},
+		{name: "keeps changing", backend: changingBackend{changes: queryAttempts}, wantErr: ErrInsufficientBuffer, wantQueries: queryAttempts},
+		{name: "other errors are not retried", backend: changingBackend{changes: 1, queryStatus: ErrorGenFailure}, wantErr: ErrGenFailure, wantQueries: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := tt.backend
+			paths, _, _, err := GetDisplaySettings(&backend, true)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetDisplaySettings() = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || len(paths) != 1 || paths[0].TargetInfo.ID != 100 {
+				t.Errorf("GetDisplaySettings() = %+v, %v; want the path to target 100", paths, err)
+			}
+			if backend.queried != tt.wantQueries || backend.sized != tt.wantQueries {
+				t.Errorf("sized %d and queried %d times, want %d", backend.sized, backend.queried, tt.wantQueries)
+			}
+		})
+	}
+}
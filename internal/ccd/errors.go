@@ -0,0 +1,79 @@
+package ccd
+
+import "fmt"
+
+// Errno is a Win32 status code returned by a CCD call. The sentinels below
+// can be matched with errors.Is against any error returned by this package.
+type Errno uint32
+
+// customerBit marks a status code as application-defined; no Win32 code
+// has it set.
+const customerBit = 1 << 29
+
+// ErrorNotRecorded is not a Win32 code: a backend replaying a recorded trace
+// returns it for a call the trace has no answer for.
+const ErrorNotRecorded uint32 = customerBit | 1
+
+const (
+	ErrAccessDenied       = Errno(ErrorAccessDenied)
+	ErrGenFailure         = Errno(ErrorGenFailure)
+	ErrNotSupported       = Errno(ErrorNotSupported)
+	ErrInvalidParameter   = Errno(ErrorInvalidParameter)
+	ErrInsufficientBuffer = Errno(ErrorInsufficientBuffer)
+	ErrBadConfiguration   = Errno(ErrorBadConfiguration)
+	ErrNotRecorded        = Errno(ErrorNotRecorded)
+)
+
+var errnoNames = map[Errno]string{
+	ErrAccessDenied:       "ERROR_ACCESS_DENIED",
+	ErrGenFailure:         "ERROR_GEN_FAILURE",
+	ErrNotSupported:       "ERROR_NOT_SUPPORTED",
+	ErrInvalidParameter:   "ERROR_INVALID_PARAMETER",
+	ErrInsufficientBuffer: "ERROR_INSUFFICIENT_BUFFER",
+	ErrBadConfiguration:   "ERROR_BAD_CONFIGURATION",
+	ErrNotRecorded:        "NOT_RECORDED",
+}
+
+var errnoExplanations = map[Errno]string{
+	ErrAccessDenied:       "the caller is not on the interactive console desktop (locked workstation, service, or a remote session without display access)",
+	ErrGenFailure:         "the display driver rejected the request, usually because a mode (resolution, refresh rate or pixel format) is not supported by the monitor or adapter",
+	ErrNotSupported:       "the display driver does not support the CCD API (for example a non-WDDM or basic display driver), or the call is not available on this system",
+	ErrInvalidParameter:   "the paths and modes do not describe a valid configuration for the connected hardware: an adapter ID, source or target ID, mode index or flag combination is wrong",
+	ErrInsufficientBuffer: "the display configuration kept changing between sizing and querying the buffers, even after querying again (monitors being connected, or a driver resetting); retry once it settles",
+	ErrBadConfiguration:   "the topology cannot be set up on this machine, typically because a target in the profile is not connected or cannot be driven by the requested source",
+	ErrNotRecorded:        "the replayed trace has no answer for this call, so the run took a different path than the recorded one (-debug lists the calls)",
+}
+
+func (e Errno) Error() string {
+	name, ok := errnoNames[e]
+	switch {
+	case !ok:
+		return fmt.Sprintf("error %d", uint32(e))
+	case uint32(e)&customerBit != 0:
+		return name
+	}
+	return fmt.Sprintf("%s (%d)", name, uint32(e))
+}
+
+// Explanation describes the usual causes of the code, or "" when unknown.
+func (e Errno) Explanation() string {
+	return errnoExplanations[e]
+}
+
+// CallError is a CCD call that returned a status other than ERROR_SUCCESS.
+type CallError struct {
+	API  string
+	Code Errno
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.API, e.Code)
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Code
+}
+
+func callError(api string, code uint32) error {
+	return &CallError{API: api, Code: Errno(code)}
+}
@@ -0,0 +1,39 @@
+package ccd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrno(t *testing.T) {
+	tests := []struct {
+		code        uint32
+		want        string
+		explanation bool
+	}{
+		{ErrorAccessDenied, "ERROR_ACCESS_DENIED (5)", true},
+		{ErrorGenFailure, "ERROR_GEN_FAILURE (31)", true},
+		{ErrorNotSupported, "ERROR_NOT_SUPPORTED (50)", true},
+		{ErrorInvalidParameter, "ERROR_INVALID_PARAMETER (87)", true},
+		{ErrorInsufficientBuffer, "ERROR_INSUFFICIENT_BUFFER (122)", true},
+		{ErrorBadConfiguration, "ERROR_BAD_CONFIGURATION (1610)", true},
+		{ErrorNotRecorded, "NOT_RECORDED", true},
+		{1234, "error 1234", false},
+	}
+	for _, tt := range tests {
+		err := callError("SetDisplayConfig", tt.code)
+		if got := err.Error(); got != "SetDisplayConfig failed: "+tt.want {
+			t.Errorf("callError(%d) = %q, want %q", tt.code, got, "SetDisplayConfig failed: "+tt.want)
+		}
+		if !errors.Is(fmt.Errorf("load: %w", err), Errno(tt.code)) {
+			t.Errorf("wrapped callError(%d) does not match Errno(%d)", tt.code, tt.code)
+		}
+		if got := Errno(tt.code).Explanation() != ""; got != tt.explanation {
+			t.Errorf("Errno(%d).Explanation() present = %v, want %v", tt.code, got, tt.explanation)
+		}
+	}
+	if errors.Is(callError("SetDisplayConfig", ErrorGenFailure), ErrInvalidParameter) {
+		t.Error("ERROR_GEN_FAILURE matches ErrInvalidParameter")
+	}
+}
@@ -0,0 +1,82 @@
+package ccd
+
+import "unsafe"
+
+// The structures in this package are passed straight to user32, so their
+// sizes and field offsets must match the Windows SDK (wingdi.h) on every
+// architecture. Each pair below fails to compile (negative array length)
+// if the Go layout drifts from the C layout.
+const (
+	sizeofLUID                   = 8
+	sizeofPathSourceInfo         = 20
+	sizeofPathTargetInfo         = 48
+	sizeofPathInfo               = 72
+	sizeofModeInfo               = 64
+	sizeofVideoSignalInfo        = 48
+	sizeofSourceMode             = 20
+	sizeofDesktopImageInfo       = 40
+	sizeofDeviceInfoHeader       = 20
+	sizeofTargetDeviceName       = 420
+	offsetofModeInfoAdapterID    = 8
+	offsetofModeInfoUnion        = 16
+	offsetofPathTargetInfo       = 20
+	offsetofPathFlags            = 68
+	offsetofTargetRefreshRate    = 28
+	offsetofTargetAvailable      = 40
+	offsetofSignalVideoStandard  = 40
+	offsetofSourceModePosition   = 12
+	offsetofTargetNameFriendly   = 36
+	offsetofTargetNameDevicePath = 164
+)
+
+var (
+	_ [unsafe.Sizeof(LUID{}) - sizeofLUID]struct{}
+	_ [sizeofLUID - unsafe.Sizeof(LUID{})]struct{}
+
+	_ [unsafe.Sizeof(DisplayConfigPathSourceInfo{}) - sizeofPathSourceInfo]struct{}
+	_ [sizeofPathSourceInfo - unsafe.Sizeof(DisplayConfigPathSourceInfo{})]struct{}
+	_ [unsafe.Sizeof(DisplayConfigPathTargetInfo{}) - sizeofPathTargetInfo]struct{}
+	_ [sizeofPathTargetInfo - unsafe.Sizeof(DisplayConfigPathTargetInfo{})]struct{}
+	_ [unsafe.Sizeof(DisplayConfigPathInfo{}) - sizeofPathInfo]struct{}
+	_ [sizeofPathInfo - unsafe.Sizeof(DisplayConfigPathInfo{})]struct{}
+
+	_ [unsafe.Sizeof(DisplayConfigModeInfo{}) - sizeofModeInfo]struct{}
+	_ [sizeofModeInfo - unsafe.Sizeof(DisplayConfigModeInfo{})]struct{}
+	_ [unsafe.Sizeof(DisplayConfigVideoSignalInfo{}) - sizeofVideoSignalInfo]struct{}
+	_ [sizeofVideoSignalInfo - unsafe.Sizeof(DisplayConfigVideoSignalInfo{})]struct{}
+	_ [unsafe.Sizeof(DisplayConfigSourceMode{}) - sizeofSourceMode]struct{}
+	_ [sizeofSourceMode - unsafe.Sizeof(DisplayConfigSourceMode{})]struct{}
+	_ [unsafe.Sizeof(DisplayConfigDesktopImageInfo{}) - sizeofDesktopImageInfo]struct{}
+	_ [sizeofDesktopImageInfo - unsafe.Sizeof(DisplayConfigDesktopImageInfo{})]struct{}
+
+	// Every member of the mode union must fit in the reserved 48 bytes.
+	_ [displayConfigModeInfoUnionSize - unsafe.Sizeof(DisplayConfigTargetMode{})]struct{}
+	_ [displayConfigModeInfoUnionSize - unsafe.Sizeof(DisplayConfigSourceMode{})]struct{}
+	_ [displayConfigModeInfoUnionSize - unsafe.Sizeof(DisplayConfigDesktopImageInfo{})]struct{}
+
+	_ [unsafe.Sizeof(DisplayConfigDeviceInfoHeader{}) - sizeofDeviceInfoHeader]struct{}
+	_ [sizeofDeviceInfoHeader - unsafe.Sizeof(DisplayConfigDeviceInfoHeader{})]struct{}
+	_ [unsafe.Sizeof(DisplayConfigTargetDeviceName{}) - sizeofTargetDeviceName]struct{}
+	_ [sizeofTargetDeviceName - unsafe.Sizeof(DisplayConfigTargetDeviceName{})]struct{}
+
+	_ [unsafe.Offsetof(DisplayConfigModeInfo{}.AdapterID) - offsetofModeInfoAdapterID]struct{}
+	_ [offsetofModeInfoAdapterID - unsafe.Offsetof(DisplayConfigModeInfo{}.AdapterID)]struct{}
+	_ [unsafe.Offsetof(DisplayConfigModeInfo{}.Mode) - offsetofModeInfoUnion]struct{}
+	_ [offsetofModeInfoUnion - unsafe.Offsetof(DisplayConfigModeInfo{}.Mode)]struct{}
+	_ [unsafe.Offsetof(DisplayConfigPathInfo{}.TargetInfo) - offsetofPathTargetInfo]struct{}
+	_ [offsetofPathTargetInfo - unsafe.Offsetof(DisplayConfigPathInfo{}.TargetInfo)]struct{}
+	_ [unsafe.Offsetof(DisplayConfigPathInfo{}.Flags) - offsetofPathFlags]struct{}
+	_ [offsetofPathFlags - unsafe.Offsetof(DisplayConfigPathInfo{}.Flags)]struct{}
+	_ [unsafe.Offsetof(DisplayConfigPathTargetInfo{}.RefreshRate) - offsetofTargetRefreshRate]struct{}
+	_ [offsetofTargetRefreshRate - unsafe.Offsetof(DisplayConfigPathTargetInfo{}.RefreshRate)]struct{}
+	_ [unsafe.Offsetof(DisplayConfigPathTargetInfo{}.TargetAvailable) - offsetofTargetAvailable]struct{}
+	_ [offsetofTargetAvailable - unsafe.Offsetof(DisplayConfigPathTargetInfo{}.TargetAvailable)]struct{}
+	_ [unsafe.Offsetof(DisplayConfigVideoSignalInfo{}.VideoStandard) - offsetofSignalVideoStandard]struct{}
+	_ [offsetofSignalVideoStandard - unsafe.Offsetof(DisplayConfigVideoSignalInfo{}.VideoStandard)]struct{}
+	_ [unsafe.Offsetof(DisplayConfigSourceMode{}.Position) - offsetofSourceModePosition]struct{}
+	_ [offsetofSourceModePosition - unsafe.Offsetof(DisplayConfigSourceMode{}.Position)]struct{}
+	_ [unsafe.Offsetof(DisplayConfigTargetDeviceName{}.MonitorFriendlyDeviceName) - offsetofTargetNameFriendly]struct{}
+	_ [offsetofTargetNameFriendly - unsafe.Offsetof(DisplayConfigTargetDeviceName{}.MonitorFriendlyDeviceName)]struct{}
+	_ [unsafe.Offsetof(DisplayConfigTargetDeviceName{}.MonitorDevicePath) - offsetofTargetNameDevicePath]struct{}
+	_ [offsetofTargetNameDevicePath - unsafe.Offsetof(DisplayConfigTargetDeviceName{}.MonitorDevicePath)]struct{}
+)
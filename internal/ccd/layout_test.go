@@ -0,0 +1,143 @@
+package ccd
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestLayoutMatchesSDK(t *testing.T) {
+	tests := []struct {
+		name string
+		got  uintptr
+		want uintptr
+	}{
+		{"sizeof LUID", unsafe.Sizeof(LUID{}), 8},
+		{"sizeof DISPLAYCONFIG_PATH_SOURCE_INFO", unsafe.Sizeof(DisplayConfigPathSourceInfo{}), 20},
+		{"sizeof DISPLAYCONFIG_PATH_TARGET_INFO", unsafe.Sizeof(DisplayConfigPathTargetInfo{}), 48},
+		{"sizeof DISPLAYCONFIG_PATH_INFO", unsafe.Sizeof(DisplayConfigPathInfo{}), 72},
+		{"sizeof DISPLAYCONFIG_MODE_INFO", unsafe.Sizeof(DisplayConfigModeInfo{}), 64},
+		{"sizeof DISPLAYCONFIG_VIDEO_SIGNAL_INFO", unsafe.Sizeof(DisplayConfigVideoSignalInfo{}), 48},
+		{"sizeof DISPLAYCONFIG_TARGET_MODE", unsafe.Sizeof(DisplayConfigTargetMode{}), 48},
+		{"sizeof DISPLAYCONFIG_SOURCE_MODE", unsafe.Sizeof(DisplayConfigSourceMode{}), 20},
+		{"sizeof DISPLAYCONFIG_DESKTOP_IMAGE_INFO", unsafe.Sizeof(DisplayConfigDesktopImageInfo{}), 40},
+		{"sizeof DISPLAYCONFIG_DEVICE_INFO_HEADER", unsafe.Sizeof(DisplayConfigDeviceInfoHeader{}), 20},
+		{"sizeof DISPLAYCONFIG_TARGET_DEVICE_NAME", unsafe.Sizeof(DisplayConfigTargetDeviceName{}), 420},
+		{"offsetof MODE_INFO.adapterId", unsafe.Offsetof(DisplayConfigModeInfo{}.AdapterID), 8},
+		{"offsetof MODE_INFO union", unsafe.Offsetof(DisplayConfigModeInfo{}.Mode), 16},
+		{"offsetof PATH_INFO.targetInfo", unsafe.Offsetof(DisplayConfigPathInfo{}.TargetInfo), 20},
+		{"offsetof PATH_INFO.flags", unsafe.Offsetof(DisplayConfigPathInfo{}.Flags), 68},
+		{"offsetof PATH_TARGET_INFO.refreshRate", unsafe.Offsetof(DisplayConfigPathTargetInfo{}.RefreshRate), 28},
+		{"offsetof PATH_TARGET_INFO.targetAvailable", unsafe.Offsetof(DisplayConfigPathTargetInfo{}.TargetAvailable), 40},
+		{"offsetof VIDEO_SIGNAL_INFO.videoStandard", unsafe.Offsetof(DisplayConfigVideoSignalInfo{}.VideoStandard), 40},
+		{"offsetof SOURCE_MODE.position", unsafe.Offsetof(DisplayConfigSourceMode{}.Position), 12},
+		{"offsetof TARGET_DEVICE_NAME.monitorFriendlyDeviceName", unsafe.Offsetof(DisplayConfigTargetDeviceName{}.MonitorFriendlyDeviceName), 36},
+		{"offsetof TARGET_DEVICE_NAME.monitorDevicePath", unsafe.Offsetof(DisplayConfigTargetDeviceName{}.MonitorDevicePath), 164},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestModeInfoUnion(t *testing.T) {
+	var mode DisplayConfigModeInfo
+	source := DisplayConfigSourceMode{Width: 2560, Height: 1440, PixelFormat: DisplayConfigPixelFormat32Bpp, Position: PointL{X: -2560, Y: 120}}
+	mode.SetSourceMode(source)
+	if got := *mode.SourceMode(); got != source {
+		t.Errorf("SourceMode() = %+v, want %+v", got, source)
+	}
+
+	var target DisplayConfigTargetMode
+	target.TargetVideoSignalInfo.PixelRate = 241500000
+	target.TargetVideoSignalInfo.VSyncFreq = DisplayConfigRational{Numerator: 59951, Denominator: 1000}
+	target.TargetVideoSignalInfo.VideoStandard = D3DkmdtVideoSignalStandardVesaDmt
+	mode.SetTargetMode(target)
+	if got := *mode.TargetMode(); got != target {
+		t.Errorf("TargetMode() = %+v, want %+v", got, target)
+	}
+
+	info := DisplayConfigDesktopImageInfo{PathSourceSize: PointL{X: 1920, Y: 1080}}
+	mode.SetDesktopImageInfo(info)
+	if got := *mode.DesktopImageInfo(); got != info {
+		t.Errorf("DesktopImageInfo() = %+v, want %+v", got, info)
+	}
+}
+
+func TestModeIndices(t *testing.T) {
+	tests := []struct {
+		name        string
+		flags       uint32
+		sourceIdx   uint32
+		targetIdx   uint32
+		wantSource  int
+		wantTarget  int
+		wantDesktop int
+		wantGroup   uint32
+		hasSource   bool
+		hasTarget   bool
+		hasDesktop  bool
+		hasGroup    bool
+	}{
+		{
+			name:      "plain indices",
+			sourceIdx: 1, targetIdx: 0,
+			wantSource: 1, wantTarget: 0,
+			hasSource: true, hasTarget: true,
+		},
+		{
+			name:      "plain without modes",
+			sourceIdx: DisplayConfigPathModeIdxInvalid, targetIdx: DisplayConfigPathModeIdxInvalid,
+		},
+		{
+			name:      "packed indices",
+			flags:     uint32(DisplayConfigFlagPathSupportVirtualMode),
+			sourceIdx: PackSourceModeIdx(2, 3), targetIdx: PackTargetModeIdx(4, 5),
+			wantSource: 3, wantTarget: 4, wantDesktop: 5, wantGroup: 2,
+			hasSource: true, hasTarget: true, hasDesktop: true, hasGroup: true,
+		},
+		{
+			name:      "packed without modes",
+			flags:     uint32(DisplayConfigFlagPathSupportVirtualMode),
+			sourceIdx: PackSourceModeIdx(DisplayConfigPathPackedIdxInvalid, -1), targetIdx: PackTargetModeIdx(-1, -1),
+			wantGroup: DisplayConfigPathPackedIdxInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := DisplayConfigPathInfo{Flags: tt.flags}
+			path.SourceInfo.ModeInfoIdx = tt.sourceIdx
+			path.TargetInfo.ModeInfoIdx = tt.targetIdx
+			if idx, ok := path.SourceModeIdx(); ok != tt.hasSource || ok && idx != tt.wantSource {
+				t.Errorf("SourceModeIdx() = %d, %v", idx, ok)
+			}
+			if idx, ok := path.TargetModeIdx(); ok != tt.hasTarget || ok && idx != tt.wantTarget {
+				t.Errorf("TargetModeIdx() = %d, %v", idx, ok)
+			}
+			if idx, ok := path.DesktopModeIdx(); ok != tt.hasDesktop || ok && idx != tt.wantDesktop {
+				t.Errorf("DesktopModeIdx() = %d, %v", idx, ok)
+			}
+			if group, ok := path.CloneGroupID(); ok != tt.hasGroup || tt.flags != 0 && group != tt.wantGroup {
+				t.Errorf("CloneGroupID() = %d, %v", group, ok)
+			}
+		})
+	}
+}
+
+func TestTargetDeviceNameStrings(t *testing.T) {
+	var name DisplayConfigTargetDeviceName
+	name.SetFriendlyName("DELL P2419H")
+	if got := name.FriendlyName(); got != "DELL P2419H" {
+		t.Errorf("FriendlyName() = %q", got)
+	}
+	long := strings.Repeat("x", 200)
+	name.SetDevicePath(long)
+	if got := name.DevicePath(); got != long[:127] {
+		t.Errorf("DevicePath() kept %d characters, want 127", len(got))
+	}
+	name.SetDevicePath("short")
+	if got := name.DevicePath(); got != "short" {
+		t.Errorf("DevicePath() after a shorter name = %q", got)
+	}
+}
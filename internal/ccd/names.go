@@ -0,0 +1,395 @@
+package ccd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Names of the enum and flag values, as printed by String and written by
+// MarshalText. UnmarshalText accepts these names (in any case) as well as
+// numbers, so values Windows adds later still round-trip; UnmarshalJSON
+// also takes bare JSON numbers.
+
+var outputTechnologyNames = map[DisplayConfigVideoOutputTechnology]string{
+	DisplayConfigVideoOutputTechnologyOther:                "other",
+	DisplayConfigVideoOutputTechnologyHd15:                 "hd15",
+	DisplayConfigVideoOutputTechnologySVideo:               "svideo",
+	DisplayConfigVideoOutputTechnologyCompositeVideo:       "compositeVideo",
+	DisplayConfigVideoOutputTechnologyComponentVideo:       "componentVideo",
+	DisplayConfigVideoOutputTechnologyDvi:                  "dvi",
+	DisplayConfigVideoOutputTechnologyHdmi:                 "hdmi",
+	DisplayConfigVideoOutputTechnologyLvds:                 "lvds",
+	DisplayConfigVideoOutputTechnologyDJpn:                 "dJpn",
+	DisplayConfigVideoOutputTechnologySdi:                  "sdi",
+	DisplayConfigVideoOutputTechnologyDisplayPortExt:       "displayPortExternal",
+	DisplayConfigVideoOutputTechnologyDisplayPortEmb:       "displayPortEmbedded",
+	DisplayConfigVideoOutputTechnologyUdiExternal:          "udiExternal",
+	DisplayConfigVideoOutputTechnologyUdiEmbedded:          "udiEmbedded",
+	DisplayConfigVideoOutputTechnologySdtvDongle:           "sdtvDongle",
+	DisplayConfigVideoOutputTechnologyMiracast:             "miracast",
+	DisplayConfigVideoOutputTechnologyIndirectWired:        "indirectWired",
+	DisplayConfigVideoOutputTechnologyIndirectVirtual:      "indirectVirtual",
+	DisplayConfigVideoOutputTechnologyDisplayPortUsbTunnel: "displayPortUsbTunnel",
+	DisplayConfigVideoOutputTechnologyInternal:             "internal",
+}
+
+var rotationNames = map[DisplayConfigRotation]string{
+	DisplayConfigRotationIdentity:  "identity",
+	DisplayConfigRotationRotate90:  "rotate90",
+	DisplayConfigRotationRotate180: "rotate180",
+	DisplayConfigRotationRotate270: "rotate270",
+}
+
+// scalingNames match the scaling values of per-monitor profiles.
+var scalingNames = map[DisplayConfigScaling]string{
+	DisplayConfigScalingIdentity:               "identity",
+	DisplayConfigScalingCentered:               "centered",
+	DisplayConfigScalingStretched:              "stretched",
+	DisplayConfigScalingAspectRatioCenteredMax: "aspect-ratio",
+	DisplayConfigScalingCustom:                 "custom",
+	DisplayConfigScalingPreferred:              "preferred",
+}
+
+var pixelFormatNames = map[DisplayConfigPixelFormat]string{
+	DisplayConfigPixelFormat8Bpp:   "8bpp",
+	DisplayConfigPixelFormat16Bpp:  "16bpp",
+	DisplayConfigPixelFormat24Bpp:  "24bpp",
+	DisplayConfigPixelFormat32Bpp:  "32bpp",
+	DisplayConfigPixelFormatNongdi: "nongdi",
+}
+
+var scanLineOrderingNames = map[DisplayConfigScanLineOrdering]string{
+	DisplayConfigScanLineOrderingUnspecified:               "unspecified",
+	DisplayConfigScanLineOrderingProgressive:               "progressive",
+	DisplayConfigScanLineOrderingInterlaced:                "interlaced",
+	DisplayConfigScanLineOrderingInterlacedLowerFieldFirst: "interlacedLowerFieldFirst",
+}
+
+var videoStandardNames = map[D3DkmdtVideoSignalStandard]string{
+	D3DkmdtVideoSignalStandardUninitialized: "uninitialized",
+	D3DkmdtVideoSignalStandardVesaDmt:       "vesaDmt",
+	D3DkmdtVideoSignalStandardVesaGtf:       "vesaGtf",
+	D3DkmdtVideoSignalStandardVesaCvt:       "vesaCvt",
+	D3DkmdtVideoSignalStandardIbm:           "ibm",
+	D3DkmdtVideoSignalStandardApple:         "apple",
+	D3DkmdtVideoSignalStandardNtscM:         "ntscM",
+	D3DkmdtVideoSignalStandardNtscJ:         "ntscJ",
+	D3DkmdtVideoSignalStandardNtsc443:       "ntsc443",
+	D3DkmdtVideoSignalStandardPalB:          "palB",
+	D3DkmdtVideoSignalStandardPalB1:         "palB1",
+	D3DkmdtVideoSignalStandardPalG:          "palG",
+	D3DkmdtVideoSignalStandardPalH:          "palH",
+	D3DkmdtVideoSignalStandardPalI:          "palI",
+	D3DkmdtVideoSignalStandardPalD:          "palD",
+	D3DkmdtVideoSignalStandardPalN:          "palN",
+	D3DkmdtVideoSignalStandardPalNc:         "palNc",
+	D3DkmdtVideoSignalStandardSecamB:        "secamB",
+	D3DkmdtVideoSignalStandardSecamD:        "secamD",
+	D3DkmdtVideoSignalStandardSecamG:        "secamG",
+	D3DkmdtVideoSignalStandardSecamH:        "secamH",
+	D3DkmdtVideoSignalStandardSecamK:        "secamK",
+	D3DkmdtVideoSignalStandardSecamK1:       "secamK1",
+	D3DkmdtVideoSignalStandardSecamL:        "secamL",
+	D3DkmdtVideoSignalStandardSecamL1:       "secamL1",
+	D3DkmdtVideoSignalStandardEia861:        "eia861",
+	D3DkmdtVideoSignalStandardEia861A:       "eia861A",
+	D3DkmdtVideoSignalStandardEia861B:       "eia861B",
+	D3DkmdtVideoSignalStandardPalK:          "palK",
+	D3DkmdtVideoSignalStandardPalK1:         "palK1",
+	D3DkmdtVideoSignalStandardPalL:          "palL",
+	D3DkmdtVideoSignalStandardPalM:          "palM",
+	D3DkmdtVideoSignalStandardOther:         "other",
+	D3DkmdtVideoSignalStandardUSB:           "usb",
+}
+
+// Flag names are listed in bit order; String joins the set ones with "|".
+
+var pathFlagNames = []flagName[DisplayConfigFlags]{
+	{DisplayConfigFlagPathActive, "active"},
+	{DisplayConfigFlagPathPreferredUnscaled, "preferredUnscaled"},
+	{DisplayConfigFlagPathSupportVirtualMode, "supportVirtualMode"},
+	{DisplayConfigFlagPathBoostRefreshRate, "boostRefreshRate"},
+	{DisplayConfigFlagPathSupportVirtualRefreshRate, "supportVirtualRefreshRate"},
+}
+
+var sourceStatusNames = []flagName[DisplayConfigSourceStatus]{
+	{DisplayConfigSourceStatusInUse, "inUse"},
+}
+
+var targetStatusNames = []flagName[DisplayConfigTargetStatus]{
+	{DisplayConfigTargetStatusInUse, "inUse"},
+	{DisplayConfigTargetStatusForcible, "forcible"},
+	{DisplayConfigTargetStatusForcedAvailabilityBoot, "forcedAvailabilityBoot"},
+	{DisplayConfigTargetStatusForcedAvailabilityPath, "forcedAvailabilityPath"},
+	{DisplayConfigTargetStatusForcedAvailabilitySystem, "forcedAvailabilitySystem"},
+	{DisplayConfigTargetStatusIsHMD, "isHMD"},
+}
+
+func (t DisplayConfigVideoOutputTechnology) String() string {
+	return enumString(outputTechnologyNames, t)
+}
+
+// Defined reports whether wingdi.h defines the value.
+func (t DisplayConfigVideoOutputTechnology) Defined() bool {
+	return enumDefined(outputTechnologyNames, t)
+}
+
+func (t DisplayConfigVideoOutputTechnology) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *DisplayConfigVideoOutputTechnology) UnmarshalText(text []byte) error {
+	return parseEnum(outputTechnologyNames, "output technology", string(text), t)
+}
+
+func (t *DisplayConfigVideoOutputTechnology) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, t.UnmarshalText)
+}
+
+func (r DisplayConfigRotation) String() string {
+	return enumString(rotationNames, r)
+}
+
+// Defined reports whether wingdi.h defines the value.
+func (r DisplayConfigRotation) Defined() bool {
+	return enumDefined(rotationNames, r)
+}
+
+func (r DisplayConfigRotation) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *DisplayConfigRotation) UnmarshalText(text []byte) error {
+	return parseEnum(rotationNames, "rotation", string(text), r)
+}
+
+func (r *DisplayConfigRotation) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, r.UnmarshalText)
+}
+
+func (s DisplayConfigScaling) String() string {
+	return enumString(scalingNames, s)
+}
+
+// Defined reports whether wingdi.h defines the value.
+func (s DisplayConfigScaling) Defined() bool {
+	return enumDefined(scalingNames, s)
+}
+
+func (s DisplayConfigScaling) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *DisplayConfigScaling) UnmarshalText(text []byte) error {
+	return parseEnum(scalingNames, "scaling", string(text), s)
+}
+
+func (s *DisplayConfigScaling) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, s.UnmarshalText)
+}
+
+func (f DisplayConfigPixelFormat) String() string {
+	return enumString(pixelFormatNames, f)
+}
+
+// Defined reports whether wingdi.h defines the value.
+func (f DisplayConfigPixelFormat) Defined() bool {
+	return enumDefined(pixelFormatNames, f)
+}
+
+func (f DisplayConfigPixelFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *DisplayConfigPixelFormat) UnmarshalText(text []byte) error {
+	return parseEnum(pixelFormatNames, "pixel format", string(text), f)
+}
+
+func (f *DisplayConfigPixelFormat) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, f.UnmarshalText)
+}
+
+func (o DisplayConfigScanLineOrdering) String() string {
+	return enumString(scanLineOrderingNames, o)
+}
+
+// Defined reports whether wingdi.h defines the value.
+func (o DisplayConfigScanLineOrdering) Defined() bool {
+	return enumDefined(scanLineOrderingNames, o)
+}
+
+func (o DisplayConfigScanLineOrdering) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *DisplayConfigScanLineOrdering) UnmarshalText(text []byte) error {
+	return parseEnum(scanLineOrderingNames, "scan line ordering", string(text), o)
+}
+
+func (o *DisplayConfigScanLineOrdering) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, o.UnmarshalText)
+}
+
+func (s D3DkmdtVideoSignalStandard) String() string {
+	return enumString(videoStandardNames, s)
+}
+
+func (s D3DkmdtVideoSignalStandard) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *D3DkmdtVideoSignalStandard) UnmarshalText(text []byte) error {
+	return parseEnum(videoStandardNames, "video standard", string(text), s)
+}
+
+func (s *D3DkmdtVideoSignalStandard) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, s.UnmarshalText)
+}
+
+func (f DisplayConfigFlags) String() string {
+	return flagsString(pathFlagNames, f)
+}
+
+func (f DisplayConfigFlags) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *DisplayConfigFlags) UnmarshalText(text []byte) error {
+	return parseFlags(pathFlagNames, "path flag", string(text), f)
+}
+
+func (f *DisplayConfigFlags) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, f.UnmarshalText)
+}
+
+func (s DisplayConfigSourceStatus) String() string {
+	return flagsString(sourceStatusNames, s)
+}
+
+func (s DisplayConfigSourceStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *DisplayConfigSourceStatus) UnmarshalText(text []byte) error {
+	return parseFlags(sourceStatusNames, "source status flag", string(text), s)
+}
+
+func (s *DisplayConfigSourceStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, s.UnmarshalText)
+}
+
+func (s DisplayConfigTargetStatus) String() string {
+	return flagsString(targetStatusNames, s)
+}
+
+func (s DisplayConfigTargetStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *DisplayConfigTargetStatus) UnmarshalText(text []byte) error {
+	return parseFlags(targetStatusNames, "target status flag", string(text), s)
+}
+
+func (s *DisplayConfigTargetStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, s.UnmarshalText)
+}
+
+// unmarshalJSON decodes a name, or a bare number as traces written before
+// the names existed hold, with unmarshalText.
+func unmarshalJSON(data []byte, unmarshalText func([]byte) error) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		return unmarshalText([]byte(text))
+	}
+	return unmarshalText(data)
+}
+
+// enumString returns the name of value, or its number when it has none.
+func enumString[T ~uint32](names map[T]string, value T) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+// parseEnum sets value from a name or a number.
+func enumDefined[T ~uint32](names map[T]string, value T) bool {
+	_, ok := names[value]
+	return ok
+}
+
+func parseEnum[T ~uint32](names map[T]string, kind, text string, value *T) error {
+	text = strings.TrimSpace(text)
+	for known, name := range names {
+		if strings.EqualFold(text, name) {
+			*value = known
+			return nil
+		}
+	}
+	number, err := strconv.ParseUint(text, 0, 32)
+	if err != nil {
+		return fmt.Errorf("unknown %s %q", kind, text)
+	}
+	*value = T(number)
+	return nil
+}
+
+type flagName[T ~uint32] struct {
+	flag T
+	name string
+}
+
+// flagsString joins the names of the flags set in value with "|", with any
+// bits that have no name as one hex number. No flags at all is "none".
+func flagsString[T ~uint32](names []flagName[T], value T) string {
+	if value == 0 {
+		return "none"
+	}
+	var parts []string
+	rest := value
+	for _, f := range names {
+		if value&f.flag != 0 {
+			parts = append(parts, f.name)
+			rest &^= f.flag
+		}
+	}
+	if rest != 0 {
+		parts = append(parts, fmt.Sprintf("0x%X", uint32(rest)))
+	}
+	return strings.Join(parts, "|")
+}
+
+// parseFlags sets value from "none", a number, or names and numbers joined
+// with "|".
+func parseFlags[T ~uint32](names []flagName[T], kind, text string, value *T) error {
+	var result T
+	for _, part := range strings.Split(text, "|") {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.EqualFold(part, "none") {
+			continue
+		}
+		found := false
+		for _, f := range names {
+			if strings.EqualFold(part, f.name) {
+				result |= f.flag
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		number, err := strconv.ParseUint(part, 0, 32)
+		if err != nil {
+			return fmt.Errorf("unknown %s %q", kind, part)
+		}
+		result |= T(number)
+	}
+	*value = result
+	return nil
+}
@@ -0,0 +1,165 @@
+package ccd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFlagsString(t *testing.T) {
+	tests := []struct {
+		flags DisplayConfigFlags
+		want  string
+	}{
+		{0, "none"},
+		{DisplayConfigFlagPathActive, "active"},
+		{DisplayConfigFlagPathActive | DisplayConfigFlagPathSupportVirtualMode, "active|supportVirtualMode"},
+		{DisplayConfigFlagPathBoostRefreshRate | DisplayConfigFlagPathSupportVirtualRefreshRate, "boostRefreshRate|supportVirtualRefreshRate"},
+		{DisplayConfigFlagPathActive | 0x40 | 0x100, "active|0x140"},
+	}
+	for _, tt := range tests {
+		if got := tt.flags.String(); got != tt.want {
+			t.Errorf("DisplayConfigFlags(0x%X).String() = %q, want %q", uint32(tt.flags), got, tt.want)
+		}
+		var parsed DisplayConfigFlags
+		if err := parsed.UnmarshalText([]byte(tt.want)); err != nil || parsed != tt.flags {
+			t.Errorf("UnmarshalText(%q) = 0x%X, %v; want 0x%X", tt.want, uint32(parsed), err, uint32(tt.flags))
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    DisplayConfigTargetStatus
+		wantErr string
+	}{
+		{text: "", want: 0},
+		{text: "None", want: 0},
+		{text: "inUse | FORCIBLE", want: DisplayConfigTargetStatusInUse | DisplayConfigTargetStatusForcible},
+		{text: "3", want: 3},
+		{text: "inUse|0x100", want: DisplayConfigTargetStatusInUse | 0x100},
+		{text: "inUse|bogus", wantErr: `unknown target status flag "bogus"`},
+	}
+	for _, tt := range tests {
+		var got DisplayConfigTargetStatus
+		err := got.UnmarshalText([]byte(tt.text))
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("UnmarshalText(%q) = %v, want error %q", tt.text, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("UnmarshalText(%q) = 0x%X, %v; want 0x%X", tt.text, uint32(got), err, uint32(tt.want))
+		}
+	}
+}
+
+func TestEnumNames(t *testing.T) {
+	tests := []struct {
+		value fmt.Stringer
+		want  string
+	}{
+		{DisplayConfigVideoOutputTechnologyDisplayPortExt, "displayPortExternal"},
+		{DisplayConfigVideoOutputTechnologyInternal, "internal"},
+		{DisplayConfigVideoOutputTechnology(99), "99"},
+		{DisplayConfigRotationRotate270, "rotate270"},
+		{DisplayConfigScalingAspectRatioCenteredMax, "aspect-ratio"},
+		{DisplayConfigPixelFormat32Bpp, "32bpp"},
+		{DisplayConfigScanLineOrderingInterlaced, "interlaced"},
+		{D3DkmdtVideoSignalStandardVesaDmt, "vesaDmt"},
+		{DisplayConfigSourceStatusInUse, "inUse"},
+	}
+	for _, tt := range tests {
+		if got := tt.value.String(); got != tt.want {
+			t.Errorf("%T(%v).String() = %q, want %q", tt.value, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEnumDefined(t *testing.T) {
+	tests := []struct {
+		value interface{ Defined() bool }
+		want  bool
+	}{
+		{DisplayConfigVideoOutputTechnologyDisplayPortUsbTunnel, true},
+		{DisplayConfigVideoOutputTechnologyOther, true},
+		{DisplayConfigVideoOutputTechnology(7), false},
+		{DisplayConfigRotation(0), false},
+		{DisplayConfigScalingPreferred, true},
+		{DisplayConfigScaling(6), false},
+		{DisplayConfigScanLineOrderingUnspecified, true},
+		{DisplayConfigPixelFormatNongdi, true},
+		{DisplayConfigPixelFormat(6), false},
+	}
+	for _, tt := range tests {
+		if got := tt.value.Defined(); got != tt.want {
+			t.Errorf("%T(%v).Defined() = %v, want %v", tt.value, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEnumUnmarshal(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    DisplayConfigRotation
+		wantErr bool
+	}{
+		{text: "rotate90", want: DisplayConfigRotationRotate90},
+		{text: " ROTATE180 ", want: DisplayConfigRotationRotate180},
+		{text: "4", want: DisplayConfigRotationRotate270},
+		{text: "0x7", want: 7},
+		{text: "sideways", wantErr: true},
+	}
+	for _, tt := range tests {
+		var got DisplayConfigRotation
+		err := got.UnmarshalText([]byte(tt.text))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("UnmarshalText(%q) = %v, want error %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("UnmarshalText(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNamesJSON(t *testing.T) {
+	type fields struct {
+		Flags    DisplayConfigFlags                 `json:"flags"`
+		Output   DisplayConfigVideoOutputTechnology `json:"output"`
+		Scaling  DisplayConfigScaling               `json:"scaling"`
+		Standard D3DkmdtVideoSignalStandard         `json:"standard"`
+	}
+	want := fields{
+		Flags:    DisplayConfigFlagPathActive | DisplayConfigFlagPathBoostRefreshRate,
+		Output:   DisplayConfigVideoOutputTechnologyHdmi,
+		Scaling:  DisplayConfigScalingPreferred,
+		Standard: 200,
+	}
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := string(data); text != `{"flags":"active|boostRefreshRate","output":"hdmi","scaling":"preferred","standard":"200"}` {
+		t.Errorf("Marshal() = %s", text)
+	}
+	for _, data := range []string{
+		string(data),
+		// Traces written before the names existed hold numbers.
+		`{"flags":17,"output":5,"scaling":128,"standard":200}`,
+	} {
+		var got fields
+		if err := json.Unmarshal([]byte(data), &got); err != nil || got != want {
+			t.Errorf("Unmarshal(%s) = %+v, %v; want %+v", data, got, err, want)
+		}
+	}
+
+	var got fields
+	if err := json.Unmarshal([]byte(`{"scaling":"squashed"}`), &got); err == nil || !strings.Contains(err.Error(), `unknown scaling "squashed"`) {
+		t.Errorf("Unmarshal() of an unknown name = %v", err)
+	}
+}
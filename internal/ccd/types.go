@@ -0,0 +1,476 @@
+package ccd
+
+import (
+	"unicode/utf16"
+	"unsafe"
+)
+
+type LUID struct {
+	LowPart  uint32
+	HighPart uint32
+}
+
+type DisplayConfigVideoOutputTechnology uint32
+
+const (
+	DisplayConfigVideoOutputTechnologyOther                DisplayConfigVideoOutputTechnology = 0xFFFFFFFF
+	DisplayConfigVideoOutputTechnologyHd15                 DisplayConfigVideoOutputTechnology = 0
+	DisplayConfigVideoOutputTechnologySVideo               DisplayConfigVideoOutputTechnology = 1
+	DisplayConfigVideoOutputTechnologyCompositeVideo       DisplayConfigVideoOutputTechnology = 2
+	DisplayConfigVideoOutputTechnologyComponentVideo       DisplayConfigVideoOutputTechnology = 3
+	DisplayConfigVideoOutputTechnologyDvi                  DisplayConfigVideoOutputTechnology = 4
+	DisplayConfigVideoOutputTechnologyHdmi                 DisplayConfigVideoOutputTechnology = 5
+	DisplayConfigVideoOutputTechnologyLvds                 DisplayConfigVideoOutputTechnology = 6
+	DisplayConfigVideoOutputTechnologyDJpn                 DisplayConfigVideoOutputTechnology = 8
+	DisplayConfigVideoOutputTechnologySdi                  DisplayConfigVideoOutputTechnology = 9
+	DisplayConfigVideoOutputTechnologyDisplayPortExt       DisplayConfigVideoOutputTechnology = 10
+	DisplayConfigVideoOutputTechnologyDisplayPortEmb       DisplayConfigVideoOutputTechnology = 11
+	DisplayConfigVideoOutputTechnologyUdiExternal          DisplayConfigVideoOutputTechnology = 12
+	DisplayConfigVideoOutputTechnologyUdiEmbedded          DisplayConfigVideoOutputTechnology = 13
+	DisplayConfigVideoOutputTechnologySdtvDongle           DisplayConfigVideoOutputTechnology = 14
+	DisplayConfigVideoOutputTechnologyMiracast             DisplayConfigVideoOutputTechnology = 15
+	DisplayConfigVideoOutputTechnologyIndirectWired        DisplayConfigVideoOutputTechnology = 16
+	DisplayConfigVideoOutputTechnologyIndirectVirtual      DisplayConfigVideoOutputTechnology = 17
+	DisplayConfigVideoOutputTechnologyDisplayPortUsbTunnel DisplayConfigVideoOutputTechnology = 18
+	DisplayConfigVideoOutputTechnologyInternal             DisplayConfigVideoOutputTechnology = 0x80000000
+	DisplayConfigVideoOutputTechnologyForceUint32          DisplayConfigVideoOutputTechnology = 0xFFFFFFFF
+)
+
+type SdcFlags uint32
+
+const (
+	SdcFlagsZero                     SdcFlags = 0
+	SdcFlagsTopologyInternal         SdcFlags = 0x00000001
+	SdcFlagsTopologyClone            SdcFlags = 0x00000002
+	SdcFlagsTopologyExtend           SdcFlags = 0x00000004
+	SdcFlagsTopologyExternal         SdcFlags = 0x00000008
+	SdcFlagsTopologySupplied         SdcFlags = 0x00000010
+	SdcFlagsUseSuppliedDisplayConfig SdcFlags = 0x00000020
+	SdcFlagsValidate                 SdcFlags = 0x00000040
+	SdcFlagsApply                    SdcFlags = 0x00000080
+	SdcFlagsNoOptimization           SdcFlags = 0x00000100
+	SdcFlagsSaveToDatabase           SdcFlags = 0x00000200
+	SdcFlagsAllowChanges             SdcFlags = 0x00000400
+	SdcFlagsPathPersistIfRequired    SdcFlags = 0x00000800
+	SdcFlagsForceModeEnumeration     SdcFlags = 0x00001000
+	SdcFlagsAllowPathOrderChanges    SdcFlags = 0x00002000
+	SdcFlagsVirtualModeAware         SdcFlags = 0x00008000
+	SdcFlagsUseDatabaseCurrent       SdcFlags = SdcFlagsTopologyInternal | SdcFlagsTopologyClone | SdcFlagsTopologyExtend | SdcFlagsTopologyExternal
+)
+
+type DisplayConfigFlags uint32
+
+const (
+	DisplayConfigFlagZero                          DisplayConfigFlags = 0x0
+	DisplayConfigFlagPathActive                    DisplayConfigFlags = 0x00000001
+	DisplayConfigFlagPathPreferredUnscaled         DisplayConfigFlags = 0x00000004
+	DisplayConfigFlagPathSupportVirtualMode        DisplayConfigFlags = 0x00000008
+	DisplayConfigFlagPathBoostRefreshRate          DisplayConfigFlags = 0x00000010
+	DisplayConfigFlagPathSupportVirtualRefreshRate DisplayConfigFlags = 0x00000020
+	DisplayConfigFlagPathValidFlags                DisplayConfigFlags = 0x0000003D
+)
+
+type DisplayConfigSourceStatus uint32
+
+const (
+	DisplayConfigSourceStatusZero  DisplayConfigSourceStatus = 0x0
+	DisplayConfigSourceStatusInUse DisplayConfigSourceStatus = 0x00000001
+)
+
+type DisplayConfigTargetStatus uint32
+
+const (
+	DisplayConfigTargetStatusZero                     DisplayConfigTargetStatus = 0x0
+	DisplayConfigTargetStatusInUse                    DisplayConfigTargetStatus = 0x00000001
+	DisplayConfigTargetStatusForcible                 DisplayConfigTargetStatus = 0x00000002
+	DisplayConfigTargetStatusForcedAvailabilityBoot   DisplayConfigTargetStatus = 0x00000004
+	DisplayConfigTargetStatusForcedAvailabilityPath   DisplayConfigTargetStatus = 0x00000008
+	DisplayConfigTargetStatusForcedAvailabilitySystem DisplayConfigTargetStatus = 0x00000010
+	DisplayConfigTargetStatusIsHMD                    DisplayConfigTargetStatus = 0x00000020
+)
+
+type DisplayConfigRotation uint32
+
+const (
+	DisplayConfigRotationZero        DisplayConfigRotation = 0x0
+	DisplayConfigRotationIdentity    DisplayConfigRotation = 1
+	DisplayConfigRotationRotate90    DisplayConfigRotation = 2
+	DisplayConfigRotationRotate180   DisplayConfigRotation = 3
+	DisplayConfigRotationRotate270   DisplayConfigRotation = 4
+	DisplayConfigRotationForceUint32 DisplayConfigRotation = 0xFFFFFFFF
+)
+
+type DisplayConfigPixelFormat uint32
+
+const (
+	DisplayConfigPixelFormatZero        DisplayConfigPixelFormat = 0x0
+	DisplayConfigPixelFormat8Bpp        DisplayConfigPixelFormat = 1
+	DisplayConfigPixelFormat16Bpp       DisplayConfigPixelFormat = 2
+	DisplayConfigPixelFormat24Bpp       DisplayConfigPixelFormat = 3
+	DisplayConfigPixelFormat32Bpp       DisplayConfigPixelFormat = 4
+	DisplayConfigPixelFormatNongdi      DisplayConfigPixelFormat = 5
+	DisplayConfigPixelFormatForceUint32 DisplayConfigPixelFormat = 0xFFFFFFFF
+)
+
+type DisplayConfigScaling uint32
+
+const (
+	DisplayConfigScalingZero                   DisplayConfigScaling = 0x0
+	DisplayConfigScalingIdentity               DisplayConfigScaling = 1
+	DisplayConfigScalingCentered               DisplayConfigScaling = 2
+	DisplayConfigScalingStretched              DisplayConfigScaling = 3
+	DisplayConfigScalingAspectRatioCenteredMax DisplayConfigScaling = 4
+	DisplayConfigScalingCustom                 DisplayConfigScaling = 5
+	DisplayConfigScalingPreferred              DisplayConfigScaling = 128
+	DisplayConfigScalingForceUint32            DisplayConfigScaling = 0xFFFFFFFF
+)
+
+type DisplayConfigRational struct {
+	Numerator   uint32
+	Denominator uint32
+}
+
+type DisplayConfigScanLineOrdering uint32
+
+const (
+	DisplayConfigScanLineOrderingUnspecified               DisplayConfigScanLineOrdering = 0
+	DisplayConfigScanLineOrderingProgressive               DisplayConfigScanLineOrdering = 1
+	DisplayConfigScanLineOrderingInterlaced                DisplayConfigScanLineOrdering = 2
+	DisplayConfigScanLineOrderingInterlacedUpperFieldFirst DisplayConfigScanLineOrdering = DisplayConfigScanLineOrderingInterlaced
+	DisplayConfigScanLineOrderingInterlacedLowerFieldFirst DisplayConfigScanLineOrdering = 3
+	DisplayConfigScanLineOrderingForceUint32               DisplayConfigScanLineOrdering = 0xFFFFFFFF
+)
+
+type DisplayConfigPathInfo struct {
+	SourceInfo DisplayConfigPathSourceInfo
+	TargetInfo DisplayConfigPathTargetInfo
+	Flags      uint32
+}
+
+// Mode indices in DisplayConfigPathInfo. A plain index of
+// DisplayConfigPathModeIdxInvalid means "no mode". When the path carries
+// DisplayConfigFlagPathSupportVirtualMode, each ModeInfoIdx instead packs two
+// 16-bit fields: the source holds cloneGroupId (low) and sourceModeInfoIdx
+// (high), the target holds desktopModeInfoIdx (low) and targetModeInfoIdx
+// (high), each using DisplayConfigPathPackedIdxInvalid for "no mode".
+const (
+	DisplayConfigPathModeIdxInvalid   uint32 = 0xFFFFFFFF
+	DisplayConfigPathPackedIdxInvalid uint32 = 0xFFFF
+)
+
+// VirtualModeAware reports whether the path uses the packed index layout.
+func (p *DisplayConfigPathInfo) VirtualModeAware() bool {
+	return p.Flags&uint32(DisplayConfigFlagPathSupportVirtualMode) != 0
+}
+
+// SourceModeIdx returns the index of the path's source mode.
+func (p *DisplayConfigPathInfo) SourceModeIdx() (int, bool) {
+	if p.VirtualModeAware() {
+		return unpackModeIdx(p.SourceInfo.ModeInfoIdx >> 16)
+	}
+	return unpackPlainModeIdx(p.SourceInfo.ModeInfoIdx)
+}
+
+// TargetModeIdx returns the index of the path's target mode.
+func (p *DisplayConfigPathInfo) TargetModeIdx() (int, bool) {
+	if p.VirtualModeAware() {
+		return unpackModeIdx(p.TargetInfo.ModeInfoIdx >> 16)
+	}
+	return unpackPlainModeIdx(p.TargetInfo.ModeInfoIdx)
+}
+
+// DesktopModeIdx returns the index of the path's desktop image mode. Only
+// virtual-mode-aware paths reference one.
+func (p *DisplayConfigPathInfo) DesktopModeIdx() (int, bool) {
+	if !p.VirtualModeAware() {
+		return 0, false
+	}
+	return unpackModeIdx(p.TargetInfo.ModeInfoIdx & 0xFFFF)
+}
+
+// CloneGroupID returns the clone group of a virtual-mode-aware path.
+func (p *DisplayConfigPathInfo) CloneGroupID() (uint32, bool) {
+	if !p.VirtualModeAware() {
+		return 0, false
+	}
+	group := p.SourceInfo.ModeInfoIdx & 0xFFFF
+	return group, group != DisplayConfigPathPackedIdxInvalid
+}
+
+// PackSourceModeIdx builds a virtual-mode-aware source ModeInfoIdx. Pass a
+// negative index for "no mode".
+func PackSourceModeIdx(cloneGroup uint32, sourceIdx int) uint32 {
+	return packModeIdx(sourceIdx)<<16 | cloneGroup&0xFFFF
+}
+
+// PackTargetModeIdx builds a virtual-mode-aware target ModeInfoIdx. Pass a
+// negative index for "no mode".
+func PackTargetModeIdx(targetIdx int, desktopIdx int) uint32 {
+	return packModeIdx(targetIdx)<<16 | packModeIdx(desktopIdx)
+}
+
+func packModeIdx(idx int) uint32 {
+	if idx < 0 || idx >= int(DisplayConfigPathPackedIdxInvalid) {
+		return DisplayConfigPathPackedIdxInvalid
+	}
+	return uint32(idx)
+}
+
+func unpackModeIdx(value uint32) (int, bool) {
+	value &= 0xFFFF
+	if value == DisplayConfigPathPackedIdxInvalid {
+		return 0, false
+	}
+	return int(value), true
+}
+
+func unpackPlainModeIdx(value uint32) (int, bool) {
+	if value == DisplayConfigPathModeIdxInvalid {
+		return 0, false
+	}
+	return int(value), true
+}
+
+type DisplayConfigModeInfoType uint32
+
+const (
+	DisplayConfigModeInfoTypeZero         DisplayConfigModeInfoType = 0
+	DisplayConfigModeInfoTypeSource       DisplayConfigModeInfoType = 1
+	DisplayConfigModeInfoTypeTarget       DisplayConfigModeInfoType = 2
+	DisplayConfigModeInfoTypeDesktopImage DisplayConfigModeInfoType = 3
+	DisplayConfigModeInfoTypeForceUint32  DisplayConfigModeInfoType = 0xFFFFFFFF
+)
+
+const displayConfigModeInfoUnionSize = 48
+
+type DisplayConfigModeInfo struct {
+	InfoType  DisplayConfigModeInfoType
+	ID        uint32
+	AdapterID LUID
+	Mode      [displayConfigModeInfoUnionSize]byte
+}
+
+func (m *DisplayConfigModeInfo) TargetMode() *DisplayConfigTargetMode {
+	return (*DisplayConfigTargetMode)(unsafe.Pointer(&m.Mode[0]))
+}
+
+func (m *DisplayConfigModeInfo) SourceMode() *DisplayConfigSourceMode {
+	return (*DisplayConfigSourceMode)(unsafe.Pointer(&m.Mode[0]))
+}
+
+func (m *DisplayConfigModeInfo) SetTargetMode(target DisplayConfigTargetMode) {
+	*m.TargetMode() = target
+}
+
+func (m *DisplayConfigModeInfo) SetSourceMode(source DisplayConfigSourceMode) {
+	*m.SourceMode() = source
+}
+
+func (m *DisplayConfigModeInfo) DesktopImageInfo() *DisplayConfigDesktopImageInfo {
+	return (*DisplayConfigDesktopImageInfo)(unsafe.Pointer(&m.Mode[0]))
+}
+
+func (m *DisplayConfigModeInfo) SetDesktopImageInfo(info DisplayConfigDesktopImageInfo) {
+	*m.DesktopImageInfo() = info
+}
+
+type DisplayConfig2DRegion struct {
+	Cx uint32
+	Cy uint32
+}
+
+type D3DkmdtVideoSignalStandard uint32
+
+const (
+	D3DkmdtVideoSignalStandardUninitialized D3DkmdtVideoSignalStandard = 0
+	D3DkmdtVideoSignalStandardVesaDmt       D3DkmdtVideoSignalStandard = 1
+	D3DkmdtVideoSignalStandardVesaGtf       D3DkmdtVideoSignalStandard = 2
+	D3DkmdtVideoSignalStandardVesaCvt       D3DkmdtVideoSignalStandard = 3
+	D3DkmdtVideoSignalStandardIbm           D3DkmdtVideoSignalStandard = 4
+	D3DkmdtVideoSignalStandardApple         D3DkmdtVideoSignalStandard = 5
+	D3DkmdtVideoSignalStandardNtscM         D3DkmdtVideoSignalStandard = 6
+	D3DkmdtVideoSignalStandardNtscJ         D3DkmdtVideoSignalStandard = 7
+	D3DkmdtVideoSignalStandardNtsc443       D3DkmdtVideoSignalStandard = 8
+	D3DkmdtVideoSignalStandardPalB          D3DkmdtVideoSignalStandard = 9
+	D3DkmdtVideoSignalStandardPalB1         D3DkmdtVideoSignalStandard = 10
+	D3DkmdtVideoSignalStandardPalG          D3DkmdtVideoSignalStandard = 11
+	D3DkmdtVideoSignalStandardPalH          D3DkmdtVideoSignalStandard = 12
+	D3DkmdtVideoSignalStandardPalI          D3DkmdtVideoSignalStandard = 13
+	D3DkmdtVideoSignalStandardPalD          D3DkmdtVideoSignalStandard = 14
+	D3DkmdtVideoSignalStandardPalN          D3DkmdtVideoSignalStandard = 15
+	D3DkmdtVideoSignalStandardPalNc         D3DkmdtVideoSignalStandard = 16
+	D3DkmdtVideoSignalStandardSecamB        D3DkmdtVideoSignalStandard = 17
+	D3DkmdtVideoSignalStandardSecamD        D3DkmdtVideoSignalStandard = 18
+	D3DkmdtVideoSignalStandardSecamG        D3DkmdtVideoSignalStandard = 19
+	D3DkmdtVideoSignalStandardSecamH        D3DkmdtVideoSignalStandard = 20
+	D3DkmdtVideoSignalStandardSecamK        D3DkmdtVideoSignalStandard = 21
+	D3DkmdtVideoSignalStandardSecamK1       D3DkmdtVideoSignalStandard = 22
+	D3DkmdtVideoSignalStandardSecamL        D3DkmdtVideoSignalStandard = 23
+	D3DkmdtVideoSignalStandardSecamL1       D3DkmdtVideoSignalStandard = 24
+	D3DkmdtVideoSignalStandardEia861        D3DkmdtVideoSignalStandard = 25
+	D3DkmdtVideoSignalStandardEia861A       D3DkmdtVideoSignalStandard = 26
+	D3DkmdtVideoSignalStandardEia861B       D3DkmdtVideoSignalStandard = 27
+	D3DkmdtVideoSignalStandardPalK          D3DkmdtVideoSignalStandard = 28
+	D3DkmdtVideoSignalStandardPalK1         D3DkmdtVideoSignalStandard = 29
+	D3DkmdtVideoSignalStandardPalL          D3DkmdtVideoSignalStandard = 30
+	D3DkmdtVideoSignalStandardPalM          D3DkmdtVideoSignalStandard = 31
+	D3DkmdtVideoSignalStandardOther         D3DkmdtVideoSignalStandard = 255
+	D3DkmdtVideoSignalStandardUSB           D3DkmdtVideoSignalStandard = 65791
+)
+
+type DisplayConfigVideoSignalInfo struct {
+	PixelRate        int64
+	HSyncFreq        DisplayConfigRational
+	VSyncFreq        DisplayConfigRational
+	ActiveSize       DisplayConfig2DRegion
+	TotalSize        DisplayConfig2DRegion
+	VideoStandard    D3DkmdtVideoSignalStandard
+	ScanLineOrdering DisplayConfigScanLineOrdering
+}
+
+type DisplayConfigTargetMode struct {
+	TargetVideoSignalInfo DisplayConfigVideoSignalInfo
+}
+
+type PointL struct {
+	X int32
+	Y int32
+}
+
+type RectL struct {
+	Left   int32
+	Top    int32
+	Right  int32
+	Bottom int32
+}
+
+type DisplayConfigSourceMode struct {
+	Width       uint32
+	Height      uint32
+	PixelFormat DisplayConfigPixelFormat
+	Position    PointL
+}
+
+type DisplayConfigDesktopImageInfo struct {
+	PathSourceSize     PointL
+	DesktopImageRegion RectL
+	DesktopImageClip   RectL
+}
+
+type DisplayConfigPathSourceInfo struct {
+	AdapterID   LUID
+	ID          uint32
+	ModeInfoIdx uint32
+	StatusFlags DisplayConfigSourceStatus
+}
+
+type DisplayConfigPathTargetInfo struct {
+	AdapterID        LUID
+	ID               uint32
+	ModeInfoIdx      uint32
+	OutputTechnology DisplayConfigVideoOutputTechnology
+	Rotation         DisplayConfigRotation
+	Scaling          DisplayConfigScaling
+	RefreshRate      DisplayConfigRational
+	ScanLineOrdering DisplayConfigScanLineOrdering
+	TargetAvailable  uint32
+	StatusFlags      DisplayConfigTargetStatus
+}
+
+type QueryDisplayFlags uint32
+
+const (
+	QueryDisplayFlagsZero             QueryDisplayFlags = 0x0
+	QueryDisplayFlagsAllPaths         QueryDisplayFlags = 0x00000001
+	QueryDisplayFlagsOnlyActivePaths  QueryDisplayFlags = 0x00000002
+	QueryDisplayFlagsDatabaseCurrent  QueryDisplayFlags = 0x00000004
+	QueryDisplayFlagsVirtualModeAware QueryDisplayFlags = 0x00000010
+	QueryDisplayFlagsIncludeHMD       QueryDisplayFlags = 0x00000020
+)
+
+type DisplayConfigDeviceInfoType uint32
+
+const (
+	DisplayConfigDeviceInfoTypeGetSourceName               DisplayConfigDeviceInfoType = 1
+	DisplayConfigDeviceInfoTypeGetTargetName               DisplayConfigDeviceInfoType = 2
+	DisplayConfigDeviceInfoTypeGetTargetPreferredMode      DisplayConfigDeviceInfoType = 3
+	DisplayConfigDeviceInfoTypeGetAdapterName              DisplayConfigDeviceInfoType = 4
+	DisplayConfigDeviceInfoTypeSetTargetPersistence        DisplayConfigDeviceInfoType = 5
+	DisplayConfigDeviceInfoTypeGetTargetBaseType           DisplayConfigDeviceInfoType = 6
+	DisplayConfigDeviceInfoTypeGetSupportVirtualResolution DisplayConfigDeviceInfoType = 7
+	DisplayConfigDeviceInfoTypeSetSupportVirtualResolution DisplayConfigDeviceInfoType = 8
+	DisplayConfigDeviceInfoTypeAdvancedColorInfo           DisplayConfigDeviceInfoType = 9
+	DisplayConfigDeviceInfoTypeAdvancedColorState          DisplayConfigDeviceInfoType = 10
+	DisplayConfigDeviceInfoTypeSDRWhiteLevel               DisplayConfigDeviceInfoType = 11
+	DisplayConfigDeviceInfoTypeForceUint32                 DisplayConfigDeviceInfoType = 0xFFFFFFFF
+)
+
+type DisplayConfigTargetDeviceNameFlags struct {
+	Value uint32
+}
+
+type DisplayConfigDeviceInfoHeader struct {
+	Type      DisplayConfigDeviceInfoType
+	Size      uint32
+	AdapterID LUID
+	ID        uint32
+}
+
+type DisplayConfigTargetDeviceName struct {
+	Header                    DisplayConfigDeviceInfoHeader
+	Flags                     DisplayConfigTargetDeviceNameFlags
+	OutputTechnology          DisplayConfigVideoOutputTechnology
+	EdidManufactureID         uint16
+	EdidProductCodeID         uint16
+	ConnectorInstance         uint32
+	MonitorFriendlyDeviceName [64]uint16
+	MonitorDevicePath         [128]uint16
+}
+
+func (n *DisplayConfigTargetDeviceName) FriendlyName() string {
+	return utf16ToString(n.MonitorFriendlyDeviceName[:])
+}
+
+func (n *DisplayConfigTargetDeviceName) DevicePath() string {
+	return utf16ToString(n.MonitorDevicePath[:])
+}
+
+func (n *DisplayConfigTargetDeviceName) SetFriendlyName(name string) {
+	putUTF16(n.MonitorFriendlyDeviceName[:], name)
+}
+
+func (n *DisplayConfigTargetDeviceName) SetDevicePath(path string) {
+	putUTF16(n.MonitorDevicePath[:], path)
+}
+
+func utf16ToString(s []uint16) string {
+	for i, v := range s {
+		if v == 0 {
+			s = s[:i]
+			break
+		}
+	}
+	return string(utf16.Decode(s))
+}
+
+// putUTF16 writes a NUL-terminated, truncated copy of s into dst.
+func putUTF16(dst []uint16, s string) {
+	encoded := utf16.Encode([]rune(s))
+	if len(encoded) > len(dst)-1 {
+		encoded = encoded[:len(dst)-1]
+	}
+	n := copy(dst, encoded)
+	for i := n; i < len(dst); i++ {
+		dst[i] = 0
+	}
+}
+
+type MonitorAdditionalInfo struct {
+	ManufactureID         uint16
+	ProductCodeID         uint16
+	Valid                 bool
+	MonitorDevicePath     string
+	MonitorFriendlyDevice string
+	ConnectorInstance     uint32
+	OutputTechnology      DisplayConfigVideoOutputTechnology
+}
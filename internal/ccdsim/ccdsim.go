@@ -0,0 +1,614 @@
+// Package ccdsim simulates the Windows CCD (Connecting and Configuring
+// Displays) API for a fixed set of adapters and targets. A Machine implements
+// ccd.DisplayBackend, validates SetDisplayConfig input the way user32 does
+// and answers with the same Win32 status codes, so the switcher's apply and
+// fallback logic can be exercised on any OS.
+package ccdsim
+
+import (
+	"unsafe"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// Monitor is the EDID identity reported for a connected target.
+type Monitor struct {
+	ManufactureID     uint16
+	ProductCodeID     uint16
+	ConnectorInstance uint32
+	FriendlyName      string
+	DevicePath        string
+}
+
+// Target is a video output on an adapter.
+type Target struct {
+	ID               uint32
+	OutputTechnology ccd.DisplayConfigVideoOutputTechnology
+	// Present marks the target as connected (TargetAvailable).
+	Present bool
+	// VirtualModeCapable allows virtual-mode-aware paths and desktop image
+	// modes on this target, as on WDDM 2.x drivers and virtual displays.
+	VirtualModeCapable bool
+	// Resolutions restricts the accepted target active sizes. Empty
+	// accepts any size.
+	Resolutions []ccd.DisplayConfig2DRegion
+	Monitor     Monitor
+}
+
+// Adapter is a display adapter with its sources and targets.
+type Adapter struct {
+	ID      ccd.LUID
+	Sources []uint32
+	Targets []Target
+}
+
+// ActivePath is one active source-to-target path and the modes it uses.
+// The ModeInfoIdx fields of Path are ignored; indices are assigned when the
+// configuration is queried.
+type ActivePath struct {
+	Path    ccd.DisplayConfigPathInfo
+	Source  ccd.DisplayConfigSourceMode
+	Target  ccd.DisplayConfigTargetMode
+	Desktop *ccd.DisplayConfigDesktopImageInfo
+}
+
+type deviceKey struct {
+	adapter ccd.LUID
+	id      uint32
+}
+
+type pathKey struct {
+	adapter  ccd.LUID
+	sourceID uint32
+	targetID uint32
+}
+
+// Machine is an in-memory display configuration.
+type Machine struct {
+	adapters  []Adapter
+	active    []ActivePath
+	setErrors []uint32
+	setCalls  int
+	applied   int
+	adjust    func([]ActivePath)
+}
+
+var _ ccd.DisplayBackend = (*Machine)(nil)
+
+// New returns a machine with the given adapters and no active paths.
+func New(adapters ...Adapter) *Machine {
+	m := &Machine{adapters: make([]Adapter, len(adapters))}
+	for i, adapter := range adapters {
+		adapter.Sources = append([]uint32(nil), adapter.Sources...)
+		adapter.Targets = append([]Target(nil), adapter.Targets...)
+		m.adapters[i] = adapter
+	}
+	return m
+}
+
+// Activate adds an active path without going through SetDisplayConfig. It
+// returns the status code SetDisplayConfig would have produced for it.
+func (m *Machine) Activate(path ActivePath) uint32 {
+	adapter, target, code := m.lookupPath(path.Path.SourceInfo.AdapterID, path.Path.SourceInfo.ID, path.Path.TargetInfo.AdapterID, path.Path.TargetInfo.ID)
+	if code != ccd.ErrorSuccess {
+		return code
+	}
+	if !target.Present {
+		return ccd.ErrorBadConfiguration
+	}
+	path.Path.SourceInfo.AdapterID = adapter.ID
+	path.Path.TargetInfo.AdapterID = adapter.ID
+	path.Path.TargetInfo.OutputTechnology = target.OutputTechnology
+	if path.Desktop != nil {
+		desktop := *path.Desktop
+		path.Desktop = &desktop
+	}
+	m.active = append(m.active, path)
+	return ccd.ErrorSuccess
+}
+
+// Active returns a copy of the active paths.
+func (m *Machine) Active() []ActivePath {
+	return append([]ActivePath(nil), m.active...)
+}
+
+// SetPresent connects or disconnects a target. Disconnecting drops any
+// active path that drives it, as Windows does on hot-unplug.
+func (m *Machine) SetPresent(adapterID ccd.LUID, targetID uint32, present bool) bool {
+	target := m.findTarget(adapterID, targetID)
+	if target == nil {
+		return false
+	}
+	target.Present = present
+	if !present {
+		kept := m.active[:0]
+		for _, path := range m.active {
+			if !(path.Path.TargetInfo.AdapterID == adapterID && path.Path.TargetInfo.ID == targetID) {
+				kept = append(kept, path)
+			}
+		}
+		m.active = kept
+	}
+	return true
+}
+
+// FailSetDisplayConfig queues status codes returned by the next
+// SetDisplayConfig calls before any validation. ErrorSuccess entries let a
+// call through to normal processing.
+func (m *Machine) FailSetDisplayConfig(codes ...uint32) {
+	m.setErrors = append(m.setErrors, codes...)
+}
+
+// AdjustApplied sets a function that rewrites every configuration committed
+// with SdcFlagsApply before it becomes active, the way Windows "optimizes"
+// a request by moving monitors or picking a nearby refresh rate.
+func (m *Machine) AdjustApplied(adjust func([]ActivePath)) {
+	m.adjust = adjust
+}
+
+// SetCalls is the number of SetDisplayConfig calls made so far.
+func (m *Machine) SetCalls() int {
+	return m.setCalls
+}
+
+// Applied is the number of configurations committed with SdcFlagsApply.
+func (m *Machine) Applied() int {
+	return m.applied
+}
+
+func (m *Machine) GetDisplayConfigBufferSizes(flags ccd.QueryDisplayFlags, numPaths *uint32, numModes *uint32) uint32 {
+	if numPaths == nil || numModes == nil || !validQueryFlags(flags) {
+		return ccd.ErrorInvalidParameter
+	}
+	paths, modes := m.snapshot(flags)
+	*numPaths = uint32(len(paths))
+	*numModes = uint32(len(modes))
+	return ccd.ErrorSuccess
+}
+
+func (m *Machine) QueryDisplayConfig(flags ccd.QueryDisplayFlags, numPaths *uint32, paths []ccd.DisplayConfigPathInfo, numModes *uint32, modes []ccd.DisplayConfigModeInfo) uint32 {
+	if numPaths == nil || numModes == nil || !validQueryFlags(flags) {
+		return ccd.ErrorInvalidParameter
+	}
+	if int(*numPaths) > len(paths) || int(*numModes) > len(modes) {
+		return ccd.ErrorInvalidParameter
+	}
+	snapPaths, snapModes := m.snapshot(flags)
+	if len(snapPaths) > int(*numPaths) || len(snapModes) > int(*numModes) {
+		return ccd.ErrorInsufficientBuffer
+	}
+	*numPaths = uint32(copy(paths, snapPaths))
+	*numModes = uint32(copy(modes, snapModes))
+	return ccd.ErrorSuccess
+}
+
+func (m *Machine) DisplayConfigGetDeviceInfo(request *ccd.DisplayConfigDeviceInfoHeader) uint32 {
+	if request == nil {
+		return ccd.ErrorInvalidParameter
+	}
+	if request.Type != ccd.DisplayConfigDeviceInfoTypeGetTargetName {
+		return ccd.ErrorNotSupported
+	}
+	if request.Size != uint32(unsafe.Sizeof(ccd.DisplayConfigTargetDeviceName{})) {
+		return ccd.ErrorInvalidParameter
+	}
+	target := m.findTarget(request.AdapterID, request.ID)
+	if target == nil {
+		return ccd.ErrorInvalidParameter
+	}
+
+	name := (*ccd.DisplayConfigTargetDeviceName)(unsafe.Pointer(request))
+	name.Flags = ccd.DisplayConfigTargetDeviceNameFlags{}
+	name.OutputTechnology = target.OutputTechnology
+	name.EdidManufactureID = 0
+	name.EdidProductCodeID = 0
+	name.ConnectorInstance = target.Monitor.ConnectorInstance
+	name.SetFriendlyName("")
+	name.SetDevicePath(target.Monitor.DevicePath)
+	if target.Present {
+		// friendlyNameFromEdid | edidIdsValid
+		name.Flags.Value = 0x1 | 0x4
+		name.EdidManufactureID = target.Monitor.ManufactureID
+		name.EdidProductCodeID = target.Monitor.ProductCodeID
+		name.SetFriendlyName(target.Monitor.FriendlyName)
+	}
+	return ccd.ErrorSuccess
+}
+
+func (m *Machine) SetDisplayConfig(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, flags ccd.SdcFlags) uint32 {
+	m.setCalls++
+	if len(m.setErrors) > 0 {
+		code := m.setErrors[0]
+		m.setErrors = m.setErrors[1:]
+		if code != ccd.ErrorSuccess {
+			return code
+		}
+	}
+
+	switch flags & (ccd.SdcFlagsApply | ccd.SdcFlagsValidate) {
+	case ccd.SdcFlagsApply:
+	case ccd.SdcFlagsValidate:
+		if flags&ccd.SdcFlagsSaveToDatabase != 0 {
+			return ccd.ErrorInvalidParameter
+		}
+	default:
+		return ccd.ErrorInvalidParameter
+	}
+	topology := flags & (ccd.SdcFlagsUseDatabaseCurrent | ccd.SdcFlagsTopologySupplied)
+	if flags&ccd.SdcFlagsUseSuppliedDisplayConfig == 0 {
+		if len(paths) != 0 || len(modes) != 0 || topology == 0 {
+			return ccd.ErrorInvalidParameter
+		}
+		// Database topologies are not modelled; the current layout stays.
+		return ccd.ErrorSuccess
+	}
+	if topology != 0 || len(paths) == 0 {
+		return ccd.ErrorInvalidParameter
+	}
+
+	next, code := m.validate(paths, modes, flags)
+	if code != ccd.ErrorSuccess {
+		return code
+	}
+	if flags&ccd.SdcFlagsApply != 0 {
+		if m.adjust != nil {
+			m.adjust(next)
+		}
+		m.active = next
+		m.applied++
+	}
+	return ccd.ErrorSuccess
+}
+
+func (m *Machine) validate(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, flags ccd.SdcFlags) ([]ActivePath, uint32) {
+	virtualAware := flags&ccd.SdcFlagsVirtualModeAware != 0
+
+	for _, mode := range modes {
+		switch mode.InfoType {
+		case ccd.DisplayConfigModeInfoTypeSource, ccd.DisplayConfigModeInfoTypeTarget:
+		case ccd.DisplayConfigModeInfoTypeDesktopImage:
+			if !virtualAware {
+				return nil, ccd.ErrorInvalidParameter
+			}
+		default:
+			return nil, ccd.ErrorInvalidParameter
+		}
+	}
+
+	usedTargets := make(map[deviceKey]bool)
+	sourceModes := make(map[deviceKey]ccd.DisplayConfigSourceMode)
+
+	var next []ActivePath
+	for i := range paths {
+		path := paths[i]
+		if path.Flags&uint32(ccd.DisplayConfigFlagPathActive) == 0 {
+			continue
+		}
+		if path.Flags&^uint32(ccd.DisplayConfigFlagPathValidFlags) != 0 {
+			return nil, ccd.ErrorInvalidParameter
+		}
+		if path.VirtualModeAware() && !virtualAware {
+			return nil, ccd.ErrorInvalidParameter
+		}
+
+		adapter, target, code := m.lookupPath(path.SourceInfo.AdapterID, path.SourceInfo.ID, path.TargetInfo.AdapterID, path.TargetInfo.ID)
+		if code != ccd.ErrorSuccess {
+			return nil, code
+		}
+		if path.VirtualModeAware() && !target.VirtualModeCapable {
+			return nil, ccd.ErrorInvalidParameter
+		}
+		targetKey := deviceKey{adapter: adapter.ID, id: target.ID}
+		if usedTargets[targetKey] {
+			return nil, ccd.ErrorInvalidParameter
+		}
+		usedTargets[targetKey] = true
+		if !validRotation(path.TargetInfo.Rotation) || !validScaling(path.TargetInfo.Scaling) {
+			return nil, ccd.ErrorInvalidParameter
+		}
+		if !target.Present {
+			return nil, ccd.ErrorBadConfiguration
+		}
+
+		current := m.findActive(adapter.ID, path.SourceInfo.ID, target.ID)
+		active := ActivePath{Path: path}
+
+		sourceIdx, ok := path.SourceModeIdx()
+		switch {
+		case ok:
+			mode, code := modeAt(modes, sourceIdx, ccd.DisplayConfigModeInfoTypeSource, adapter.ID, path.SourceInfo.ID)
+			if code != ccd.ErrorSuccess {
+				return nil, code
+			}
+			active.Source = *mode.SourceMode()
+		case current != nil:
+			active.Source = current.Source
+		default:
+			return nil, ccd.ErrorBadConfiguration
+		}
+		key := deviceKey{adapter: adapter.ID, id: path.SourceInfo.ID}
+		if previous, ok := sourceModes[key]; ok && previous != active.Source {
+			// Clones share one source and therefore one source mode.
+			return nil, ccd.ErrorInvalidParameter
+		}
+		sourceModes[key] = active.Source
+
+		targetIdx, ok := path.TargetModeIdx()
+		switch {
+		case ok:
+			mode, code := modeAt(modes, targetIdx, ccd.DisplayConfigModeInfoTypeTarget, adapter.ID, target.ID)
+			if code != ccd.ErrorSuccess {
+				return nil, code
+			}
+			active.Target = *mode.TargetMode()
+		case current != nil && (flags&ccd.SdcFlagsAllowChanges == 0 || sameActiveSize(current.Target, active.Source, path.TargetInfo.Rotation)):
+			active.Target = current.Target
+		case flags&ccd.SdcFlagsAllowChanges != 0:
+			// Windows picks timings that show the source mode unscaled.
+			active.Target = pickTargetMode(active.Source, path.TargetInfo.Rotation, path.TargetInfo.RefreshRate)
+		default:
+			return nil, ccd.ErrorBadConfiguration
+		}
+		if !supportsResolution(target, active.Target.TargetVideoSignalInfo.ActiveSize) {
+			return nil, ccd.ErrorGenFailure
+		}
+
+		if desktopIdx, ok := path.DesktopModeIdx(); ok {
+			mode, code := modeAt(modes, desktopIdx, ccd.DisplayConfigModeInfoTypeDesktopImage, adapter.ID, target.ID)
+			if code != ccd.ErrorSuccess {
+				return nil, code
+			}
+			desktop := *mode.DesktopImageInfo()
+			active.Desktop = &desktop
+		}
+
+		active.Path.SourceInfo.AdapterID = adapter.ID
+		active.Path.TargetInfo.AdapterID = adapter.ID
+		active.Path.TargetInfo.OutputTechnology = target.OutputTechnology
+		next = append(next, active)
+	}
+
+	if len(next) == 0 {
+		return nil, ccd.ErrorInvalidParameter
+	}
+	return next, ccd.ErrorSuccess
+}
+
+// snapshot renders the configuration the way QueryDisplayConfig reports it
+// for the given flags.
+func (m *Machine) snapshot(flags ccd.QueryDisplayFlags) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+	virtualAware := flags&ccd.QueryDisplayFlagsVirtualModeAware != 0
+
+	var paths []ccd.DisplayConfigPathInfo
+	var modes []ccd.DisplayConfigModeInfo
+	sourceIdxByKey := make(map[deviceKey]int)
+	activeKeys := make(map[pathKey]bool)
+
+	for _, active := range m.active {
+		path := active.Path
+		target := m.findTarget(path.TargetInfo.AdapterID, path.TargetInfo.ID)
+		activeKeys[pathKey{adapter: path.SourceInfo.AdapterID, sourceID: path.SourceInfo.ID, targetID: path.TargetInfo.ID}] = true
+
+		key := deviceKey{adapter: path.SourceInfo.AdapterID, id: path.SourceInfo.ID}
+		sourceIdx, ok := sourceIdxByKey[key]
+		if !ok {
+			sourceIdx = len(modes)
+			sourceIdxByKey[key] = sourceIdx
+			mode := ccd.DisplayConfigModeInfo{
+				InfoType:  ccd.DisplayConfigModeInfoTypeSource,
+				ID:        path.SourceInfo.ID,
+				AdapterID: path.SourceInfo.AdapterID,
+			}
+			mode.SetSourceMode(active.Source)
+			modes = append(modes, mode)
+		}
+
+		targetIdx := len(modes)
+		targetMode := ccd.DisplayConfigModeInfo{
+			InfoType:  ccd.DisplayConfigModeInfoTypeTarget,
+			ID:        path.TargetInfo.ID,
+			AdapterID: path.TargetInfo.AdapterID,
+		}
+		targetMode.SetTargetMode(active.Target)
+		modes = append(modes, targetMode)
+
+		path.Flags = uint32(ccd.DisplayConfigFlagPathActive) | path.Flags&uint32(ccd.DisplayConfigFlagPathPreferredUnscaled)
+		path.SourceInfo.StatusFlags = ccd.DisplayConfigSourceStatusInUse
+		path.TargetInfo.StatusFlags = ccd.DisplayConfigTargetStatusInUse
+		path.TargetInfo.TargetAvailable = 1
+
+		if virtualAware && target.VirtualModeCapable {
+			desktop := active.Desktop
+			if desktop == nil {
+				desktop = defaultDesktopImage(active.Source)
+			}
+			desktopIdx := len(modes)
+			desktopMode := ccd.DisplayConfigModeInfo{
+				InfoType:  ccd.DisplayConfigModeInfoTypeDesktopImage,
+				ID:        path.TargetInfo.ID,
+				AdapterID: path.TargetInfo.AdapterID,
+			}
+			desktopMode.SetDesktopImageInfo(*desktop)
+			modes = append(modes, desktopMode)
+
+			path.Flags |= uint32(ccd.DisplayConfigFlagPathSupportVirtualMode)
+			path.SourceInfo.ModeInfoIdx = ccd.PackSourceModeIdx(uint32(sourceIdx), sourceIdx)
+			path.TargetInfo.ModeInfoIdx = ccd.PackTargetModeIdx(targetIdx, desktopIdx)
+		} else {
+			path.SourceInfo.ModeInfoIdx = uint32(sourceIdx)
+			path.TargetInfo.ModeInfoIdx = uint32(targetIdx)
+		}
+		paths = append(paths, path)
+	}
+
+	if flags&ccd.QueryDisplayFlagsAllPaths == 0 {
+		return paths, modes
+	}
+
+	for _, adapter := range m.adapters {
+		for _, target := range adapter.Targets {
+			for _, sourceID := range adapter.Sources {
+				if activeKeys[pathKey{adapter: adapter.ID, sourceID: sourceID, targetID: target.ID}] {
+					continue
+				}
+				path := ccd.DisplayConfigPathInfo{}
+				path.SourceInfo.AdapterID = adapter.ID
+				path.SourceInfo.ID = sourceID
+				path.SourceInfo.ModeInfoIdx = ccd.DisplayConfigPathModeIdxInvalid
+				path.TargetInfo.AdapterID = adapter.ID
+				path.TargetInfo.ID = target.ID
+				path.TargetInfo.ModeInfoIdx = ccd.DisplayConfigPathModeIdxInvalid
+				path.TargetInfo.OutputTechnology = target.OutputTechnology
+				path.TargetInfo.Rotation = ccd.DisplayConfigRotationIdentity
+				path.TargetInfo.Scaling = ccd.DisplayConfigScalingPreferred
+				if target.Present {
+					path.TargetInfo.TargetAvailable = 1
+				}
+				if virtualAware && target.VirtualModeCapable {
+					path.Flags |= uint32(ccd.DisplayConfigFlagPathSupportVirtualMode)
+				}
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths, modes
+}
+
+func (m *Machine) lookupPath(sourceAdapter ccd.LUID, sourceID uint32, targetAdapter ccd.LUID, targetID uint32) (*Adapter, *Target, uint32) {
+	if sourceAdapter != targetAdapter {
+		return nil, nil, ccd.ErrorInvalidParameter
+	}
+	adapter := m.findAdapter(sourceAdapter)
+	if adapter == nil {
+		return nil, nil, ccd.ErrorInvalidParameter
+	}
+	hasSource := false
+	for _, id := range adapter.Sources {
+		if id == sourceID {
+			hasSource = true
+			break
+		}
+	}
+	if !hasSource {
+		return nil, nil, ccd.ErrorInvalidParameter
+	}
+	target := m.findTarget(targetAdapter, targetID)
+	if target == nil {
+		return nil, nil, ccd.ErrorInvalidParameter
+	}
+	return adapter, target, ccd.ErrorSuccess
+}
+
+func (m *Machine) findAdapter(id ccd.LUID) *Adapter {
+	for i := range m.adapters {
+		if m.adapters[i].ID == id {
+			return &m.adapters[i]
+		}
+	}
+	return nil
+}
+
+func (m *Machine) findTarget(adapterID ccd.LUID, targetID uint32) *Target {
+	adapter := m.findAdapter(adapterID)
+	if adapter == nil {
+		return nil
+	}
+	for i := range adapter.Targets {
+		if adapter.Targets[i].ID == targetID {
+			return &adapter.Targets[i]
+		}
+	}
+	return nil
+}
+
+func (m *Machine) findActive(adapterID ccd.LUID, sourceID uint32, targetID uint32) *ActivePath {
+	for i := range m.active {
+		path := &m.active[i].Path
+		if path.SourceInfo.AdapterID == adapterID && path.SourceInfo.ID == sourceID && path.TargetInfo.ID == targetID {
+			return &m.active[i]
+		}
+	}
+	return nil
+}
+
+func modeAt(modes []ccd.DisplayConfigModeInfo, idx int, infoType ccd.DisplayConfigModeInfoType, adapterID ccd.LUID, id uint32) (*ccd.DisplayConfigModeInfo, uint32) {
+	if idx < 0 || idx >= len(modes) {
+		return nil, ccd.ErrorInvalidParameter
+	}
+	mode := &modes[idx]
+	if mode.InfoType != infoType || mode.AdapterID != adapterID || mode.ID != id {
+		return nil, ccd.ErrorInvalidParameter
+	}
+	return mode, ccd.ErrorSuccess
+}
+
+func validQueryFlags(flags ccd.QueryDisplayFlags) bool {
+	base := flags & (ccd.QueryDisplayFlagsAllPaths | ccd.QueryDisplayFlagsOnlyActivePaths | ccd.QueryDisplayFlagsDatabaseCurrent)
+	switch base {
+	case ccd.QueryDisplayFlagsAllPaths, ccd.QueryDisplayFlagsOnlyActivePaths, ccd.QueryDisplayFlagsDatabaseCurrent:
+	default:
+		return false
+	}
+	known := ccd.QueryDisplayFlagsAllPaths | ccd.QueryDisplayFlagsOnlyActivePaths | ccd.QueryDisplayFlagsDatabaseCurrent |
+		ccd.QueryDisplayFlagsVirtualModeAware | ccd.QueryDisplayFlagsIncludeHMD
+	return flags&^known == 0
+}
+
+func validRotation(rotation ccd.DisplayConfigRotation) bool {
+	return rotation >= ccd.DisplayConfigRotationIdentity && rotation <= ccd.DisplayConfigRotationRotate270
+}
+
+func validScaling(scaling ccd.DisplayConfigScaling) bool {
+	switch scaling {
+	case ccd.DisplayConfigScalingIdentity, ccd.DisplayConfigScalingCentered, ccd.DisplayConfigScalingStretched,
+		ccd.DisplayConfigScalingAspectRatioCenteredMax, ccd.DisplayConfigScalingCustom, ccd.DisplayConfigScalingPreferred:
+		return true
+	}
+	return false
+}
+
+func supportsResolution(target *Target, size ccd.DisplayConfig2DRegion) bool {
+	if len(target.Resolutions) == 0 {
+		return true
+	}
+	for _, resolution := range target.Resolutions {
+		if resolution == size {
+			return true
+		}
+	}
+	return false
+}
+
+// rotatedSize is the target size that shows source at rotation.
+func rotatedSize(source ccd.DisplayConfigSourceMode, rotation ccd.DisplayConfigRotation) ccd.DisplayConfig2DRegion {
+	if rotation == ccd.DisplayConfigRotationRotate90 || rotation == ccd.DisplayConfigRotationRotate270 {
+		return ccd.DisplayConfig2DRegion{Cx: source.Height, Cy: source.Width}
+	}
+	return ccd.DisplayConfig2DRegion{Cx: source.Width, Cy: source.Height}
+}
+
+func sameActiveSize(target ccd.DisplayConfigTargetMode, source ccd.DisplayConfigSourceMode, rotation ccd.DisplayConfigRotation) bool {
+	return target.TargetVideoSignalInfo.ActiveSize == rotatedSize(source, rotation)
+}
+
+func pickTargetMode(source ccd.DisplayConfigSourceMode, rotation ccd.DisplayConfigRotation, refresh ccd.DisplayConfigRational) ccd.DisplayConfigTargetMode {
+	var mode ccd.DisplayConfigTargetMode
+	size := rotatedSize(source, rotation)
+	mode.TargetVideoSignalInfo.ActiveSize = size
+	mode.TargetVideoSignalInfo.TotalSize = size
+	if refresh.Denominator == 0 {
+		refresh = ccd.DisplayConfigRational{Numerator: 60, Denominator: 1}
+	}
+	mode.TargetVideoSignalInfo.VSyncFreq = refresh
+	return mode
+}
+
+func defaultDesktopImage(source ccd.DisplayConfigSourceMode) *ccd.DisplayConfigDesktopImageInfo {
+	width := int32(source.Width)
+	height := int32(source.Height)
+	return &ccd.DisplayConfigDesktopImageInfo{
+		PathSourceSize:     ccd.PointL{X: width, Y: height},
+		DesktopImageRegion: ccd.RectL{Right: width, Bottom: height},
+		DesktopImageClip:   ccd.RectL{Right: width, Bottom: height},
+	}
+}
@@ -0,0 +1,277 @@
+package ccdsim_test
+
+import (
+	"errors"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+)
+
+var adapterID = ccd.LUID{LowPart: 0x1234}
+
+// newMachine returns a machine with two connected monitors on sources 0 and
+// 1, side by side at 1920x1080, and one disconnected target.
+func newMachine() *ccdsim.Machine {
+	m := ccdsim.New(ccdsim.Adapter{ID: adapterID, Sources: []uint32{0, 1}, Targets: []ccdsim.Target{
+		{ID: 100, Present: true, VirtualModeCapable: true, Monitor: ccdsim.Monitor{ManufactureID: 0x10AC, ProductCodeID: 0x4123, FriendlyName: "DELL P2419H", DevicePath: `\\?\DISPLAY#DEL4123#1`}},
+		{ID: 200, Present: true, Resolutions: []ccd.DisplayConfig2DRegion{{Cx: 1920, Cy: 1080}}, Monitor: ccdsim.Monitor{FriendlyName: "LG HDR 4K"}},
+		{ID: 300},
+	}})
+	for i, targetID := range []uint32{100, 200} {
+		m.Activate(activePath(uint32(i), targetID, int32(1920*i)))
+	}
+	return m
+}
+
+func activePath(sourceID, targetID uint32, x int32) ccdsim.ActivePath {
+	var path ccd.DisplayConfigPathInfo
+	path.Flags = uint32(ccd.DisplayConfigFlagPathActive)
+	path.SourceInfo.AdapterID = adapterID
+	path.SourceInfo.ID = sourceID
+	path.TargetInfo.AdapterID = adapterID
+	path.TargetInfo.ID = targetID
+	path.TargetInfo.Rotation = ccd.DisplayConfigRotationIdentity
+	path.TargetInfo.Scaling = ccd.DisplayConfigScalingIdentity
+	var target ccd.DisplayConfigTargetMode
+	target.TargetVideoSignalInfo.ActiveSize = ccd.DisplayConfig2DRegion{Cx: 1920, Cy: 1080}
+	return ccdsim.ActivePath{
+		Path:   path,
+		Source: ccd.DisplayConfigSourceMode{Width: 1920, Height: 1080, Position: ccd.PointL{X: x}},
+		Target: target,
+	}
+}
+
+func query(t *testing.T, m *ccdsim.Machine, flags ccd.QueryDisplayFlags) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo, []ccd.MonitorAdditionalInfo) {
+	t.Helper()
+	paths, modes, additional, err := ccd.GetDisplaySettingsWithFlags(m, flags)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	return paths, modes, additional
+}
+
+func TestQuery(t *testing.T) {
+	m := newMachine()
+
+	paths, modes, additional := query(t, m, ccd.QueryDisplayFlagsOnlyActivePaths)
+	if len(paths) != 2 || len(modes) != 4 {
+		t.Fatalf("got %d paths and %d modes, want 2 and 4", len(paths), len(modes))
+	}
+	for _, path := range paths {
+		sourceIdx, ok := path.SourceModeIdx()
+		if !ok || modes[sourceIdx].InfoType != ccd.DisplayConfigModeInfoTypeSource || modes[sourceIdx].ID != path.SourceInfo.ID {
+			t.Errorf("path to target %d: source mode index %d does not reference its source", path.TargetInfo.ID, sourceIdx)
+		}
+		targetIdx, ok := path.TargetModeIdx()
+		if !ok || modes[targetIdx].InfoType != ccd.DisplayConfigModeInfoTypeTarget || modes[targetIdx].ID != path.TargetInfo.ID {
+			t.Errorf("path to target %d: target mode index %d does not reference its target", path.TargetInfo.ID, targetIdx)
+		}
+		if _, ok := path.DesktopModeIdx(); ok {
+			t.Errorf("path to target %d: desktop mode without a virtual-mode-aware query", path.TargetInfo.ID)
+		}
+	}
+	if info := additional[1]; !info.Valid || info.ManufactureID != 0x10AC || info.MonitorFriendlyDevice != "DELL P2419H" {
+		t.Errorf("additional info for target 100 = %+v", info)
+	}
+
+	paths, modes, _ = query(t, m, ccd.QueryDisplayFlagsOnlyActivePaths|ccd.QueryDisplayFlagsVirtualModeAware)
+	if !paths[0].VirtualModeAware() || paths[1].VirtualModeAware() {
+		t.Errorf("virtual-mode-aware flags = %v, %v; want only the capable target", paths[0].VirtualModeAware(), paths[1].VirtualModeAware())
+	}
+	if idx, ok := paths[0].DesktopModeIdx(); !ok || modes[idx].InfoType != ccd.DisplayConfigModeInfoTypeDesktopImage {
+		t.Errorf("desktop mode index %d, %v does not reference a desktop image mode", idx, ok)
+	}
+
+	// Disconnected targets are filtered out of the all-paths query.
+	paths, _, _ = query(t, m, ccd.QueryDisplayFlagsAllPaths)
+	for _, path := range paths {
+		if path.TargetInfo.ID == 300 {
+			t.Errorf("all-paths query includes the disconnected target")
+		}
+	}
+	if len(paths) != 4 {
+		t.Errorf("all-paths query returned %d paths, want 4", len(paths))
+	}
+}
+
+func TestSetDisplayConfig(t *testing.T) {
+	apply := ccd.SdcFlagsApply | ccd.SdcFlagsUseSuppliedDisplayConfig | ccd.SdcFlagsAllowChanges
+
+	tests := []struct {
+		name    string
+		flags   ccd.SdcFlags
+		edit    func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo)
+		want    error
+		applied bool
+	}{
+		{
+			name:    "unchanged configuration",
+			flags:   apply,
+			applied: true,
+		},
+		{
+			name:  "validate only",
+			flags: ccd.SdcFlagsValidate | ccd.SdcFlagsUseSuppliedDisplayConfig,
+		},
+		{
+			name:  "validate and save",
+			flags: ccd.SdcFlagsValidate | ccd.SdcFlagsUseSuppliedDisplayConfig | ccd.SdcFlagsSaveToDatabase,
+			want:  ccd.ErrInvalidParameter,
+		},
+		{
+			name:  "disconnected target",
+			flags: apply,
+			edit: func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+				paths[1].TargetInfo.ID = 300
+				paths[1].TargetInfo.ModeInfoIdx = ccd.DisplayConfigPathModeIdxInvalid
+				return paths, modes
+			},
+			want: ccd.ErrBadConfiguration,
+		},
+		{
+			name:  "unknown target",
+			flags: apply,
+			edit: func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+				paths[1].TargetInfo.ID = 999
+				return paths, modes
+			},
+			want: ccd.ErrInvalidParameter,
+		},
+		{
+			name:  "target driven twice",
+			flags: apply,
+			edit: func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+				paths[1].TargetInfo.ID = paths[0].TargetInfo.ID
+				return paths, modes
+			},
+			want: ccd.ErrInvalidParameter,
+		},
+		{
+			name:  "mode index of another target",
+			flags: apply,
+			edit: func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+				paths[0].TargetInfo.ModeInfoIdx, paths[1].TargetInfo.ModeInfoIdx = paths[1].TargetInfo.ModeInfoIdx, paths[0].TargetInfo.ModeInfoIdx
+				return paths, modes
+			},
+			want: ccd.ErrInvalidParameter,
+		},
+		{
+			name:  "unsupported resolution",
+			flags: apply,
+			edit: func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+				idx, _ := paths[1].TargetModeIdx()
+				modes[idx].TargetMode().TargetVideoSignalInfo.ActiveSize = ccd.DisplayConfig2DRegion{Cx: 3840, Cy: 2160}
+				return paths, modes
+			},
+			want: ccd.ErrGenFailure,
+		},
+		{
+			name:  "invalid rotation",
+			flags: apply,
+			edit: func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+				paths[0].TargetInfo.Rotation = 0
+				return paths, modes
+			},
+			want: ccd.ErrInvalidParameter,
+		},
+		{
+			name:  "virtual mode without the flag",
+			flags: apply,
+			edit: func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+				paths[0].Flags |= uint32(ccd.DisplayConfigFlagPathSupportVirtualMode)
+				return paths, modes
+			},
+			want: ccd.ErrInvalidParameter,
+		},
+		{
+			name:  "clones with different source modes",
+			flags: apply,
+			edit: func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+				paths[1].SourceInfo.ID = paths[0].SourceInfo.ID
+				idx, _ := paths[1].SourceModeIdx()
+				modes[idx].ID = paths[0].SourceInfo.ID
+				return paths, modes
+			},
+			want: ccd.ErrInvalidParameter,
+		},
+		{
+			name:  "no active paths",
+			flags: apply,
+			edit: func(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+				for i := range paths {
+					paths[i].Flags = 0
+				}
+				return paths, modes
+			},
+			want: ccd.ErrInvalidParameter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMachine()
+			paths, modes, _ := query(t, m, ccd.QueryDisplayFlagsOnlyActivePaths)
+			if tt.edit != nil {
+				paths, modes = tt.edit(paths, modes)
+			}
+			err := ccd.SetDisplayConfig(m, paths, modes, tt.flags)
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("SetDisplayConfig() = %v, want %v", err, tt.want)
+			}
+			if applied := m.Applied() == 1; applied != tt.applied {
+				t.Errorf("applied = %v, want %v", applied, tt.applied)
+			}
+		})
+	}
+}
+
+func TestFailSetDisplayConfig(t *testing.T) {
+	m := newMachine()
+	paths, modes, _ := query(t, m, ccd.QueryDisplayFlagsOnlyActivePaths)
+	flags := ccd.SdcFlagsApply | ccd.SdcFlagsUseSuppliedDisplayConfig
+
+	m.FailSetDisplayConfig(ccd.ErrorAccessDenied, ccd.ErrorSuccess)
+	if err := ccd.SetDisplayConfig(m, paths, modes, flags); !errors.Is(err, ccd.ErrAccessDenied) {
+		t.Errorf("first call = %v, want ERROR_ACCESS_DENIED", err)
+	}
+	if err := ccd.SetDisplayConfig(m, paths, modes, flags); err != nil {
+		t.Errorf("second call = %v, want success", err)
+	}
+	if m.SetCalls() != 2 || m.Applied() != 1 {
+		t.Errorf("SetCalls() = %d, Applied() = %d; want 2 and 1", m.SetCalls(), m.Applied())
+	}
+}
+
+func TestAdjustApplied(t *testing.T) {
+	m := newMachine()
+	m.AdjustApplied(func(active []ccdsim.ActivePath) {
+		active[1].Source.Position.X += 8
+	})
+	paths, modes, _ := query(t, m, ccd.QueryDisplayFlagsOnlyActivePaths)
+	if err := ccd.SetDisplayConfig(m, paths, modes, ccd.SdcFlagsApply|ccd.SdcFlagsUseSuppliedDisplayConfig); err != nil {
+		t.Fatal(err)
+	}
+	if x := m.Active()[1].Source.Position.X; x != 1928 {
+		t.Errorf("adjusted position = %d, want 1928", x)
+	}
+}
+
+func TestSetPresent(t *testing.T) {
+	m := newMachine()
+	if !m.SetPresent(adapterID, 200, false) {
+		t.Fatal("SetPresent() did not find target 200")
+	}
+	if active := m.Active(); len(active) != 1 || active[0].Path.TargetInfo.ID != 100 {
+		t.Errorf("active paths after unplugging target 200: %+v", active)
+	}
+	if m.SetPresent(adapterID, 999, true) {
+		t.Error("SetPresent() found an unknown target")
+	}
+	var path ccd.DisplayConfigPathInfo
+	path.SourceInfo.AdapterID = adapterID
+	path.TargetInfo.AdapterID = adapterID
+	path.TargetInfo.ID = 300
+	if code := m.Activate(ccdsim.ActivePath{Path: path}); code != ccd.ErrorBadConfiguration {
+		t.Errorf("Activate() on a disconnected target = %d, want ERROR_BAD_CONFIGURATION", code)
+	}
+}
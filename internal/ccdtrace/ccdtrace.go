@@ -0,0 +1,343 @@
+// Package ccdtrace records the CCD calls made through a ccd.DisplayBackend
+// and replays them later, so a failing profile load from another machine can
+// be reproduced and debugged offline on any OS.
+package ccdtrace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+const traceVersion = 1
+
+// API names used in Call.API.
+const (
+	APIGetDisplayConfigBufferSizes = "GetDisplayConfigBufferSizes"
+	APIQueryDisplayConfig          = "QueryDisplayConfig"
+	APIDisplayConfigGetDeviceInfo  = "DisplayConfigGetDeviceInfo"
+	APISetDisplayConfig            = "SetDisplayConfig"
+)
+
+// Trace is the ordered list of CCD calls made by one invocation, with the
+// profiles it loaded so a replay sends the recorded input without them.
+// Traces from older builds have no profiles.
+type Trace struct {
+	Version  int       `json:"version"`
+	Recorded time.Time `json:"recorded"`
+	Args     []string  `json:"args,omitempty"`
+	Profiles []Profile `json:"profiles,omitempty"`
+	Calls    []Call    `json:"calls"`
+}
+
+// Profile is a profile file read by the recorded invocation, stored under
+// the command argument that named it. Name is the file name, whose
+// extension selects the encoding.
+type Profile struct {
+	Arg  string `json:"arg"`
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+// Profile returns the profile recorded for the command argument arg.
+func (t Trace) Profile(arg string) (Profile, bool) {
+	for _, p := range t.Profiles {
+		if p.Arg == arg {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// Call is one CCD call with its inputs, outputs and Win32 status code.
+//
+// GetDisplayConfigBufferSizes: Flags in; NumPaths/NumModes out.
+// QueryDisplayConfig: Flags and NumPaths/NumModes (buffer capacity) in;
+// Paths/Modes out.
+// DisplayConfigGetDeviceInfo: Request in; Response (the raw request
+// structure of Request.Size bytes after the call) out.
+// SetDisplayConfig: Paths, Modes and Flags in.
+type Call struct {
+	API      string                             `json:"api"`
+	Flags    uint32                             `json:"flags,omitempty"`
+	NumPaths uint32                             `json:"numPaths,omitempty"`
+	NumModes uint32                             `json:"numModes,omitempty"`
+	Paths    []ccd.DisplayConfigPathInfo        `json:"paths,omitempty"`
+	Modes    []ccd.DisplayConfigModeInfo        `json:"modes,omitempty"`
+	Request  *ccd.DisplayConfigDeviceInfoHeader `json:"request,omitempty"`
+	Response []byte                             `json:"response,omitempty"`
+	Status   uint32                             `json:"status"`
+}
+
+func Load(path string) (Trace, error) {
+	var trace Trace
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return trace, fmt.Errorf("read trace: %w", err)
+	}
+	if err := json.Unmarshal(data, &trace); err != nil {
+		return trace, fmt.Errorf("parse trace: %w", err)
+	}
+	if trace.Version != traceVersion {
+		return trace, fmt.Errorf("unsupported trace version %d", trace.Version)
+	}
+	return trace, nil
+}
+
+func Save(path string, trace Trace) error {
+	data, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		return fmt.Errorf("serialize trace: %w", err)
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write trace: %w", err)
+	}
+	return nil
+}
+
+// Recorder passes every call through to another backend and records it.
+type Recorder struct {
+	backend ccd.DisplayBackend
+	trace   Trace
+}
+
+var _ ccd.DisplayBackend = (*Recorder)(nil)
+
+// NewRecorder wraps backend. args is stored in the trace for context.
+func NewRecorder(backend ccd.DisplayBackend, args []string) *Recorder {
+	return &Recorder{
+		backend: backend,
+		trace: Trace{
+			Version:  traceVersion,
+			Recorded: time.Now().UTC(),
+			Args:     append([]string(nil), args...),
+		},
+	}
+}
+
+// AddProfile stores the profile file at path, named by the command
+// argument arg, in the trace. A profile already stored for arg is kept.
+func (r *Recorder) AddProfile(arg, path string) error {
+	if _, ok := r.trace.Profile(arg); ok {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read profile: %w", err)
+	}
+	r.trace.Profiles = append(r.trace.Profiles, Profile{Arg: arg, Name: filepath.Base(path), Data: string(data)})
+	return nil
+}
+
+// Trace returns the profiles and calls recorded so far.
+func (r *Recorder) Trace() Trace {
+	trace := r.trace
+	trace.Profiles = append([]Profile(nil), r.trace.Profiles...)
+	trace.Calls = append([]Call(nil), r.trace.Calls...)
+	return trace
+}
+
+func (r *Recorder) GetDisplayConfigBufferSizes(flags ccd.QueryDisplayFlags, numPaths *uint32, numModes *uint32) uint32 {
+	status := r.backend.GetDisplayConfigBufferSizes(flags, numPaths, numModes)
+	call := Call{API: APIGetDisplayConfigBufferSizes, Flags: uint32(flags), Status: status}
+	if status == ccd.ErrorSuccess {
+		call.NumPaths = *numPaths
+		call.NumModes = *numModes
+	}
+	r.trace.Calls = append(r.trace.Calls, call)
+	return status
+}
+
+func (r *Recorder) QueryDisplayConfig(flags ccd.QueryDisplayFlags, numPaths *uint32, paths []ccd.DisplayConfigPathInfo, numModes *uint32, modes []ccd.DisplayConfigModeInfo) uint32 {
+	call := Call{API: APIQueryDisplayConfig, Flags: uint32(flags), NumPaths: *numPaths, NumModes: *numModes}
+	status := r.backend.QueryDisplayConfig(flags, numPaths, paths, numModes, modes)
+	call.Status = status
+	if status == ccd.ErrorSuccess {
+		call.Paths = append([]ccd.DisplayConfigPathInfo{}, paths[:*numPaths]...)
+		call.Modes = append([]ccd.DisplayConfigModeInfo{}, modes[:*numModes]...)
+	}
+	r.trace.Calls = append(r.trace.Calls, call)
+	return status
+}
+
+func (r *Recorder) DisplayConfigGetDeviceInfo(request *ccd.DisplayConfigDeviceInfoHeader) uint32 {
+	header := *request
+	status := r.backend.DisplayConfigGetDeviceInfo(request)
+	r.trace.Calls = append(r.trace.Calls, Call{
+		API:      APIDisplayConfigGetDeviceInfo,
+		Request:  &header,
+		Response: bytes.Clone(deviceInfoBytes(request)),
+		Status:   status,
+	})
+	return status
+}
+
+func (r *Recorder) SetDisplayConfig(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, flags ccd.SdcFlags) uint32 {
+	call := Call{
+		API:   APISetDisplayConfig,
+		Flags: uint32(flags),
+		Paths: append([]ccd.DisplayConfigPathInfo{}, paths...),
+		Modes: append([]ccd.DisplayConfigModeInfo{}, modes...),
+	}
+	call.Status = r.backend.SetDisplayConfig(paths, modes, flags)
+	r.trace.Calls = append(r.trace.Calls, call)
+	return call.Status
+}
+
+// Replayer answers CCD calls from a recorded trace.
+//
+// Queries are matched by API and flags in recording order; once the
+// recorded answers for a flag combination run out, the last one is repeated.
+// Device info requests are matched by type, adapter and ID. A
+// SetDisplayConfig call returns the status of the first unused recorded call
+// with identical paths, modes and flags. Calls that were never recorded
+// return ccd.ErrorNotRecorded, which no real driver does, and are listed by
+// Unmatched.
+type Replayer struct {
+	calls     []Call
+	used      []bool
+	unmatched []Call
+}
+
+var _ ccd.DisplayBackend = (*Replayer)(nil)
+
+func NewReplayer(trace Trace) *Replayer {
+	return &Replayer{
+		calls: trace.Calls,
+		used:  make([]bool, len(trace.Calls)),
+	}
+}
+
+// Unmatched returns the calls that had no recorded counterpart.
+func (r *Replayer) Unmatched() []Call {
+	return append([]Call(nil), r.unmatched...)
+}
+
+func (r *Replayer) GetDisplayConfigBufferSizes(flags ccd.QueryDisplayFlags, numPaths *uint32, numModes *uint32) uint32 {
+	call, ok := r.next(func(c *Call) bool {
+		return c.API == APIGetDisplayConfigBufferSizes && c.Flags == uint32(flags)
+	})
+	if !ok {
+		r.unmatched = append(r.unmatched, Call{API: APIGetDisplayConfigBufferSizes, Flags: uint32(flags), Status: ccd.ErrorNotRecorded})
+		return ccd.ErrorNotRecorded
+	}
+	if call.Status == ccd.ErrorSuccess {
+		*numPaths = call.NumPaths
+		*numModes = call.NumModes
+	}
+	return call.Status
+}
+
+func (r *Replayer) QueryDisplayConfig(flags ccd.QueryDisplayFlags, numPaths *uint32, paths []ccd.DisplayConfigPathInfo, numModes *uint32, modes []ccd.DisplayConfigModeInfo) uint32 {
+	call, ok := r.next(func(c *Call) bool {
+		return c.API == APIQueryDisplayConfig && c.Flags == uint32(flags)
+	})
+	if !ok {
+		r.unmatched = append(r.unmatched, Call{API: APIQueryDisplayConfig, Flags: uint32(flags), NumPaths: *numPaths, NumModes: *numModes, Status: ccd.ErrorNotRecorded})
+		return ccd.ErrorNotRecorded
+	}
+	if call.Status != ccd.ErrorSuccess {
+		return call.Status
+	}
+	if len(call.Paths) > int(*numPaths) || len(call.Modes) > int(*numModes) {
+		return ccd.ErrorInsufficientBuffer
+	}
+	*numPaths = uint32(copy(paths, call.Paths))
+	*numModes = uint32(copy(modes, call.Modes))
+	return ccd.ErrorSuccess
+}
+
+func (r *Replayer) DisplayConfigGetDeviceInfo(request *ccd.DisplayConfigDeviceInfoHeader) uint32 {
+	header := *request
+	call, ok := r.find(func(c *Call) bool {
+		return c.API == APIDisplayConfigGetDeviceInfo && c.Request != nil && *c.Request == header
+	})
+	if !ok {
+		r.unmatched = append(r.unmatched, Call{API: APIDisplayConfigGetDeviceInfo, Request: &header, Status: ccd.ErrorNotRecorded})
+		return ccd.ErrorNotRecorded
+	}
+	copy(deviceInfoBytes(request), call.Response)
+	return call.Status
+}
+
+func (r *Replayer) SetDisplayConfig(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, flags ccd.SdcFlags) uint32 {
+	for i := range r.calls {
+		call := &r.calls[i]
+		if r.used[i] || call.API != APISetDisplayConfig || call.Flags != uint32(flags) {
+			continue
+		}
+		if reflect.DeepEqual(nonNilPaths(call.Paths), nonNilPaths(paths)) && reflect.DeepEqual(nonNilModes(call.Modes), nonNilModes(modes)) {
+			r.used[i] = true
+			return call.Status
+		}
+	}
+	r.unmatched = append(r.unmatched, Call{
+		API:    APISetDisplayConfig,
+		Flags:  uint32(flags),
+		Paths:  append([]ccd.DisplayConfigPathInfo{}, paths...),
+		Modes:  append([]ccd.DisplayConfigModeInfo{}, modes...),
+		Status: ccd.ErrorNotRecorded,
+	})
+	return ccd.ErrorNotRecorded
+}
+
+// next returns the first unused call matching the predicate, falling back to
+// the last used one so repeated queries keep seeing the final state.
+func (r *Replayer) next(match func(*Call) bool) (*Call, bool) {
+	last := -1
+	for i := range r.calls {
+		if !match(&r.calls[i]) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return &r.calls[i], true
+		}
+		last = i
+	}
+	if last < 0 {
+		return nil, false
+	}
+	return &r.calls[last], true
+}
+
+func (r *Replayer) find(match func(*Call) bool) (*Call, bool) {
+	for i := range r.calls {
+		if match(&r.calls[i]) {
+			r.used[i] = true
+			return &r.calls[i], true
+		}
+	}
+	return nil, false
+}
+
+// deviceInfoBytes views the full request structure a header starts.
+func deviceInfoBytes(request *ccd.DisplayConfigDeviceInfoHeader) []byte {
+	size := request.Size
+	if size < uint32(unsafe.Sizeof(*request)) {
+		size = uint32(unsafe.Sizeof(*request))
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(request)), size)
+}
+
+func nonNilPaths(paths []ccd.DisplayConfigPathInfo) []ccd.DisplayConfigPathInfo {
+	if paths == nil {
+		return []ccd.DisplayConfigPathInfo{}
+	}
+	return paths
+}
+
+func nonNilModes(modes []ccd.DisplayConfigModeInfo) []ccd.DisplayConfigModeInfo {
+	if modes == nil {
+		return []ccd.DisplayConfigModeInfo{}
+	}
+	return modes
+}
@@ -0,0 +1,162 @@
+package ccdtrace_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+	"monitor-profile-switcher/internal/ccdtrace"
+)
+
+var adapterID = ccd.LUID{LowPart: 1}
+
+func newMachine() *ccdsim.Machine {
+	m := ccdsim.New(ccdsim.Adapter{ID: adapterID, Sources: []uint32{0}, Targets: []ccdsim.Target{
+		{ID: 100, Present: true, Monitor: ccdsim.Monitor{ManufactureID: 0x10AC, ProductCodeID: 0x4123, FriendlyName: "DELL P2419H"}},
+	}})
+	var path ccd.DisplayConfigPathInfo
+	path.Flags = uint32(ccd.DisplayConfigFlagPathActive)
+	path.SourceInfo.AdapterID = adapterID
+	path.TargetInfo.AdapterID = adapterID
+	path.TargetInfo.ID = 100
+	path.TargetInfo.Rotation = ccd.DisplayConfigRotationIdentity
+	path.TargetInfo.Scaling = ccd.DisplayConfigScalingIdentity
+	var target ccd.DisplayConfigTargetMode
+	target.TargetVideoSignalInfo.ActiveSize = ccd.DisplayConfig2DRegion{Cx: 1920, Cy: 1080}
+	m.Activate(ccdsim.ActivePath{Path: path, Source: ccd.DisplayConfigSourceMode{Width: 1920, Height: 1080}, Target: target})
+	return m
+}
+
+const applyFlags = ccd.SdcFlagsApply | ccd.SdcFlagsUseSuppliedDisplayConfig
+
+// profileData is the profile the recorded run loads.
+const profileData = "pathInfo: []\n"
+
+// record queries the machine and applies the result back through a
+// Recorder, and returns the saved and reloaded trace.
+func record(t *testing.T) (ccdtrace.Trace, []ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo, []ccd.MonitorAdditionalInfo) {
+	t.Helper()
+	recorder := ccdtrace.NewRecorder(newMachine(), []string{"-load:test"})
+	profile := filepath.Join(t.TempDir(), "test.yaml")
+	if err := os.WriteFile(profile, []byte(profileData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := recorder.AddProfile("test", profile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths, modes, additional, err := ccd.GetDisplaySettings(recorder, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ccd.SetDisplayConfig(recorder, paths, modes, applyFlags); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "trace.json")
+	if err := ccdtrace.Save(file, recorder.Trace()); err != nil {
+		t.Fatal(err)
+	}
+	trace, err := ccdtrace.Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trace, paths, modes, additional
+}
+
+func TestReplay(t *testing.T) {
+	trace, paths, modes, additional := record(t)
+	if got := len(trace.Calls); got != 4 {
+		t.Fatalf("recorded %d calls, want 4 (sizes, query, device info, set)", got)
+	}
+
+	replayer := ccdtrace.NewReplayer(trace)
+	for i := 0; i < 2; i++ {
+		// The last answer repeats once the recorded ones are used up.
+		gotPaths, gotModes, gotAdditional, err := ccd.GetDisplaySettings(replayer, true)
+		if err != nil {
+			t.Fatalf("query %d: %v", i+1, err)
+		}
+		if !reflect.DeepEqual(gotPaths, paths) || !reflect.DeepEqual(gotModes, modes) || !reflect.DeepEqual(gotAdditional, additional) {
+			t.Errorf("query %d returned a different configuration than recorded", i+1)
+		}
+	}
+	if err := ccd.SetDisplayConfig(replayer, paths, modes, applyFlags); err != nil {
+		t.Errorf("recorded SetDisplayConfig = %v", err)
+	}
+	if unmatched := replayer.Unmatched(); len(unmatched) != 0 {
+		t.Errorf("unmatched calls: %+v", unmatched)
+	}
+}
+
+func TestRecordProfiles(t *testing.T) {
+	trace, _, _, _ := record(t)
+	if len(trace.Profiles) != 1 {
+		t.Fatalf("recorded %d profiles, want 1: %+v", len(trace.Profiles), trace.Profiles)
+	}
+	want := ccdtrace.Profile{Arg: "test", Name: "test.yaml", Data: profileData}
+	if got, ok := trace.Profile("test"); !ok || got != want {
+		t.Errorf("Profile(test) = %+v, %v; want %+v", got, ok, want)
+	}
+	if _, ok := trace.Profile("other"); ok {
+		t.Error("Profile(other) found a profile that was not recorded")
+	}
+
+	recorder := ccdtrace.NewRecorder(newMachine(), nil)
+	if err := recorder.AddProfile("missing", filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("AddProfile() of a missing file succeeded")
+	}
+	if profiles := recorder.Trace().Profiles; len(profiles) != 0 {
+		t.Errorf("profiles after a failed AddProfile() = %+v", profiles)
+	}
+}
+
+func TestReplayDivergence(t *testing.T) {
+	trace, paths, modes, _ := record(t)
+
+	tests := []struct {
+		name string
+		call func(ccd.DisplayBackend) error
+	}{
+		{
+			name: "SetDisplayConfig with other input",
+			call: func(backend ccd.DisplayBackend) error {
+				moved := append([]ccd.DisplayConfigModeInfo(nil), modes...)
+				moved[0].SourceMode().Position.X = 8
+				return ccd.SetDisplayConfig(backend, paths, moved, applyFlags)
+			},
+		},
+		{
+			name: "SetDisplayConfig with other flags",
+			call: func(backend ccd.DisplayBackend) error {
+				return ccd.SetDisplayConfig(backend, paths, modes, applyFlags|ccd.SdcFlagsSaveToDatabase)
+			},
+		},
+		{
+			name: "query with other flags",
+			call: func(backend ccd.DisplayBackend) error {
+				_, _, _, err := ccd.GetDisplaySettings(backend, false)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayer := ccdtrace.NewReplayer(trace)
+			err := tt.call(replayer)
+			if !errors.Is(err, ccd.ErrNotRecorded) {
+				t.Fatalf("call = %v, want NOT_RECORDED", err)
+			}
+			if errors.Is(err, ccd.ErrNotSupported) {
+				t.Error("divergence reported as ERROR_NOT_SUPPORTED")
+			}
+			if unmatched := replayer.Unmatched(); len(unmatched) != 1 {
+				t.Errorf("Unmatched() = %+v, want the diverging call", unmatched)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package confirm asks the user to keep a display change, like the Windows
+// "Keep these display settings?" dialog. A change is kept when Enter is
+// pressed on the console or when another process calls Accept, for example
+// `monitor-switcher -confirm` run over a remote session.
+package confirm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	markerPrefix = "monitor-switcher-confirm-"
+	pendingExt   = ".pending"
+	acceptedExt  = ".accepted"
+	pollInterval = 200 * time.Millisecond
+)
+
+// ErrNothingPending is returned by Accept when no change is waiting.
+var ErrNothingPending = errors.New("no display change is waiting for confirmation")
+
+// Waiter waits for a confirmation. Input is read line by line: an empty line
+// or "y" keeps the change, "n" or "r" reverts it at once. Dir holds the files
+// used to confirm from another process.
+type Waiter struct {
+	Input  io.Reader
+	Output io.Writer
+	Dir    string
+}
+
+// Default waits on the console and the system temp directory.
+func Default() Waiter {
+	return Waiter{Input: os.Stdin, Output: os.Stdout, Dir: os.TempDir()}
+}
+
+// Wait prompts and blocks until the change is kept, reverted or timeout
+// passes. It reports whether the change was kept.
+func (w Waiter) Wait(timeout time.Duration) (bool, error) {
+	pending := markerPath(w.Dir, os.Getpid(), pendingExt)
+	accepted := markerPath(w.Dir, os.Getpid(), acceptedExt)
+	_ = os.Remove(accepted)
+	if err := os.WriteFile(pending, []byte(strconv.Itoa(os.Getpid())), 0600); err != nil {
+		return false, fmt.Errorf("write confirmation marker: %w", err)
+	}
+	defer os.Remove(pending)
+	defer os.Remove(accepted)
+
+	if w.Output != nil {
+		fmt.Fprintf(w.Output, "Keep these display settings? Press Enter to keep or type r to revert (reverting in %s).\n", timeout)
+	}
+
+	answers := make(chan bool, 1)
+	done := make(chan struct{})
+	defer close(done)
+	if w.Input != nil {
+		go readAnswer(w.Input, answers, done)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case keep := <-answers:
+			return keep, nil
+		case <-ticker.C:
+			if _, err := os.Stat(accepted); err == nil {
+				return true, nil
+			}
+		case <-deadline.C:
+			return false, nil
+		}
+	}
+}
+
+// readAnswer sends the first keep or revert answer read from input. An
+// *os.File is only read once waitInput reports input, so the reader stops
+// when done is closed instead of staying blocked in Read and taking the next
+// line typed after Wait returned. Other readers cannot be interrupted.
+func readAnswer(input io.Reader, answers chan<- bool, done <-chan struct{}) {
+	file, _ := input.(*os.File)
+	buf := make([]byte, 256)
+	var line []byte
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if file != nil && !waitInput(file, done) {
+			return
+		}
+		n, err := input.Read(buf)
+		for _, b := range buf[:n] {
+			if b != '\n' {
+				line = append(line, b)
+				continue
+			}
+			if keep, ok := parseAnswer(string(line)); ok {
+				answers <- keep
+				return
+			}
+			line = line[:0]
+		}
+		if err != nil {
+			// No console (or it was closed): leave the decision to Accept
+			// and the timeout.
+			return
+		}
+	}
+}
+
+// parseAnswer reads one line typed at the prompt.
+func parseAnswer(line string) (keep bool, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "", "y", "yes", "k", "keep":
+		return true, true
+	case "n", "no", "r", "revert":
+		return false, true
+	}
+	return false, false
+}
+
+// Accept confirms the changes the current user's processes in dir are
+// waiting on.
+func Accept(dir string) error {
+	pending, err := filepath.Glob(filepath.Join(dir, markerPrefix+userTag()+"-*"+pendingExt))
+	if err != nil {
+		return fmt.Errorf("find confirmation markers: %w", err)
+	}
+	if len(pending) == 0 {
+		return ErrNothingPending
+	}
+	for _, marker := range pending {
+		accepted := strings.TrimSuffix(marker, pendingExt) + acceptedExt
+		if err := os.WriteFile(accepted, nil, 0600); err != nil {
+			return fmt.Errorf("write confirmation: %w", err)
+		}
+	}
+	return nil
+}
+
+// markerPath names a marker file of process pid. Markers carry the user
+// name so that users sharing a temp directory cannot confirm each other's
+// changes, and the PID so that concurrent waits do not collide.
+func markerPath(dir string, pid int, ext string) string {
+	return filepath.Join(dir, markerPrefix+userTag()+"-"+strconv.Itoa(pid)+ext)
+}
+
+// userTag is the current user name reduced to characters safe in a file
+// name, e.g. "DOMAIN_alice" for DOMAIN\alice.
+func userTag() string {
+	name := "unknown"
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		name = current.Username
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,123 @@
+package confirm
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitAnswers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"enter keeps", "\n", true},
+		{"yes keeps", "Yes\r\n", true},
+		{"r reverts", "r\n", false},
+		{"no reverts", " no \n", false},
+		{"unknown answers are ignored", "maybe\nkeep\n", true},
+		{"closed input waits for the timeout", "", false},
+		{"unterminated line waits for the timeout", "y", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := Waiter{Input: strings.NewReader(tt.input), Output: io.Discard, Dir: t.TempDir()}
+			keep, err := w.Wait(100 * time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keep != tt.want {
+				t.Errorf("Wait() = %v, want %v", keep, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccept(t *testing.T) {
+	dir := t.TempDir()
+	if err := Accept(dir); !errors.Is(err, ErrNothingPending) {
+		t.Fatalf("Accept() with nothing pending = %v, want ErrNothingPending", err)
+	}
+
+	kept := make(chan bool)
+	go func() {
+		keep, _ := Waiter{Dir: dir}.Wait(5 * time.Second)
+		kept <- keep
+	}()
+	pending := markerPath(dir, os.Getpid(), pendingExt)
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(pending); err == nil {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatal("Wait() did not write its pending marker")
+		}
+	}
+	if !strings.Contains(filepath.Base(pending), "-"+strconv.Itoa(os.Getpid())+".") {
+		t.Errorf("marker %s does not name the waiting process", pending)
+	}
+
+	if err := Accept(dir); err != nil {
+		t.Fatalf("Accept() = %v", err)
+	}
+	if !<-kept {
+		t.Error("Wait() did not see the confirmation")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("markers left behind: %v", entries)
+	}
+}
+
+func TestAcceptIgnoresOtherUsers(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, markerPrefix+"someoneelse-1"+pendingExt)
+	if userTag() == "someoneelse" {
+		t.Skip("running as the user the test pretends is someone else")
+	}
+	if err := os.WriteFile(other, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Accept(dir); !errors.Is(err, ErrNothingPending) {
+		t.Errorf("Accept() = %v, want ErrNothingPending", err)
+	}
+}
+
+func TestTimeoutLeavesInputUnread(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	keep, err := Waiter{Input: r, Output: io.Discard, Dir: t.TempDir()}.Wait(50 * time.Millisecond)
+	if err != nil || keep {
+		t.Fatalf("Wait() = %v, %v; want a timeout", keep, err)
+	}
+	// Give a leaked reader time to reach Read.
+	time.Sleep(3 * pollInterval)
+
+	if _, err := io.WriteString(w, "next\n"); err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 16)
+		n, _ := r.Read(buf)
+		got <- string(buf[:n])
+	}()
+	select {
+	case line := <-got:
+		if line != "next\n" {
+			t.Errorf("read %q after Wait() returned, want %q", line, "next\n")
+		}
+	case <-time.After(time.Second):
+		t.Error("the line written after Wait() returned was consumed")
+	}
+}
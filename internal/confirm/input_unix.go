@@ -0,0 +1,31 @@
+//go:build unix
+
+package confirm
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitInput blocks until file has data to read, reporting false when done
+// is closed first.
+func waitInput(file *os.File, done <-chan struct{}) bool {
+	fds := []unix.PollFd{{Fd: int32(file.Fd()), Events: unix.POLLIN}}
+	for {
+		n, err := unix.Poll(fds, int(pollInterval.Milliseconds()))
+		select {
+		case <-done:
+			return false
+		default:
+		}
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil || n > 0 {
+			// Errors and hang-ups are left for Read to report.
+			return true
+		}
+	}
+}
@@ -0,0 +1,84 @@
+//go:build windows
+
+package confirm
+
+import (
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32              = windows.NewLazySystemDLL("kernel32.dll")
+	procPeekConsoleInputW = kernel32.NewProc("PeekConsoleInputW")
+	procPeekNamedPipe     = kernel32.NewProc("PeekNamedPipe")
+)
+
+// keyInputRecord is an INPUT_RECORD holding a KEY_EVENT_RECORD.
+type keyInputRecord struct {
+	EventType       uint16
+	_               uint16
+	KeyDown         int32
+	RepeatCount     uint16
+	VirtualKeyCode  uint16
+	VirtualScanCode uint16
+	UnicodeChar     uint16
+	ControlKeyState uint32
+}
+
+const vkReturn = 0x0D
+
+// waitInput blocks until reading file will not block, reporting false when
+// done is closed first. A console in line mode only returns from ReadFile
+// once Enter is pressed, so it waits for Enter to be queued; a pipe waits
+// for data.
+func waitInput(file *os.File, done <-chan struct{}) bool {
+	handle := windows.Handle(file.Fd())
+	fileType, err := windows.GetFileType(handle)
+	if err != nil {
+		return true
+	}
+	var mode uint32
+	console := fileType == windows.FILE_TYPE_CHAR && windows.GetConsoleMode(handle, &mode) == nil
+	if !console && fileType != windows.FILE_TYPE_PIPE {
+		return true
+	}
+	for {
+		select {
+		case <-done:
+			return false
+		default:
+		}
+		if console && enterQueued(handle) || !console && pipeReadable(handle) {
+			return true
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// enterQueued reports whether the console input buffer holds an Enter key
+// press, without removing any input.
+func enterQueued(handle windows.Handle) bool {
+	var records [128]keyInputRecord
+	var read uint32
+	r1, _, _ := procPeekConsoleInputW.Call(uintptr(handle), uintptr(unsafe.Pointer(&records[0])), uintptr(len(records)), uintptr(unsafe.Pointer(&read)))
+	if r1 == 0 {
+		return true
+	}
+	for _, record := range records[:read] {
+		if record.EventType == windows.KEY_EVENT && record.KeyDown != 0 && record.VirtualKeyCode == vkReturn {
+			return true
+		}
+	}
+	return false
+}
+
+// pipeReadable reports whether a pipe has data, or is broken so that Read
+// returns at once.
+func pipeReadable(handle windows.Handle) bool {
+	var available uint32
+	r1, _, _ := procPeekNamedPipe.Call(uintptr(handle), 0, 0, 0, uintptr(unsafe.Pointer(&available)), 0)
+	return r1 == 0 || available > 0
+}
@@ -0,0 +1,274 @@
+package profile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Runtime holds the values a canonical save keeps out of the profile
+// because Windows changes them between boots: adapter LUIDs and path status
+// flags. It is written next to the profile as {path}.runtime and applied on
+// load only while LayoutHash still matches.
+type Runtime struct {
+	LayoutHash string `json:"layoutHash"`
+	// Adapters are the real LUIDs; canonical LUID {n, 0} is Adapters[n-1].
+	Adapters     []LUID   `json:"adapters"`
+	SourceStatus []uint32 `json:"sourceStatus"`
+	TargetStatus []uint32 `json:"targetStatus"`
+}
+
+// RuntimePath returns where the runtime values of a canonical profile
+// saved to path are kept.
+func RuntimePath(path string) string {
+	return path + ".runtime"
+}
+
+// Canonicalize returns p in canonical form: active paths first, ordered by
+// the monitor they drive; modes in the order the paths use them; LUIDs
+// replaced with adapter ordinals and status flags cleared. Two saves of the
+// same physical layout give the same result. The removed values are
+// returned as a Runtime.
+func Canonicalize(p Profile) (Profile, Runtime) {
+	var runtime Runtime
+	out := p
+	out.SchemaVersion = SchemaVersion
+	if len(p.PathInfo) == 0 {
+		out.LayoutHash = layoutHash(out)
+		runtime.LayoutHash = out.LayoutHash
+		return out, runtime
+	}
+
+	order := make([]int, len(p.PathInfo))
+	for i := range order {
+		order[i] = i
+	}
+	keys := make([]pathKey, len(p.PathInfo))
+	for i := range p.PathInfo {
+		keys[i] = p.pathKey(i)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return keys[order[a]].less(keys[order[b]])
+	})
+
+	// Adapter ordinals in order of first use.
+	ordinals := map[LUID]uint32{}
+	ordinal := func(id LUID) LUID {
+		n, ok := ordinals[id]
+		if !ok {
+			runtime.Adapters = append(runtime.Adapters, id)
+			n = uint32(len(runtime.Adapters))
+			ordinals[id] = n
+		}
+		return LUID{LowPart: n}
+	}
+
+	// Modes in the order the sorted paths reference them, then the rest.
+	newIdx := map[uint32]uint32{}
+	var modeOrder []uint32
+	use := func(idx uint32) {
+		if int(idx) >= len(p.ModeInfo) {
+			return
+		}
+		if _, ok := newIdx[idx]; !ok {
+			newIdx[idx] = uint32(len(modeOrder))
+			modeOrder = append(modeOrder, idx)
+		}
+	}
+	for _, i := range order {
+		for _, idx := range p.PathInfo[i].modeIndices() {
+			use(idx)
+		}
+	}
+	var rest []uint32
+	for i := range p.ModeInfo {
+		if _, ok := newIdx[uint32(i)]; !ok {
+			rest = append(rest, uint32(i))
+		}
+	}
+	sort.SliceStable(rest, func(a, b int) bool {
+		ma, mb := p.ModeInfo[rest[a]], p.ModeInfo[rest[b]]
+		if ma.InfoType != mb.InfoType {
+			return ma.InfoType < mb.InfoType
+		}
+		return ma.ID < mb.ID
+	})
+	for _, idx := range rest {
+		use(idx)
+	}
+	remap := func(idx uint32) uint32 {
+		if n, ok := newIdx[idx]; ok {
+			return n
+		}
+		return idx
+	}
+
+	out.PathInfo = make([]PathInfo, 0, len(p.PathInfo))
+	for _, i := range order {
+		path := p.PathInfo[i]
+		runtime.SourceStatus = append(runtime.SourceStatus, path.SourceInfo.StatusFlags)
+		runtime.TargetStatus = append(runtime.TargetStatus, path.TargetInfo.StatusFlags)
+		path.SourceInfo.StatusFlags = 0
+		path.TargetInfo.StatusFlags = 0
+		path.SourceInfo.AdapterID = ordinal(path.SourceInfo.AdapterID)
+		path.TargetInfo.AdapterID = ordinal(path.TargetInfo.AdapterID)
+		if path.Flags&pathFlagSupportVirtualMode != 0 {
+			path.SourceInfo.ModeInfoIdx = remapPacked(path.SourceInfo.ModeInfoIdx, remap, false)
+			path.TargetInfo.ModeInfoIdx = remapPacked(path.TargetInfo.ModeInfoIdx, remap, true)
+		} else {
+			path.SourceInfo.ModeInfoIdx = remap(path.SourceInfo.ModeInfoIdx)
+			path.TargetInfo.ModeInfoIdx = remap(path.TargetInfo.ModeInfoIdx)
+		}
+		out.PathInfo = append(out.PathInfo, path)
+	}
+
+	out.ModeInfo = make([]ModeInfo, 0, len(modeOrder))
+	for _, idx := range modeOrder {
+		mode := p.ModeInfo[idx]
+		mode.AdapterID = ordinal(mode.AdapterID)
+		out.ModeInfo = append(out.ModeInfo, mode)
+	}
+	if len(p.AdditionalInfo) == len(p.ModeInfo) {
+		out.AdditionalInfo = make([]AdditionalInfo, 0, len(modeOrder))
+		for _, idx := range modeOrder {
+			out.AdditionalInfo = append(out.AdditionalInfo, p.AdditionalInfo[idx])
+		}
+	}
+
+	out.LayoutHash = layoutHash(out)
+	runtime.LayoutHash = out.LayoutHash
+	return out, runtime
+}
+
+// remapPacked renumbers the mode indices packed into a virtual-mode-aware
+// ModeInfoIdx. The low word is a clone group for sources and a desktop
+// image mode for targets.
+func remapPacked(value uint32, remap func(uint32) uint32, lowIsMode bool) uint32 {
+	high, low := value>>16, value&0xFFFF
+	if high != packedModeIdxInvalid {
+		high = remap(high)
+	}
+	if lowIsMode && low != packedModeIdxInvalid {
+		low = remap(low)
+	}
+	return high<<16 | low
+}
+
+// modeIndices lists the modes a path uses: source, target, desktop image.
+func (p *PathInfo) modeIndices() []uint32 {
+	if p.Flags&pathFlagSupportVirtualMode != 0 {
+		return []uint32{p.SourceInfo.ModeInfoIdx >> 16, p.TargetInfo.ModeInfoIdx >> 16, p.TargetInfo.ModeInfoIdx & 0xFFFF}
+	}
+	return []uint32{p.SourceInfo.ModeInfoIdx, p.TargetInfo.ModeInfoIdx}
+}
+
+type pathKey struct {
+	inactive bool
+	monitor  string
+	targetID uint32
+	sourceID uint32
+}
+
+func (a pathKey) less(b pathKey) bool {
+	if a.inactive != b.inactive {
+		return !a.inactive
+	}
+	if a.monitor != b.monitor {
+		return a.monitor < b.monitor
+	}
+	if a.targetID != b.targetID {
+		return a.targetID < b.targetID
+	}
+	return a.sourceID < b.sourceID
+}
+
+// pathKey identifies path i by values that survive a reboot: the monitor's
+// device path (or EDID IDs) and the target and source IDs.
+func (p Profile) pathKey(i int) pathKey {
+	path := &p.PathInfo[i]
+	key := pathKey{
+		inactive: path.Flags&pathFlagActive == 0,
+		targetID: path.TargetInfo.ID,
+		sourceID: path.SourceInfo.ID,
+	}
+	idx := path.TargetInfo.ModeInfoIdx
+	if path.Flags&pathFlagSupportVirtualMode != 0 {
+		idx >>= 16
+	}
+	if int(idx) < len(p.AdditionalInfo) && p.AdditionalInfo[idx].Valid {
+		info := p.AdditionalInfo[idx]
+		key.monitor = info.MonitorDevicePath
+		if key.monitor == "" {
+			key.monitor = strconv.Itoa(int(info.ManufactureID)) + ":" + strconv.Itoa(int(info.ProductCodeID))
+		}
+	}
+	return key
+}
+
+// layoutHash hashes the layout part of a canonical profile: everything but
+// the schema version, strategies and the hash itself.
+func layoutHash(p Profile) string {
+	data, _ := json.Marshal(struct {
+		PathInfo       []PathInfo       `json:"pathInfo"`
+		ModeInfo       []ModeInfo       `json:"modeInfo"`
+		AdditionalInfo []AdditionalInfo `json:"additionalInfo"`
+		Monitors       []Monitor        `json:"monitors"`
+	}{p.PathInfo, p.ModeInfo, p.AdditionalInfo, p.Monitors})
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// LayoutHash returns the content hash of p's physical layout. It is the same
+// for every save of the same layout, canonical or not.
+func LayoutHash(p Profile) string {
+	canonical, _ := Canonicalize(p)
+	return canonical.LayoutHash
+}
+
+// applyRuntime restores the values a canonical save moved to
+// RuntimePath(path), if that file exists and belongs to this layout. The
+// hash is computed from the loaded layout, so a hand-edited profile that
+// kept its old layoutHash does not get the runtime values of the old one.
+func applyRuntime(path string, p *Profile) {
+	data, err := os.ReadFile(RuntimePath(path))
+	if err != nil {
+		return
+	}
+	var runtime Runtime
+	if json.Unmarshal(data, &runtime) != nil || runtime.LayoutHash != layoutHash(*p) ||
+		len(runtime.SourceStatus) != len(p.PathInfo) || len(runtime.TargetStatus) != len(p.PathInfo) {
+		return
+	}
+	adapter := func(id LUID) LUID {
+		if id.HighPart == 0 && id.LowPart >= 1 && int(id.LowPart) <= len(runtime.Adapters) {
+			return runtime.Adapters[id.LowPart-1]
+		}
+		return id
+	}
+	for i := range p.PathInfo {
+		path := &p.PathInfo[i]
+		path.SourceInfo.StatusFlags = runtime.SourceStatus[i]
+		path.TargetInfo.StatusFlags = runtime.TargetStatus[i]
+		path.SourceInfo.AdapterID = adapter(path.SourceInfo.AdapterID)
+		path.TargetInfo.AdapterID = adapter(path.TargetInfo.AdapterID)
+	}
+	for i := range p.ModeInfo {
+		p.ModeInfo[i].AdapterID = adapter(p.ModeInfo[i].AdapterID)
+	}
+}
+
+func saveRuntime(path string, runtime Runtime) error {
+	data, err := json.MarshalIndent(runtime, "", "  ")
+	if err != nil {
+		return fmt.Errorf("serialize runtime values: %w", err)
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(RuntimePath(path), data, 0644); err != nil {
+		return fmt.Errorf("write runtime values: %w", err)
+	}
+	return nil
+}
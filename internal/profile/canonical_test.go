@@ -0,0 +1,167 @@
+package profile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// rebooted returns validProfile as a later save after a reboot might see
+// it: another adapter LUID, status flags set, paths and modes in reverse
+// order.
+func rebooted() Profile {
+	p := validProfile()
+	adapter := LUID{LowPart: 0x1a2b3c, HighPart: 7}
+	for i := range p.PathInfo {
+		p.PathInfo[i].SourceInfo.AdapterID = adapter
+		p.PathInfo[i].TargetInfo.AdapterID = adapter
+		p.PathInfo[i].SourceInfo.StatusFlags = 1
+		p.PathInfo[i].TargetInfo.StatusFlags = 1
+	}
+	for i := range p.ModeInfo {
+		p.ModeInfo[i].AdapterID = adapter
+	}
+	// Mode i moves to 4-i.
+	p.PathInfo[0].SourceInfo.ModeInfoIdx, p.PathInfo[0].TargetInfo.ModeInfoIdx = 3, 4
+	p.PathInfo[1].SourceInfo.ModeInfoIdx, p.PathInfo[1].TargetInfo.ModeInfoIdx = 1<<16|packedModeIdxInvalid, 2<<16|0
+	p.PathInfo[0], p.PathInfo[1] = p.PathInfo[1], p.PathInfo[0]
+	for i, j := 0, len(p.ModeInfo)-1; i < j; i, j = i+1, j-1 {
+		p.ModeInfo[i], p.ModeInfo[j] = p.ModeInfo[j], p.ModeInfo[i]
+		p.AdditionalInfo[i], p.AdditionalInfo[j] = p.AdditionalInfo[j], p.AdditionalInfo[i]
+	}
+	return p
+}
+
+func TestCanonicalize(t *testing.T) {
+	first, firstRuntime := Canonicalize(validProfile())
+	second, secondRuntime := Canonicalize(rebooted())
+
+	if issues := Validate(rebooted()); len(issues) != 0 {
+		t.Fatalf("setup: rebooted profile is invalid: %v", issues)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("canonical forms differ:\n%+v\n%+v", first, second)
+	}
+	if issues := Validate(second); len(issues) != 0 {
+		t.Errorf("canonical profile is invalid: %v", issues)
+	}
+	if again, _ := Canonicalize(second); !reflect.DeepEqual(again, second) {
+		t.Errorf("Canonicalize is not idempotent:\n%+v\n%+v", again, second)
+	}
+
+	for _, path := range second.PathInfo {
+		if path.SourceInfo.AdapterID != (LUID{LowPart: 1}) || path.SourceInfo.StatusFlags != 0 || path.TargetInfo.StatusFlags != 0 {
+			t.Errorf("path to target %d kept runtime values: %+v", path.TargetInfo.ID, path)
+		}
+	}
+	if want := []LUID{{LowPart: 1}}; !reflect.DeepEqual(firstRuntime.Adapters, want) {
+		t.Errorf("adapters = %v, want %v", firstRuntime.Adapters, want)
+	}
+	if want := []LUID{{LowPart: 0x1a2b3c, HighPart: 7}}; !reflect.DeepEqual(secondRuntime.Adapters, want) {
+		t.Errorf("adapters = %v, want %v", secondRuntime.Adapters, want)
+	}
+	if want := []uint32{1, 1}; !reflect.DeepEqual(secondRuntime.SourceStatus, want) || !reflect.DeepEqual(secondRuntime.TargetStatus, want) {
+		t.Errorf("status = %v, %v, want %v", secondRuntime.SourceStatus, secondRuntime.TargetStatus, want)
+	}
+	if secondRuntime.LayoutHash != second.LayoutHash {
+		t.Errorf("runtime hash %q, profile hash %q", secondRuntime.LayoutHash, second.LayoutHash)
+	}
+}
+
+func TestLayoutHash(t *testing.T) {
+	base := LayoutHash(validProfile())
+	tests := []struct {
+		name    string
+		profile Profile
+		same    bool
+	}{
+		{name: "after a reboot", profile: rebooted(), same: true},
+		{name: "strategies", profile: func() Profile {
+			p := validProfile()
+			p.Strategies = []string{"as-saved"}
+			return p
+		}(), same: true},
+		{name: "moved monitor", profile: func() Profile {
+			p := validProfile()
+			p.ModeInfo[3].SourceMode.Position.X = 2560
+			return p
+		}()},
+		{name: "other refresh rate", profile: func() Profile {
+			p := validProfile()
+			p.PathInfo[0].TargetInfo.RefreshRate = Rational{144, 1}
+			return p
+		}()},
+		{name: "per-monitor", profile: validLayout()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LayoutHash(tt.profile); (got == base) != tt.same {
+				t.Errorf("LayoutHash() = %s, base %s, want same %v", got, base, tt.same)
+			}
+		})
+	}
+	if LayoutHash(validLayout()) != LayoutHash(validLayout()) {
+		t.Error("LayoutHash of a per-monitor profile is not stable")
+	}
+}
+
+func TestCanonicalSave(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.json"), filepath.Join(dir, "second.json")
+	if err := Save(first, validProfile(), SaveOptions{Canonical: true}); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	if err := Save(second, rebooted(), SaveOptions{Canonical: true}); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	a, _ := os.ReadFile(first)
+	b, _ := os.ReadFile(second)
+	if !bytes.Equal(a, b) {
+		t.Errorf("saves of the same layout differ:\n%s\n%s", a, b)
+	}
+
+	loaded, err := Load(second)
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	adapter := LUID{LowPart: 0x1a2b3c, HighPart: 7}
+	for _, path := range loaded.PathInfo {
+		if path.SourceInfo.AdapterID != adapter || path.TargetInfo.AdapterID != adapter || path.SourceInfo.StatusFlags != 1 {
+			t.Errorf("runtime values not restored on path to target %d: %+v", path.TargetInfo.ID, path)
+		}
+	}
+	for _, mode := range loaded.ModeInfo {
+		if mode.AdapterID != adapter {
+			t.Errorf("mode %d of type %d has adapter %v, want %v", mode.ID, mode.InfoType, mode.AdapterID, adapter)
+		}
+	}
+
+	// Saving the loaded profile again stays canonical.
+	if err := Save(second, loaded, SaveOptions{}); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	if b, _ = os.ReadFile(second); !bytes.Equal(a, b) {
+		t.Errorf("resave changed the file:\n%s\n%s", a, b)
+	}
+
+	// Runtime values of another layout are ignored.
+	if err := os.Rename(RuntimePath(second), RuntimePath(first)); err != nil {
+		t.Fatal(err)
+	}
+	edited := bytes.Replace(a, []byte(`"x": 1920`), []byte(`"x": 2560`), 1)
+	if bytes.Equal(edited, a) {
+		t.Fatalf("setup: no source at x=1920 in\n%s", a)
+	}
+	if err := os.WriteFile(first, edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = Load(first)
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if got := loaded.PathInfo[0].SourceInfo.AdapterID; got != (LUID{LowPart: 1}) {
+		t.Errorf("adapter = %v after editing the layout, want the canonical ordinal", got)
+	}
+}
@@ -0,0 +1,324 @@
+package profile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Encodings a profile can be stored in, chosen by file extension. All of
+// them use the JSON field names.
+const (
+	EncodingJSON  = "json"
+	EncodingJSONC = "jsonc"
+	EncodingYAML  = "yaml"
+	EncodingTOML  = "toml"
+)
+
+// EncodingFor returns the encoding for path's extension: .yaml/.yml,
+// .toml and .jsonc; anything else, including .monitorprofile, is JSON.
+func EncodingFor(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return EncodingYAML
+	case ".toml":
+		return EncodingTOML
+	case ".jsonc":
+		return EncodingJSONC
+	}
+	return EncodingJSON
+}
+
+// toJSON turns a document in the given encoding into plain JSON for decode,
+// dropping comments.
+func toJSON(encoding string, data []byte) ([]byte, error) {
+	var doc any
+	switch encoding {
+	case EncodingYAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse YAML profile: %w", err)
+		}
+	case EncodingTOML:
+		if _, err := toml.Decode(string(data), &doc); err != nil {
+			return nil, fmt.Errorf("parse TOML profile: %w", err)
+		}
+	case EncodingJSONC:
+		node, err := parseJSONC(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse profile: %w", err)
+		}
+		if err := node.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("parse profile: %w", err)
+		}
+	default:
+		return data, nil
+	}
+	if doc == nil {
+		return nil, errors.New("parse profile: document is empty")
+	}
+	return json.Marshal(doc)
+}
+
+// decodeAs parses profile data stored in encoding, applying migrations.
+func decodeAs(encoding string, data []byte) (Profile, int, error) {
+	data, err := toJSON(encoding, data)
+	if err != nil {
+		return Profile{}, 0, err
+	}
+	return decode(data)
+}
+
+// encode serializes profile in encoding. comments, when not nil, is a
+// previously read document whose comments are carried over to matching
+// keys and array elements. names writes enums and flags by name.
+func encode(encoding string, profile Profile, comments *yaml.Node, names bool) ([]byte, error) {
+	// Every file carries the three CCD arrays, as older versions expect;
+	// per-monitor profiles leave them empty. TOML has no null.
+	if profile.PathInfo == nil {
+		profile.PathInfo = []PathInfo{}
+	}
+	if profile.ModeInfo == nil {
+		profile.ModeInfo = []ModeInfo{}
+	}
+	if profile.AdditionalInfo == nil {
+		profile.AdditionalInfo = []AdditionalInfo{}
+	}
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("serialize profile: %w", err)
+	}
+	if encoding == EncodingJSON && !names {
+		return append(data, '\n'), nil
+	}
+
+	doc, err := nodeFromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("serialize profile: %w", err)
+	}
+	if names {
+		nameEnums(doc)
+	}
+	if comments != nil {
+		copyComments(doc, comments)
+	}
+
+	var buf bytes.Buffer
+	switch encoding {
+	case EncodingYAML:
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return nil, fmt.Errorf("serialize profile: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("serialize profile: %w", err)
+		}
+	case EncodingTOML:
+		// The TOML encoder cannot place comments, so only the comment
+		// block at the top of the document survives.
+		if header := leadingComment(doc); header != "" {
+			writeComments(&buf, header, "# ", "")
+			buf.WriteString("\n")
+		}
+		encoder := toml.NewEncoder(&buf)
+		encoder.Indent = ""
+		if err := encoder.Encode(nodeValue(doc)); err != nil {
+			return nil, fmt.Errorf("serialize profile: %w", err)
+		}
+	case EncodingJSONC, EncodingJSON:
+		// Without comments this writes the same JSON as MarshalIndent.
+		writeJSONC(&buf, doc)
+	default:
+		return nil, fmt.Errorf("serialize profile: unknown encoding %q", encoding)
+	}
+	return buf.Bytes(), nil
+}
+
+// leadingComment returns the comment at the top of doc, which YAML and
+// JSONC attach either to the document or to its first key.
+func leadingComment(doc *yaml.Node) string {
+	if doc.HeadComment != "" {
+		return doc.HeadComment
+	}
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode && len(doc.Content[0].Content) > 0 {
+		return doc.Content[0].Content[0].HeadComment
+	}
+	return ""
+}
+
+// readComments returns the comments of the profile at path, or nil when it
+// does not exist, cannot be parsed or its encoding has no comments. For
+// TOML only the comment block at the top of the file is read.
+func readComments(path string) *yaml.Node {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	switch EncodingFor(path) {
+	case EncodingYAML:
+		var doc yaml.Node
+		if yaml.Unmarshal(data, &doc) != nil {
+			return nil
+		}
+		return &doc
+	case EncodingJSONC:
+		doc, err := parseJSONC(data)
+		if err != nil {
+			return nil
+		}
+		return doc
+	case EncodingTOML:
+		var lines []string
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "#") {
+				break
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+		return &yaml.Node{Kind: yaml.DocumentNode, HeadComment: strings.Join(lines, "\n")}
+	}
+	return nil
+}
+
+// nodeFromJSON builds a document tree from JSON, keeping key order.
+func nodeFromJSON(data []byte) (*yaml.Node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	root, err := jsonValueNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}, nil
+}
+
+func jsonValueNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch value := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if value == '[' {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			child, err := jsonValueNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+}
+
+// nodeValue converts a tree built by nodeFromJSON to plain Go values for
+// the TOML encoder.
+func nodeValue(node *yaml.Node) any {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return nodeValue(node.Content[0])
+	case yaml.MappingNode:
+		values := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			values[node.Content[i].Value] = nodeValue(node.Content[i+1])
+		}
+		return values
+	case yaml.SequenceNode:
+		values := make([]any, 0, len(node.Content))
+		for _, child := range node.Content {
+			values = append(values, nodeValue(child))
+		}
+		return values
+	}
+	switch node.Tag {
+	case "!!int":
+		if value, err := strconv.ParseInt(node.Value, 10, 64); err == nil {
+			return value
+		}
+	case "!!float":
+		if value, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			return value
+		}
+	case "!!bool":
+		return node.Value == "true"
+	}
+	return node.Value
+}
+
+// copyComments copies comments from src onto the nodes of dst at the same
+// key path, matching mapping entries by key and sequence items by index.
+// Comments already on dst are kept.
+func copyComments(dst, src *yaml.Node) {
+	if dst.HeadComment == "" {
+		dst.HeadComment = src.HeadComment
+	}
+	if dst.LineComment == "" {
+		dst.LineComment = src.LineComment
+	}
+	if dst.FootComment == "" {
+		dst.FootComment = src.FootComment
+	}
+	switch {
+	case dst.Kind == yaml.DocumentNode && src.Kind == yaml.DocumentNode:
+		if len(dst.Content) > 0 && len(src.Content) > 0 {
+			copyComments(dst.Content[0], src.Content[0])
+		}
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			for j := 0; j+1 < len(dst.Content); j += 2 {
+				if dst.Content[j].Value == src.Content[i].Value {
+					key, value := dst.Content[j], dst.Content[j+1]
+					copyComments(key, src.Content[i])
+					copyComments(value, src.Content[i+1])
+					// A comment after "key: [" belongs on the key; the
+					// YAML emitter drops it from block collections.
+					if value.Kind != yaml.ScalarNode && key.LineComment == "" {
+						key.LineComment, value.LineComment = value.LineComment, ""
+					}
+					break
+				}
+			}
+		}
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		for i := 0; i < len(dst.Content) && i < len(src.Content); i++ {
+			copyComments(dst.Content[i], src.Content[i])
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodingFor(t *testing.T) {
+	tests := map[string]string{
+		"Home.monitorprofile":   EncodingJSON,
+		"Home.json":             EncodingJSON,
+		"Home":                  EncodingJSON,
+		"Home.jsonc":            EncodingJSONC,
+		"Home.yaml":             EncodingYAML,
+		"Home.YML":              EncodingYAML,
+		"Home.toml":             EncodingTOML,
+		`C:\profiles\Home.Toml`: EncodingTOML,
+	}
+	for path, want := range tests {
+		if got := EncodingFor(path); got != want {
+			t.Errorf("EncodingFor(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// saved is p as Load returns it after Save: at the current schema
+// version, with empty CCD arrays where p had none.
+func saved(p Profile) Profile {
+	p.SchemaVersion = SchemaVersion
+	if p.PathInfo == nil {
+		p.PathInfo, p.ModeInfo, p.AdditionalInfo = []PathInfo{}, []ModeInfo{}, []AdditionalInfo{}
+	}
+	return p
+}
+
+func TestEncodingsRoundTrip(t *testing.T) {
+	withStrategies := validProfile()
+	withStrategies.Strategies = []string{"identity", "as-saved"}
+	withStrategies.Fingerprint = "ec448d5eed904534"
+	profiles := map[string]Profile{"ccd": withStrategies, "monitors": validLayout()}
+
+	for _, ext := range []string{".monitorprofile", ".jsonc", ".yaml", ".toml"} {
+		for name, profile := range profiles {
+			for _, names := range []bool{false, true} {
+				t.Run(fmtCase(ext, name, names), func(t *testing.T) {
+					path := filepath.Join(t.TempDir(), "profile"+ext)
+					if err := Save(path, profile, SaveOptions{Names: names}); err != nil {
+						t.Fatalf("Save() = %v", err)
+					}
+					loaded, err := Load(path)
+					if err != nil {
+						t.Fatalf("Load() = %v", err)
+					}
+					if want := saved(profile); !reflect.DeepEqual(loaded, want) {
+						t.Errorf("Load() =\n%+v\nwant\n%+v", loaded, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func fmtCase(ext, name string, names bool) string {
+	if names {
+		return ext + "/" + name + "/names"
+	}
+	return ext + "/" + name
+}
+
+func TestSaveKeepsComments(t *testing.T) {
+	tests := []struct {
+		ext      string
+		original string
+		// want are comments that survive saving a changed profile.
+		want []string
+	}{
+		{
+			ext: ".yaml",
+			original: "# Desk at home\nschemaVersion: 2\n" +
+				"monitors:\n  # left\n  - name: DELL P2419H # the old one\n    enabled: true\n    width: 1920\n    height: 1080\n",
+			want: []string{"# Desk at home", "# left", "# the old one"},
+		},
+		{
+			ext: ".jsonc",
+			original: "// Desk at home\n{\n  \"monitors\": [\n    /* left */\n" +
+				"    {\"name\": \"DELL P2419H\", \"enabled\": true, \"width\": 1920, \"height\": 1080}, // the old one\n  ],\n}\n",
+			want: []string{"// Desk at home", "// left", "// the old one"},
+		},
+		{
+			ext:      ".toml",
+			original: "# Desk at home\n# second line\n\n[[monitors]]\nname = \"DELL P2419H\"\nenabled = true\nwidth = 1920\nheight = 1080\n",
+			want:     []string{"# Desk at home\n# second line"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "desk"+tt.ext)
+			if err := os.WriteFile(path, []byte(tt.original), 0644); err != nil {
+				t.Fatal(err)
+			}
+			profile, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			profile.Monitors[0].Width, profile.Monitors[0].Height = 2560, 1440
+			if err := Save(path, profile, SaveOptions{}); err != nil {
+				t.Fatalf("Save() = %v", err)
+			}
+			data, _ := os.ReadFile(path)
+			for _, comment := range tt.want {
+				if !strings.Contains(string(data), comment) {
+					t.Errorf("saved file lost %q:\n%s", comment, data)
+				}
+			}
+			reloaded, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() of the saved file = %v", err)
+			}
+			if reloaded.Monitors[0].Width != 2560 {
+				t.Errorf("width = %d after saving, want 2560", reloaded.Monitors[0].Width)
+			}
+		})
+	}
+}
+
+func TestParseJSONC(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "plain JSON", data: `{"a": [1, 2.5, "x", true, null]}`},
+		{name: "comments and trailing commas", data: "// head\n{\n  \"a\": 1, // one\n  /* block\n   * two */\n  \"b\": [1, 2,],\n}\n"},
+		{name: "byte order mark", data: "\xef\xbb\xbf{}"},
+		{name: "trailing text", data: `{} {}`, wantErr: "line 1: unexpected '{' after the document"},
+		{name: "missing comma", data: "{\n\"a\": 1\n\"b\": 2}", wantErr: "line 3: expected ',' or '}'"},
+		{name: "unterminated", data: `{"a": `, wantErr: "unexpected end of input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseJSONC([]byte(tt.data))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("parseJSONC() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("parseJSONC() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadEncodingErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty.yaml":  "",
+		"bad.yaml":    "monitors: [",
+		"bad.toml":    "monitors = ",
+		"bad.jsonc":   "{,}",
+		"bad.profile": "{",
+	}
+	dir := t.TempDir()
+	for name, data := range tests {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(%s) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSONC is JSON with // and /* */ comments and trailing commas. It is
+// parsed into the same yaml.Node tree the YAML encoder uses so comments can
+// be carried between the two. Comment text is stored YAML style ("# text").
+
+type jsoncParser struct {
+	data     []byte
+	pos      int
+	line     int
+	pending  []string
+	last     *yaml.Node
+	lastLine int
+}
+
+func parseJSONC(data []byte) (*yaml.Node, error) {
+	p := &jsoncParser{data: bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), line: 1}
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	p.skip()
+	doc.HeadComment = p.takePending()
+	root, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	doc.Content = []*yaml.Node{root}
+	p.skip()
+	if p.pos < len(p.data) {
+		return nil, p.errorf("unexpected %q after the document", p.data[p.pos])
+	}
+	doc.FootComment = p.takePending()
+	return doc, nil
+}
+
+func (p *jsoncParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// skip moves past whitespace and comments. A comment on the line where the
+// last value ended belongs to that value; others wait for the next one.
+func (p *jsoncParser) skip() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case bytes.HasPrefix(p.data[p.pos:], []byte("//")):
+			end := bytes.IndexByte(p.data[p.pos:], '\n')
+			if end < 0 {
+				end = len(p.data) - p.pos
+			}
+			p.comment(p.line, []string{string(p.data[p.pos+2 : p.pos+end])})
+			p.pos += end
+		case bytes.HasPrefix(p.data[p.pos:], []byte("/*")):
+			start := p.line
+			end := bytes.Index(p.data[p.pos+2:], []byte("*/"))
+			if end < 0 {
+				end = len(p.data) - p.pos - 2
+			}
+			text := string(p.data[p.pos+2 : p.pos+2+end])
+			var lines []string
+			for _, line := range strings.Split(text, "\n") {
+				line = strings.TrimPrefix(strings.TrimSpace(line), "*")
+				if strings.TrimSpace(line) != "" {
+					lines = append(lines, line)
+				}
+			}
+			p.line += strings.Count(text, "\n")
+			p.pos += end + 4
+			p.comment(start, lines)
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsoncParser) comment(line int, lines []string) {
+	for i, text := range lines {
+		text = "# " + strings.TrimSpace(text)
+		if i == 0 && p.last != nil && line == p.lastLine {
+			p.last.LineComment = joinComments(p.last.LineComment, text)
+			continue
+		}
+		p.pending = append(p.pending, text)
+	}
+}
+
+func (p *jsoncParser) takePending() string {
+	text := strings.Join(p.pending, "\n")
+	p.pending = nil
+	return text
+}
+
+func (p *jsoncParser) ended(node *yaml.Node) {
+	p.last = node
+	p.lastLine = p.line
+}
+
+func (p *jsoncParser) value() (*yaml.Node, error) {
+	p.skip()
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"':
+		value, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+		p.ended(node)
+		return node, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.data) && strings.IndexByte("+-0123456789.eE", p.data[p.pos]) >= 0 {
+			p.pos++
+		}
+		text := string(p.data[start:p.pos])
+		var number json.Number
+		if err := json.Unmarshal([]byte(text), &number); err != nil {
+			return nil, p.errorf("invalid number %q", text)
+		}
+		tag := "!!int"
+		if strings.ContainsAny(text, ".eE") {
+			tag = "!!float"
+		}
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: text}
+		p.ended(node)
+		return node, nil
+	}
+	for _, literal := range []struct{ text, tag string }{{"true", "!!bool"}, {"false", "!!bool"}, {"null", "!!null"}} {
+		if bytes.HasPrefix(p.data[p.pos:], []byte(literal.text)) {
+			p.pos += len(literal.text)
+			node := &yaml.Node{Kind: yaml.ScalarNode, Tag: literal.tag, Value: literal.text}
+			p.ended(node)
+			return node, nil
+		}
+	}
+	return nil, p.errorf("unexpected %q", p.data[p.pos])
+}
+
+func (p *jsoncParser) str() (string, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.data) && p.data[p.pos] != '"' {
+		if p.data[p.pos] == '\\' {
+			p.pos++
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+			return "", p.errorf("newline in string")
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.data) {
+		return "", p.errorf("unterminated string")
+	}
+	p.pos++
+	var value string
+	if err := json.Unmarshal(p.data[start:p.pos], &value); err != nil {
+		return "", p.errorf("invalid string: %v", err)
+	}
+	return value, nil
+}
+
+func (p *jsoncParser) object() (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	p.pos++
+	for {
+		p.skip()
+		if p.pos < len(p.data) && p.data[p.pos] == '}' {
+			node.FootComment = p.takePending()
+			p.pos++
+			p.ended(node)
+			return node, nil
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+			return nil, p.errorf("expected a key or '}'")
+		}
+		key, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key, HeadComment: p.takePending()}
+		p.skip()
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return nil, p.errorf("expected ':' after key %q", key)
+		}
+		p.pos++
+		p.ended(keyNode)
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, keyNode, value)
+		if err := p.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *jsoncParser) array() (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	p.pos++
+	p.ended(node)
+	for {
+		p.skip()
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			node.FootComment = p.takePending()
+			p.pos++
+			p.ended(node)
+			return node, nil
+		}
+		head := p.takePending()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		value.HeadComment = joinComments(head, value.HeadComment)
+		node.Content = append(node.Content, value)
+		if err := p.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes the comma after a member; a closing bracket is left
+// for the caller.
+func (p *jsoncParser) separator(closing byte) error {
+	p.skip()
+	if p.pos < len(p.data) && p.data[p.pos] == ',' {
+		p.pos++
+		return nil
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == closing {
+		return nil
+	}
+	return p.errorf("expected ',' or '%c'", closing)
+}
+
+func joinComments(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + "\n" + b
+}
+
+// writeComments writes each line of a YAML-style comment with prefix
+// instead of "#".
+func writeComments(buf *bytes.Buffer, comment, prefix, indent string) {
+	if comment == "" {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		text := strings.TrimPrefix(strings.TrimPrefix(line, "#"), " ")
+		buf.WriteString(indent + strings.TrimRight(prefix+text, " ") + "\n")
+	}
+}
+
+// writeJSONC writes doc as indented JSON with its comments.
+func writeJSONC(buf *bytes.Buffer, doc *yaml.Node) {
+	writeComments(buf, doc.HeadComment, "// ", "")
+	writeJSONCValue(buf, doc.Content[0], "", "")
+	if root := doc.Content[0]; root.Kind == yaml.ScalarNode && root.LineComment != "" {
+		writeTrailing(buf, root.LineComment)
+	}
+	buf.WriteString("\n")
+	writeComments(buf, doc.FootComment, "// ", "")
+}
+
+// writeJSONCValue writes node. For containers, trailing is written after the
+// opening bracket together with the node's own line comment; scalars leave
+// their line comment to the caller so it can follow the comma.
+func writeJSONCValue(buf *bytes.Buffer, node *yaml.Node, indent, trailing string) {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close := "{", "}"
+		step := 2
+		if node.Kind == yaml.SequenceNode {
+			open, close, step = "[", "]", 1
+		}
+		if len(node.Content) == 0 && node.FootComment == "" {
+			buf.WriteString(open + close)
+			return
+		}
+		buf.WriteString(open)
+		writeTrailing(buf, joinComments(trailing, node.LineComment))
+		inner := indent + "  "
+		for i := 0; i < len(node.Content); i += step {
+			buf.WriteString("\n")
+			value := node.Content[i]
+			lineComment := ""
+			if step == 2 {
+				key := node.Content[i]
+				value = node.Content[i+1]
+				writeComments(buf, key.HeadComment, "// ", inner)
+				writeComments(buf, value.HeadComment, "// ", inner)
+				keyText, _ := json.Marshal(key.Value)
+				buf.WriteString(inner + string(keyText) + ": ")
+				lineComment = key.LineComment
+			} else {
+				writeComments(buf, value.HeadComment, "// ", inner)
+				buf.WriteString(inner)
+			}
+			if value.Kind == yaml.ScalarNode {
+				writeJSONCValue(buf, value, inner, "")
+				lineComment = joinComments(lineComment, value.LineComment)
+			} else {
+				writeJSONCValue(buf, value, inner, lineComment)
+				lineComment = ""
+			}
+			if i+step < len(node.Content) {
+				buf.WriteString(",")
+			}
+			writeTrailing(buf, lineComment)
+		}
+		if node.FootComment != "" {
+			buf.WriteString("\n")
+			writeComments(buf, node.FootComment, "// ", inner)
+			buf.Truncate(buf.Len() - 1)
+		}
+		buf.WriteString("\n" + indent + close)
+	default:
+		if node.Tag == "!!str" {
+			text, _ := json.Marshal(node.Value)
+			buf.Write(text)
+			return
+		}
+		buf.WriteString(node.Value)
+	}
+}
+
+// writeTrailing appends a comment to the current line.
+func writeTrailing(buf *bytes.Buffer, comment string) {
+	if comment == "" {
+		return
+	}
+	var texts []string
+	for _, line := range strings.Split(comment, "\n") {
+		if text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#")); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) > 0 {
+		buf.WriteString(" // " + strings.Join(texts, "; "))
+	}
+}
@@ -0,0 +1,120 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SchemaVersion is the profile schema this build reads and writes. Files
+// without a schemaVersion field are version 1, which only had the CCD
+// arrays; version 2 added the per-monitor format (monitors), so older
+// builds can tell a file they would misread.
+const SchemaVersion = 2
+
+// NewerVersionError is returned for a profile written by a newer build.
+type NewerVersionError struct {
+	Version int
+}
+
+func (e *NewerVersionError) Error() string {
+	return fmt.Sprintf("profile schema version %d is newer than this build supports (%d); update monitor-switcher", e.Version, SchemaVersion)
+}
+
+// migration upgrades a decoded profile document from version from to
+// from+1. Documents are generic JSON so a migration never depends on the
+// current Profile struct.
+type migration struct {
+	from        int
+	description string
+	apply       func(doc map[string]any) error
+}
+
+// migrations is ordered by from. A version whose documents are valid in
+// the next one as they are has no entry: version 1 files are read as
+// version 2 unchanged, and hand-written per-monitor files without a
+// schemaVersion load too.
+var migrations = []migration{}
+
+// decode parses profile JSON of any supported version, applying
+// migrations. It returns the version the data was written with.
+func decode(data []byte) (Profile, int, error) {
+	var profile Profile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return profile, 0, fmt.Errorf("parse profile: %w", err)
+	}
+
+	version := 1
+	if raw, ok := doc["schemaVersion"]; ok {
+		number, ok := raw.(json.Number)
+		if !ok {
+			return profile, 0, fmt.Errorf("parse profile: schemaVersion is not a number")
+		}
+		v, err := number.Int64()
+		if err != nil || v < 1 {
+			return profile, 0, fmt.Errorf("parse profile: invalid schemaVersion %s", number)
+		}
+		version = int(v)
+	}
+	if version > SchemaVersion {
+		return profile, version, &NewerVersionError{Version: version}
+	}
+
+	for _, m := range migrations {
+		if m.from < version {
+			continue
+		}
+		if err := m.apply(doc); err != nil {
+			return profile, version, fmt.Errorf("migrate profile from version %d (%s): %w", m.from, m.description, err)
+		}
+	}
+	doc["schemaVersion"] = SchemaVersion
+	if err := numberEnums(doc); err != nil {
+		return profile, version, fmt.Errorf("parse profile: %w", err)
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return profile, version, fmt.Errorf("migrate profile: %w", err)
+	}
+	if err := json.Unmarshal(migrated, &profile); err != nil {
+		return profile, version, fmt.Errorf("parse profile: %w", err)
+	}
+	return profile, version, nil
+}
+
+// Migrate rewrites a profile in the current schema if it is older, keeping
+// the original next to it as {path}.v{N}.bak. It returns the version the
+// file had and the backup path ("" when the file was already current).
+func Migrate(path string) (int, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("read profile: %w", err)
+	}
+	if isXML(data) {
+		return 0, "", fmt.Errorf("%s is a MonitorSwitcher XML profile; use -convert to turn it into JSON", path)
+	}
+	profile, version, err := decodeAs(EncodingFor(path), data)
+	if err != nil {
+		return version, "", err
+	}
+	if version == SchemaVersion {
+		return version, "", nil
+	}
+	if profile.LayoutHash != "" {
+		applyRuntime(path, &profile)
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := os.WriteFile(backup, data, 0644); err != nil {
+		return version, "", fmt.Errorf("write backup: %w", err)
+	}
+	if err := Save(path, profile, SaveOptions{Names: usesNames(EncodingFor(path), data)}); err != nil {
+		return version, backup, err
+	}
+	return version, backup, nil
+}
@@ -0,0 +1,178 @@
+package profile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeVersions(t *testing.T) {
+	const arrays = `"pathInfo": [], "modeInfo": [], "additionalInfo": []`
+	const monitors = `"monitors": [{"name": "DELL P2419H", "enabled": true, "width": 1920, "height": 1080}]`
+
+	tests := []struct {
+		name         string
+		data         string
+		wantVersion  int
+		wantMonitors int
+		wantErr      string
+	}{
+		{name: "no schemaVersion", data: `{` + arrays + `}`, wantVersion: 1},
+		{name: "version 1 monitors kept", data: `{` + arrays + `, ` + monitors + `}`, wantVersion: 1, wantMonitors: 1},
+		{name: "version 2 monitors kept", data: `{"schemaVersion": 2, ` + arrays + `, ` + monitors + `}`, wantVersion: 2, wantMonitors: 1},
+		{name: "newer version", data: `{"schemaVersion": 3, ` + arrays + `}`, wantVersion: 3, wantErr: "newer than this build supports"},
+		{name: "zero version", data: `{"schemaVersion": 0}`, wantErr: "invalid schemaVersion 0"},
+		{name: "string version", data: `{"schemaVersion": "2"}`, wantErr: "schemaVersion is not a number"},
+		{name: "not JSON", data: `pathInfo`, wantErr: "parse profile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, version, err := decode([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decode() = %v, want error containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("decode() = %v", err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", version, tt.wantVersion)
+			}
+			if err == nil && profile.SchemaVersion != SchemaVersion {
+				t.Errorf("SchemaVersion = %d after migration, want %d", profile.SchemaVersion, SchemaVersion)
+			}
+			if len(profile.Monitors) != tt.wantMonitors {
+				t.Errorf("%d monitors, want %d", len(profile.Monitors), tt.wantMonitors)
+			}
+		})
+	}
+
+	var newer *NewerVersionError
+	if _, _, err := decode([]byte(`{"schemaVersion": 9}`)); !errors.As(err, &newer) || newer.Version != 9 {
+		t.Errorf("decode() of version 9 = %v, want *NewerVersionError", err)
+	}
+}
+
+// withMigrations replaces the registry for the rest of the test.
+func withMigrations(t *testing.T, registry ...migration) {
+	t.Helper()
+	saved := migrations
+	migrations = registry
+	t.Cleanup(func() { migrations = saved })
+}
+
+func TestMigrations(t *testing.T) {
+	var applied []string
+	withMigrations(t,
+		migration{from: 1, description: "rename strategy", apply: func(doc map[string]any) error {
+			applied = append(applied, "rename strategy")
+			if strategy, ok := doc["strategy"]; ok {
+				doc["strategies"] = []any{strategy}
+				delete(doc, "strategy")
+			}
+			return nil
+		}},
+		// Only sees strategies if the rename ran first.
+		migration{from: 1, description: "fall back as saved", apply: func(doc map[string]any) error {
+			applied = append(applied, "fall back as saved")
+			strategies, _ := doc["strategies"].([]any)
+			doc["strategies"] = append(strategies, "as-saved")
+			return nil
+		}},
+	)
+
+	tests := []struct {
+		name           string
+		data           string
+		wantVersion    int
+		wantApplied    []string
+		wantStrategies []string
+	}{
+		{
+			name:           "previous version upgraded in order",
+			data:           `{"strategy": "identity"}`,
+			wantVersion:    SchemaVersion - 1,
+			wantApplied:    []string{"rename strategy", "fall back as saved"},
+			wantStrategies: []string{"identity", "as-saved"},
+		},
+		{
+			name:           "current version untouched",
+			data:           `{"schemaVersion": 2, "strategies": ["identity"]}`,
+			wantVersion:    SchemaVersion,
+			wantStrategies: []string{"identity"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied = nil
+			profile, version, err := decode([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("decode() = %v", err)
+			}
+			if version != tt.wantVersion || profile.SchemaVersion != SchemaVersion {
+				t.Errorf("version = %d, SchemaVersion = %d; want %d, %d", version, profile.SchemaVersion, tt.wantVersion, SchemaVersion)
+			}
+			if strings.Join(applied, ",") != strings.Join(tt.wantApplied, ",") {
+				t.Errorf("applied %q, want %q", applied, tt.wantApplied)
+			}
+			if strings.Join(profile.Strategies, ",") != strings.Join(tt.wantStrategies, ",") {
+				t.Errorf("strategies = %q, want %q", profile.Strategies, tt.wantStrategies)
+			}
+		})
+	}
+
+	broken := errors.New("monitors is not a list")
+	withMigrations(t, migration{from: 1, description: "check monitors", apply: func(map[string]any) error { return broken }})
+	_, version, err := decode([]byte(`{"monitors": {}}`))
+	if !errors.Is(err, broken) || !strings.Contains(err.Error(), "migrate profile from version 1 (check monitors)") {
+		t.Errorf("decode() with a failing migration = %v", err)
+	}
+	if version != 1 {
+		t.Errorf("version = %d with a failing migration, want 1", version)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.monitorprofile")
+	original := `{"pathInfo": [], "modeInfo": [], "additionalInfo": [], "strategies": ["identity"]}`
+	if err := os.WriteFile(old, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	version, backup, err := Migrate(old)
+	if err != nil {
+		t.Fatalf("Migrate() = %v", err)
+	}
+	if version != 1 || backup != old+".v1.bak" {
+		t.Errorf("Migrate() = %d, %q; want 1, %q", version, backup, old+".v1.bak")
+	}
+	if data, _ := os.ReadFile(backup); string(data) != original {
+		t.Errorf("backup = %q, want the original file", data)
+	}
+	migrated, version, err := loadFile(t, old)
+	if err != nil || version != SchemaVersion {
+		t.Fatalf("migrated file: version %d, %v", version, err)
+	}
+	if len(migrated.Strategies) != 1 {
+		t.Errorf("strategies lost in migration: %v", migrated.Strategies)
+	}
+
+	version, backup, err = Migrate(old)
+	if err != nil || version != SchemaVersion || backup != "" {
+		t.Errorf("Migrate() of a current file = %d, %q, %v; want %d, no backup", version, backup, err, SchemaVersion)
+	}
+}
+
+// loadFile decodes the file at path and returns the version it was
+// written with.
+func loadFile(t *testing.T, path string) (Profile, int, error) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeAs(EncodingFor(path), data)
+}
@@ -0,0 +1,166 @@
+package profile
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// enumCodec converts one enum or flag field between its number and the
+// name the ccd package gives it.
+type enumCodec struct {
+	name  func(value uint32) string
+	parse func(text string) (uint32, error)
+}
+
+func codecOf[T interface {
+	~uint32
+	fmt.Stringer
+}, P interface {
+	*T
+	encoding.TextUnmarshaler
+}]() enumCodec {
+	return enumCodec{
+		name: func(value uint32) string { return T(value).String() },
+		parse: func(text string) (uint32, error) {
+			var value T
+			err := P(&value).UnmarshalText([]byte(text))
+			return uint32(value), err
+		},
+	}
+}
+
+var (
+	outputTechnologyCodec = codecOf[ccd.DisplayConfigVideoOutputTechnology]()
+	scanLineOrderingCodec = codecOf[ccd.DisplayConfigScanLineOrdering]()
+)
+
+// enumFields lists, per top-level array, the keys of each element that hold
+// an enum or flags, as dotted paths.
+var enumFields = map[string]map[string]enumCodec{
+	"pathInfo": {
+		"flags":                       codecOf[ccd.DisplayConfigFlags](),
+		"sourceInfo.statusFlags":      codecOf[ccd.DisplayConfigSourceStatus](),
+		"targetInfo.outputTechnology": outputTechnologyCodec,
+		"targetInfo.rotation":         codecOf[ccd.DisplayConfigRotation](),
+		"targetInfo.scaling":          codecOf[ccd.DisplayConfigScaling](),
+		"targetInfo.scanLineOrdering": scanLineOrderingCodec,
+		"targetInfo.statusFlags":      codecOf[ccd.DisplayConfigTargetStatus](),
+	},
+	"modeInfo": {
+		"sourceMode.pixelFormat":                            codecOf[ccd.DisplayConfigPixelFormat](),
+		"targetMode.targetVideoSignalInfo.videoStandard":    codecOf[ccd.D3DkmdtVideoSignalStandard](),
+		"targetMode.targetVideoSignalInfo.scanLineOrdering": scanLineOrderingCodec,
+	},
+	"additionalInfo": {"outputTechnology": outputTechnologyCodec},
+	"monitors":       {"outputTechnology": outputTechnologyCodec},
+}
+
+// numberEnums replaces enum and flag names in a decoded document with their
+// numbers, so profiles saved with SaveOptions.Names decode into Profile.
+func numberEnums(doc map[string]any) error {
+	for section, fields := range enumFields {
+		items, _ := doc[section].([]any)
+		for i, item := range items {
+			for field, codec := range fields {
+				parent, key := lookupField(item, field)
+				text, ok := parent[key].(string)
+				if !ok {
+					continue
+				}
+				value, err := codec.parse(text)
+				if err != nil {
+					return fmt.Errorf("%s[%d].%s: %w", section, i, field, err)
+				}
+				parent[key] = json.Number(strconv.FormatUint(uint64(value), 10))
+			}
+		}
+	}
+	return nil
+}
+
+// lookupField returns the object holding the last key of a dotted path
+// below item, or nil when part of the path is missing.
+func lookupField(item any, field string) (map[string]any, string) {
+	keys := strings.Split(field, ".")
+	object, _ := item.(map[string]any)
+	for _, key := range keys[:len(keys)-1] {
+		object, _ = object[key].(map[string]any)
+	}
+	return object, keys[len(keys)-1]
+}
+
+// nameEnums replaces the enum and flag numbers in a document built by
+// nodeFromJSON with their names.
+func nameEnums(doc *yaml.Node) {
+	root := doc.Content[0]
+	for section, fields := range enumFields {
+		items := mappingValue(root, section)
+		if items == nil || items.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, item := range items.Content {
+			for field, codec := range fields {
+				node := item
+				for _, key := range strings.Split(field, ".") {
+					node = mappingValue(node, key)
+				}
+				if node == nil || node.Tag != "!!int" {
+					continue
+				}
+				value, err := strconv.ParseUint(node.Value, 10, 32)
+				if err != nil {
+					continue
+				}
+				// Values without a name stay numbers.
+				if name := codec.name(uint32(value)); name != node.Value {
+					node.Tag, node.Value = "!!str", name
+				}
+			}
+		}
+	}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// usesNames reports whether profile data in encoding writes any enum or
+// flag by name, so Convert and Migrate can keep doing so.
+func usesNames(encoding string, data []byte) bool {
+	data, err := toJSON(encoding, data)
+	if err != nil {
+		return false
+	}
+	var doc map[string]any
+	if json.Unmarshal(data, &doc) != nil {
+		return false
+	}
+	for section, fields := range enumFields {
+		items, _ := doc[section].([]any)
+		for _, item := range items {
+			for field := range fields {
+				parent, key := lookupField(item, field)
+				if _, ok := parent[key].(string); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNumberEnums(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    PathInfo
+		wantErr string
+	}{
+		{
+			name: "names",
+			data: `{"pathInfo": [{"flags": "active|supportVirtualMode", "sourceInfo": {"statusFlags": "inUse"},
+				"targetInfo": {"outputTechnology": "hdmi", "rotation": "rotate90", "scaling": "Preferred", "scanLineOrdering": "progressive", "statusFlags": "inUse|forcible"}}]}`,
+			want: PathInfo{
+				Flags:      pathFlagActive | pathFlagSupportVirtualMode,
+				SourceInfo: PathSourceInfo{StatusFlags: 1},
+				TargetInfo: PathTargetInfo{OutputTechnology: 5, Rotation: 2, Scaling: 128, ScanLineOrdering: 1, StatusFlags: 3},
+			},
+		},
+		{
+			name: "numbers and names mixed",
+			data: `{"pathInfo": [{"flags": 1, "targetInfo": {"rotation": "4", "statusFlags": "inUse|0x100"}}]}`,
+			want: PathInfo{Flags: pathFlagActive, TargetInfo: PathTargetInfo{Rotation: 4, StatusFlags: 0x101}},
+		},
+		{
+			name:    "unknown name",
+			data:    `{"pathInfo": [{"flags": "active"}, {"targetInfo": {"scaling": "squashed"}}]}`,
+			wantErr: `pathInfo[1].targetInfo.scaling: unknown scaling "squashed"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, err := decode([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("decode() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode() = %v", err)
+			}
+			if p.PathInfo[0] != tt.want {
+				t.Errorf("pathInfo[0] = %+v, want %+v", p.PathInfo[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveNames(t *testing.T) {
+	p := validProfile()
+	p.PathInfo[0].Flags |= 0x40
+	p.ModeInfo[0].TargetMode.TargetVideoSignalInfo.VideoStandard = 200
+
+	for _, ext := range []string{".json", ".yaml", ".toml"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "names"+ext)
+			if err := Save(path, p, SaveOptions{Names: true}); err != nil {
+				t.Fatalf("Save() = %v", err)
+			}
+			data, _ := os.ReadFile(path)
+			for _, name := range []string{"active|0x40", "active|supportVirtualMode", "displayPortExternal", "identity", "preferred", "vesaDmt"} {
+				if !strings.Contains(string(data), name) {
+					t.Errorf("saved file has no %q:\n%s", name, data)
+				}
+			}
+			// A value without a name stays a number.
+			if strings.Contains(string(data), `"200"`) || strings.Contains(string(data), "'200'") {
+				t.Errorf("unnamed video standard written as a string:\n%s", data)
+			}
+			if !usesNames(EncodingFor(path), data) {
+				t.Error("usesNames() = false for a file saved with names")
+			}
+		})
+	}
+}
+
+func TestUsesNames(t *testing.T) {
+	tests := []struct {
+		encoding string
+		data     string
+		want     bool
+	}{
+		{EncodingJSON, `{"pathInfo": [{"flags": 1}]}`, false},
+		{EncodingJSON, `{"pathInfo": [{"flags": "active"}]}`, true},
+		{EncodingJSON, `{"modeInfo": [{"sourceMode": {"pixelFormat": "32bpp"}}]}`, true},
+		{EncodingJSON, `{"monitors": [{"name": "DELL", "scaling": "stretched"}]}`, false},
+		{EncodingJSON, `{"monitors": [{"outputTechnology": "hdmi"}]}`, true},
+		{EncodingYAML, "pathInfo:\n  - targetInfo:\n      rotation: rotate90\n", true},
+		{EncodingTOML, "[[pathInfo]]\nflags = 1\n", false},
+		{EncodingJSON, `{`, false},
+	}
+	for _, tt := range tests {
+		if got := usesNames(tt.encoding, []byte(tt.data)); got != tt.want {
+			t.Errorf("usesNames(%s, %q) = %v, want %v", tt.encoding, tt.data, got, tt.want)
+		}
+	}
+}
@@ -1,15 +1,65 @@
 package profile
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
+
+	"gopkg.in/yaml.v3"
 )
 
+// Profile is a saved display configuration in one of two forms: the raw
+// CCD arrays (PathInfo, ModeInfo, AdditionalInfo) as QueryDisplayConfig
+// returned them, or a per-monitor layout (Monitors) meant for hand editing.
 type Profile struct {
+	// SchemaVersion is set by Save; see the SchemaVersion constant.
+	SchemaVersion  int              `json:"schemaVersion"`
 	PathInfo       []PathInfo       `json:"pathInfo"`
 	ModeInfo       []ModeInfo       `json:"modeInfo"`
 	AdditionalInfo []AdditionalInfo `json:"additionalInfo"`
+	Monitors       []Monitor        `json:"monitors,omitempty"`
+	// Strategies optionally sets the order in which the loader tries its
+	// strategies for this profile; those left out are not tried.
+	Strategies []string `json:"strategies,omitempty"`
+	// Fingerprint identifies the monitors that were connected when the
+	// profile was saved, whether or not they were in use.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// LayoutHash is set by canonical saves; see Canonicalize.
+	LayoutHash string `json:"layoutHash,omitempty"`
+}
+
+// IsLayout reports whether the profile uses the per-monitor format.
+func (p Profile) IsLayout() bool {
+	return len(p.Monitors) > 0 && len(p.PathInfo) == 0
+}
+
+// Monitor is one monitor in the per-monitor format. The identity fields
+// select the connected monitor; only those that are set are compared. The
+// rest describe how it is shown.
+type Monitor struct {
+	Name              string `json:"name,omitempty"`
+	DevicePath        string `json:"devicePath,omitempty"`
+	ManufactureID     uint16 `json:"manufactureId,omitempty"`
+	ProductCodeID     uint16 `json:"productCodeId,omitempty"`
+	ConnectorInstance uint32 `json:"connectorInstance,omitempty"`
+	OutputTechnology  uint32 `json:"outputTechnology,omitempty"`
+	TargetID          uint32 `json:"targetId,omitempty"`
+
+	Enabled bool `json:"enabled"`
+	// Primary moves this monitor to (0,0) and the others with it.
+	Primary bool `json:"primary,omitempty"`
+	// Width and Height are the resolution before rotation, as Windows
+	// settings show it.
+	Width  uint32 `json:"width,omitempty"`
+	Height uint32 `json:"height,omitempty"`
+	// RefreshHz is the vertical refresh rate in Hz, e.g. 59.94. Zero keeps
+	// whatever the driver picks.
+	RefreshHz float64 `json:"refreshHz,omitempty"`
+	Position  PointL  `json:"position"`
+	// Rotation is clockwise degrees: 0, 90, 180 or 270.
+	Rotation int `json:"rotation"`
+	// Scaling is one of identity, centered, stretched, aspect-ratio,
+	// custom or preferred; empty means preferred.
+	Scaling string `json:"scaling,omitempty"`
 }
 
 type LUID struct {
@@ -107,28 +157,81 @@ type AdditionalInfo struct {
 	Valid                 bool   `json:"valid"`
 	MonitorDevicePath     string `json:"monitorDevicePath"`
 	MonitorFriendlyDevice string `json:"monitorFriendlyDevice"`
+	ConnectorInstance     uint32 `json:"connectorInstance,omitempty"`
+	OutputTechnology      uint32 `json:"outputTechnology,omitempty"`
 }
 
+// Load reads a profile in the encoding its extension selects (see
+// EncodingFor), migrating older schema versions in memory. Profiles saved by
+// the original C# MonitorSwitcher (XML) are converted on the fly. For a
+// canonical profile the runtime values saved next to it are restored.
 func Load(path string) (Profile, error) {
-	var profile Profile
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return profile, fmt.Errorf("read profile: %w", err)
+		return Profile{}, fmt.Errorf("read profile: %w", err)
 	}
-	if err := json.Unmarshal(data, &profile); err != nil {
-		return profile, fmt.Errorf("parse profile: %w", err)
+	if isXML(data) {
+		return decodeXML(data)
 	}
-	return profile, nil
-}
-
-func Save(path string, profile Profile) error {
-	data, err := json.MarshalIndent(profile, "", "  ")
+	profile, _, err := decodeAs(EncodingFor(path), data)
+	if err == nil && profile.LayoutHash != "" {
+		applyRuntime(path, &profile)
+	}
+	return profile, err
+}
+
+// SaveOptions controls how Save writes a profile.
+type SaveOptions struct {
+	// Canonical writes the layout in canonical form with its LayoutHash and
+	// moves the volatile values to RuntimePath(path), so saving the same
+	// layout again gives an identical file. Profiles that already carry a
+	// LayoutHash are always saved this way.
+	Canonical bool
+	// Names writes enums and flags (rotation, scaling, output technology,
+	// path and status flags, ...) by name instead of number. Load reads
+	// both.
+	Names bool
+}
+
+// Save writes a profile in the encoding its extension selects. When the file
+// already exists in an encoding with comments, they are kept on the keys
+// and array elements that are still there.
+func Save(path string, profile Profile, opts SaveOptions) error {
+	return save(path, profile, readComments(path), opts)
+}
+
+// Convert rewrites the profile at in to out, changing encoding by
+// extension and carrying over in's comments where out can hold them. Enums
+// written by name in in are written by name in out.
+func Convert(in, out string) error {
+	profile, err := Load(in)
 	if err != nil {
-		return fmt.Errorf("serialize profile: %w", err)
+		return err
+	}
+	data, _ := os.ReadFile(in)
+	names := !isXML(data) && usesNames(EncodingFor(in), data)
+	return save(out, profile, readComments(in), SaveOptions{Names: names})
+}
+
+func save(path string, profile Profile, comments *yaml.Node, opts SaveOptions) error {
+	profile.SchemaVersion = SchemaVersion
+	var runtime *Runtime
+	if opts.Canonical || profile.LayoutHash != "" {
+		canonical, values := Canonicalize(profile)
+		profile = canonical
+		if len(profile.PathInfo) > 0 {
+			runtime = &values
+		}
+	}
+	data, err := encode(EncodingFor(path), profile, comments, opts.Names)
+	if err != nil {
+		return err
 	}
-	data = append(data, '\n')
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("write profile: %w", err)
 	}
+	if runtime != nil {
+		return saveRuntime(path, *runtime)
+	}
 	return nil
 }
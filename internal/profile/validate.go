@@ -0,0 +1,348 @@
+package profile
+
+import (
+	"fmt"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// Issue is one structural problem found by Validate. Path is the JSON path
+// of the offending value, e.g. "pathInfo[1].targetInfo.modeInfoIdx".
+type Issue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return i.Path + ": " + i.Message
+}
+
+// Values from wingdi.h that Validate checks against, as the untyped
+// profile fields hold them.
+const (
+	modeInfoTypeSource       = uint32(ccd.DisplayConfigModeInfoTypeSource)
+	modeInfoTypeTarget       = uint32(ccd.DisplayConfigModeInfoTypeTarget)
+	modeInfoTypeDesktopImage = uint32(ccd.DisplayConfigModeInfoTypeDesktopImage)
+
+	pathFlagActive             = uint32(ccd.DisplayConfigFlagPathActive)
+	pathFlagSupportVirtualMode = uint32(ccd.DisplayConfigFlagPathSupportVirtualMode)
+	pathValidFlags             = uint32(ccd.DisplayConfigFlagPathValidFlags)
+
+	modeIdxInvalid       = ccd.DisplayConfigPathModeIdxInvalid
+	packedModeIdxInvalid = ccd.DisplayConfigPathPackedIdxInvalid
+
+	// maxCoordinate bounds desktop coordinates; GDI keeps the virtual
+	// screen within a signed 16-bit range.
+	maxCoordinate = 32767
+)
+
+var validScalingNames = map[string]bool{
+	"": true, "identity": true, "centered": true, "stretched": true,
+	"aspect-ratio": true, "custom": true, "preferred": true,
+}
+
+type validator struct {
+	profile Profile
+	issues  []Issue
+}
+
+func (v *validator) add(path, format string, args ...any) {
+	v.issues = append(v.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks a profile's structure without touching the displays: that
+// every mode index (plain or packed) points at a mode of the right type,
+// that additionalInfo lines up with modeInfo, that rationals, enums and
+// source positions are in range, and that per-monitor entries are complete.
+// It returns every issue found, or nil for a well-formed profile.
+func Validate(p Profile) []Issue {
+	v := &validator{profile: p}
+	if len(p.PathInfo) == 0 && len(p.Monitors) == 0 {
+		v.add("$", "profile has neither pathInfo nor monitors")
+		return v.issues
+	}
+	if len(p.PathInfo) > 0 && len(p.Monitors) > 0 {
+		v.add("monitors", "ignored because pathInfo is present")
+	}
+	for i, name := range p.Strategies {
+		if strings.TrimSpace(name) == "" {
+			v.add(fmt.Sprintf("strategies[%d]", i), "empty strategy name")
+		}
+	}
+	if p.LayoutHash != "" && p.LayoutHash != LayoutHash(p) {
+		v.add("layoutHash", "does not match the layout; the file was edited after a canonical save")
+	}
+	if p.IsLayout() {
+		v.monitors()
+		return v.issues
+	}
+
+	for i := range p.PathInfo {
+		v.path(i)
+	}
+	for i := range p.ModeInfo {
+		v.mode(i)
+	}
+	v.additional()
+	v.sourcePositions()
+	return v.issues
+}
+
+func (v *validator) path(i int) {
+	path := &v.profile.PathInfo[i]
+	prefix := fmt.Sprintf("pathInfo[%d]", i)
+	active := path.Flags&pathFlagActive != 0
+	if extra := path.Flags &^ pathValidFlags; extra != 0 {
+		v.add(prefix+".flags", "unknown flag bits 0x%X", extra)
+	}
+
+	source := prefix + ".sourceInfo.modeInfoIdx"
+	target := prefix + ".targetInfo.modeInfoIdx"
+	if path.Flags&pathFlagSupportVirtualMode != 0 {
+		// High word: source/target mode; low word: clone group/desktop mode.
+		v.modeRef(source, "source mode", path.SourceInfo.ModeInfoIdx>>16, packedModeIdxInvalid, modeInfoTypeSource, active)
+		v.modeRef(target, "target mode", path.TargetInfo.ModeInfoIdx>>16, packedModeIdxInvalid, modeInfoTypeTarget, false)
+		v.modeRef(target, "desktop image mode", path.TargetInfo.ModeInfoIdx&0xFFFF, packedModeIdxInvalid, modeInfoTypeDesktopImage, false)
+	} else {
+		v.modeRef(source, "source mode", path.SourceInfo.ModeInfoIdx, modeIdxInvalid, modeInfoTypeSource, active)
+		v.modeRef(target, "target mode", path.TargetInfo.ModeInfoIdx, modeIdxInvalid, modeInfoTypeTarget, false)
+	}
+	v.modeID(prefix+".sourceInfo.id", path.SourceInfo.ID, path.SourceInfo.ModeInfoIdx, path.Flags, modeInfoTypeSource)
+	v.modeID(prefix+".targetInfo.id", path.TargetInfo.ID, path.TargetInfo.ModeInfoIdx, path.Flags, modeInfoTypeTarget)
+
+	info := &path.TargetInfo
+	if !ccd.DisplayConfigVideoOutputTechnology(info.OutputTechnology).Defined() {
+		v.add(prefix+".targetInfo.outputTechnology", "unknown output technology %d", info.OutputTechnology)
+	}
+	if info.RefreshRate.Denominator == 0 && info.RefreshRate.Numerator != 0 {
+		v.add(prefix+".targetInfo.refreshRate.denominator", "zero denominator")
+	}
+	if !ccd.DisplayConfigScanLineOrdering(info.ScanLineOrdering).Defined() {
+		v.add(prefix+".targetInfo.scanLineOrdering", "unknown scan line ordering %d", info.ScanLineOrdering)
+	}
+	// Inactive paths come back from Windows with rotation and scaling
+	// unset, so only active ones must carry real values.
+	if active {
+		if !ccd.DisplayConfigRotation(info.Rotation).Defined() {
+			v.add(prefix+".targetInfo.rotation", "must be 1 (identity) to 4 (270 degrees), got %d", info.Rotation)
+		}
+		if !ccd.DisplayConfigScaling(info.Scaling).Defined() {
+			v.add(prefix+".targetInfo.scaling", "unknown scaling %d", info.Scaling)
+		}
+	}
+}
+
+// modeRef checks a mode index against modeInfo. required reports an
+// invalid index as an issue too.
+func (v *validator) modeRef(path, what string, idx, invalid, infoType uint32, required bool) {
+	if idx == invalid {
+		if required {
+			v.add(path, "active path has no %s", what)
+		}
+		return
+	}
+	if int(idx) >= len(v.profile.ModeInfo) {
+		v.add(path, "%s index %d is out of range (%d modes)", what, idx, len(v.profile.ModeInfo))
+		return
+	}
+	if got := v.profile.ModeInfo[idx].InfoType; got != infoType {
+		v.add(path, "%s index %d points at a mode of infoType %d, want %d", what, idx, got, infoType)
+	}
+}
+
+// modeID checks that the mode a path uses describes the same source or
+// target ID as the path.
+func (v *validator) modeID(path string, id, modeIdx, flags, infoType uint32) {
+	idx := modeIdx
+	if flags&pathFlagSupportVirtualMode != 0 {
+		if idx >>= 16; idx == packedModeIdxInvalid {
+			return
+		}
+	}
+	if int(idx) >= len(v.profile.ModeInfo) {
+		return
+	}
+	mode := &v.profile.ModeInfo[idx]
+	if mode.InfoType == infoType && mode.ID != id {
+		v.add(path, "is %d but modeInfo[%d] is for id %d", id, idx, mode.ID)
+	}
+}
+
+func (v *validator) mode(i int) {
+	mode := &v.profile.ModeInfo[i]
+	prefix := fmt.Sprintf("modeInfo[%d]", i)
+	members := map[string]bool{
+		"sourceMode":       mode.SourceMode != nil,
+		"targetMode":       mode.TargetMode != nil,
+		"desktopImageInfo": mode.DesktopImageInfo != nil,
+	}
+	var want string
+	switch mode.InfoType {
+	case modeInfoTypeSource:
+		want = "sourceMode"
+	case modeInfoTypeTarget:
+		want = "targetMode"
+	case modeInfoTypeDesktopImage:
+		want = "desktopImageInfo"
+	default:
+		v.add(prefix+".infoType", "must be 1 (source), 2 (target) or 3 (desktop image), got %d", mode.InfoType)
+		return
+	}
+	if !members[want] {
+		v.add(prefix, "infoType %d needs %s", mode.InfoType, want)
+		return
+	}
+	for _, name := range []string{"sourceMode", "targetMode", "desktopImageInfo"} {
+		if name != want && members[name] {
+			v.add(prefix+"."+name, "ignored for infoType %d", mode.InfoType)
+		}
+	}
+
+	switch mode.InfoType {
+	case modeInfoTypeSource:
+		source := mode.SourceMode
+		if source.Width == 0 || source.Height == 0 {
+			v.add(prefix+".sourceMode", "size %dx%d is empty", source.Width, source.Height)
+		}
+		if !ccd.DisplayConfigPixelFormat(source.PixelFormat).Defined() {
+			v.add(prefix+".sourceMode.pixelFormat", "must be 1 to 5, got %d", source.PixelFormat)
+		}
+	case modeInfoTypeTarget:
+		signal := &mode.TargetMode.TargetVideoSignalInfo
+		signalPrefix := prefix + ".targetMode.targetVideoSignalInfo"
+		if signal.HSyncFreq.Denominator == 0 {
+			v.add(signalPrefix+".hSyncFreq.denominator", "zero denominator")
+		}
+		if signal.VSyncFreq.Denominator == 0 {
+			v.add(signalPrefix+".vSyncFreq.denominator", "zero denominator")
+		}
+		if signal.ActiveSize.Cx == 0 || signal.ActiveSize.Cy == 0 {
+			v.add(signalPrefix+".activeSize", "size %dx%d is empty", signal.ActiveSize.Cx, signal.ActiveSize.Cy)
+		}
+		if signal.TotalSize.Cx < signal.ActiveSize.Cx || signal.TotalSize.Cy < signal.ActiveSize.Cy {
+			v.add(signalPrefix+".totalSize", "%dx%d is smaller than the active size", signal.TotalSize.Cx, signal.TotalSize.Cy)
+		}
+		if !ccd.DisplayConfigScanLineOrdering(signal.ScanLineOrdering).Defined() {
+			v.add(signalPrefix+".scanLineOrdering", "unknown scan line ordering %d", signal.ScanLineOrdering)
+		}
+	case modeInfoTypeDesktopImage:
+		info := mode.DesktopImageInfo
+		for _, field := range []struct {
+			name string
+			rect RectL
+		}{{"desktopImageRegion", info.DesktopImageRegion}, {"desktopImageClip", info.DesktopImageClip}} {
+			name, rect := field.name, field.rect
+			if rect.Right <= rect.Left || rect.Bottom <= rect.Top {
+				v.add(prefix+".desktopImageInfo."+name, "rectangle (%d,%d)-(%d,%d) is empty", rect.Left, rect.Top, rect.Right, rect.Bottom)
+			}
+		}
+	}
+}
+
+func (v *validator) additional() {
+	additional := v.profile.AdditionalInfo
+	if len(additional) == 0 {
+		return
+	}
+	if len(additional) != len(v.profile.ModeInfo) {
+		v.add("additionalInfo", "has %d entries but modeInfo has %d; they must line up", len(additional), len(v.profile.ModeInfo))
+	}
+	for i, info := range additional {
+		if i >= len(v.profile.ModeInfo) {
+			break
+		}
+		if info.Valid && v.profile.ModeInfo[i].InfoType != modeInfoTypeTarget {
+			v.add(fmt.Sprintf("additionalInfo[%d]", i), "describes a monitor but modeInfo[%d] is not a target mode", i)
+		}
+	}
+}
+
+// sourcePositions checks that the active sources form a usable desktop:
+// coordinates in range, no two sources overlapping, one at (0,0).
+func (v *validator) sourcePositions() {
+	type placed struct {
+		idx  int
+		rect RectL
+	}
+	var sources []placed
+	seen := map[int]bool{}
+	for _, path := range v.profile.PathInfo {
+		if path.Flags&pathFlagActive == 0 {
+			continue
+		}
+		idx := path.SourceInfo.ModeInfoIdx
+		if path.Flags&pathFlagSupportVirtualMode != 0 {
+			idx >>= 16
+		}
+		if int(idx) >= len(v.profile.ModeInfo) || seen[int(idx)] {
+			continue
+		}
+		mode := v.profile.ModeInfo[idx].SourceMode
+		if mode == nil || v.profile.ModeInfo[idx].InfoType != modeInfoTypeSource {
+			continue
+		}
+		seen[int(idx)] = true
+		pos := mode.Position
+		rect := RectL{Left: pos.X, Top: pos.Y, Right: pos.X + int32(mode.Width), Bottom: pos.Y + int32(mode.Height)}
+		if pos.X < -maxCoordinate || pos.Y < -maxCoordinate || rect.Right > maxCoordinate || rect.Bottom > maxCoordinate {
+			v.add(fmt.Sprintf("modeInfo[%d].sourceMode.position", idx), "(%d,%d) with size %dx%d is outside the desktop range", pos.X, pos.Y, mode.Width, mode.Height)
+		}
+		sources = append(sources, placed{idx: int(idx), rect: rect})
+	}
+
+	origin := len(sources) == 0
+	for i, a := range sources {
+		if a.rect.Left == 0 && a.rect.Top == 0 {
+			origin = true
+		}
+		for _, b := range sources[i+1:] {
+			if a.rect.Left < b.rect.Right && b.rect.Left < a.rect.Right && a.rect.Top < b.rect.Bottom && b.rect.Top < a.rect.Bottom {
+				v.add(fmt.Sprintf("modeInfo[%d].sourceMode.position", b.idx), "overlaps modeInfo[%d]", a.idx)
+			}
+		}
+	}
+	if !origin {
+		v.add("modeInfo", "no active source is at (0,0); the primary display must be")
+	}
+}
+
+func (v *validator) monitors() {
+	primaries, enabled := 0, 0
+	for i, monitor := range v.profile.Monitors {
+		prefix := fmt.Sprintf("monitors[%d]", i)
+		if monitor.Name == "" && monitor.DevicePath == "" && monitor.ManufactureID == 0 && monitor.ProductCodeID == 0 {
+			v.add(prefix, "needs name, devicePath or manufactureId/productCodeId to identify the monitor")
+		}
+		if !monitor.Enabled {
+			continue
+		}
+		enabled++
+		if monitor.Primary {
+			primaries++
+		}
+		if monitor.Width == 0 || monitor.Height == 0 {
+			v.add(prefix, "width and height are required for an enabled monitor")
+		}
+		if monitor.RefreshHz < 0 {
+			v.add(prefix+".refreshHz", "must not be negative")
+		}
+		switch monitor.Rotation {
+		case 0, 90, 180, 270:
+		default:
+			v.add(prefix+".rotation", "must be 0, 90, 180 or 270, got %d", monitor.Rotation)
+		}
+		if !validScalingNames[strings.ToLower(monitor.Scaling)] {
+			v.add(prefix+".scaling", "unknown scaling %q", monitor.Scaling)
+		}
+		if monitor.Position.X < -maxCoordinate || monitor.Position.X > maxCoordinate || monitor.Position.Y < -maxCoordinate || monitor.Position.Y > maxCoordinate {
+			v.add(prefix+".position", "(%d,%d) is outside the desktop range", monitor.Position.X, monitor.Position.Y)
+		}
+	}
+	if enabled == 0 {
+		v.add("monitors", "no monitor is enabled")
+	}
+	if primaries > 1 {
+		v.add("monitors", "%d monitors are marked primary", primaries)
+	}
+}
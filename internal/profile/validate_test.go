@@ -0,0 +1,253 @@
+package profile
+
+import (
+	"fmt"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// validProfile returns two side-by-side monitors in the CCD format, the
+// second with a virtual-mode-aware path and packed mode indices.
+func validProfile() Profile {
+	adapter := LUID{LowPart: 1}
+	target := func(id uint32) ModeInfo {
+		return ModeInfo{InfoType: modeInfoTypeTarget, ID: id, AdapterID: adapter, TargetMode: &TargetMode{TargetVideoSignalInfo: VideoSignalInfo{
+			PixelRate: 148500000, HSyncFreq: Rational{67500, 1}, VSyncFreq: Rational{60, 1},
+			ActiveSize: Region{1920, 1080}, TotalSize: Region{2200, 1125}, VideoStandard: 1, ScanLineOrdering: 1,
+		}}}
+	}
+	source := func(id uint32, x int32) ModeInfo {
+		return ModeInfo{InfoType: modeInfoTypeSource, ID: id, AdapterID: adapter, SourceMode: &SourceMode{
+			Width: 1920, Height: 1080, PixelFormat: 4, Position: PointL{X: x},
+		}}
+	}
+	path := func(sourceID, targetID, sourceIdx, targetIdx, flags uint32) PathInfo {
+		return PathInfo{
+			SourceInfo: PathSourceInfo{AdapterID: adapter, ID: sourceID, ModeInfoIdx: sourceIdx},
+			TargetInfo: PathTargetInfo{
+				AdapterID: adapter, ID: targetID, ModeInfoIdx: targetIdx, OutputTechnology: 10,
+				Rotation: 1, Scaling: 128, RefreshRate: Rational{60, 1}, ScanLineOrdering: 1, TargetAvailable: true,
+			},
+			Flags: flags,
+		}
+	}
+	return Profile{
+		PathInfo: []PathInfo{
+			path(0, 100, 1, 0, pathFlagActive),
+			path(1, 200, 3<<16|packedModeIdxInvalid, 2<<16|4, pathFlagActive|pathFlagSupportVirtualMode),
+		},
+		ModeInfo: []ModeInfo{
+			target(100), source(0, 0), target(200), source(1, 1920),
+			{InfoType: modeInfoTypeDesktopImage, ID: 200, AdapterID: adapter, DesktopImageInfo: &DesktopImageInfo{
+				PathSourceSize:     PointL{1920, 1080},
+				DesktopImageRegion: RectL{Right: 1920, Bottom: 1080},
+				DesktopImageClip:   RectL{Right: 1920, Bottom: 1080},
+			}},
+		},
+		AdditionalInfo: []AdditionalInfo{{Valid: true, MonitorFriendlyDevice: "DELL P2419H"}, {}, {Valid: true}, {}, {}},
+	}
+}
+
+func validLayout() Profile {
+	return Profile{Monitors: []Monitor{
+		{Name: "DELL P2419H", Enabled: true, Primary: true, Width: 1920, Height: 1080, RefreshHz: 60},
+		{Name: "LG HDR 4K", Enabled: true, Width: 3840, Height: 2160, Position: PointL{X: 1920}, Rotation: 90, Scaling: "Stretched"},
+		{Name: "TV"},
+	}}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		edit    func(p *Profile)
+		// want lists the issues as "path: message".
+		want []string
+	}{
+		{name: "valid CCD profile", profile: validProfile()},
+		{name: "valid per-monitor profile", profile: validLayout()},
+		{
+			name:    "empty",
+			profile: Profile{},
+			want:    []string{"$: profile has neither pathInfo nor monitors"},
+		},
+		{
+			name:    "refresh rate flags",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.PathInfo[0].Flags |= 0x10 | 0x20 },
+		},
+		{
+			name:    "unknown flag",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.PathInfo[0].Flags |= 0x40 },
+			want:    []string{"pathInfo[0].flags: unknown flag bits 0x40"},
+		},
+		{
+			name:    "mode index out of range",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.PathInfo[0].SourceInfo.ModeInfoIdx = 9 },
+			// The source at (0,0) is no longer referenced.
+			want: []string{"pathInfo[0].sourceInfo.modeInfoIdx: source mode index 9 is out of range (5 modes)", "modeInfo: no active source is at (0,0); the primary display must be"},
+		},
+		{
+			name:    "mode index of the wrong type",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.PathInfo[0].TargetInfo.ModeInfoIdx = 1 },
+			want:    []string{"pathInfo[0].targetInfo.modeInfoIdx: target mode index 1 points at a mode of infoType 1, want 2"},
+		},
+		{
+			name:    "packed desktop index of the wrong type",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.PathInfo[1].TargetInfo.ModeInfoIdx = 2<<16 | 3 },
+			want:    []string{"pathInfo[1].targetInfo.modeInfoIdx: desktop image mode index 3 points at a mode of infoType 1, want 3"},
+		},
+		{
+			name:    "active path without a source mode",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.PathInfo[0].SourceInfo.ModeInfoIdx = modeIdxInvalid },
+			// The source at (0,0) is no longer referenced.
+			want: []string{"pathInfo[0].sourceInfo.modeInfoIdx: active path has no source mode", "modeInfo: no active source is at (0,0); the primary display must be"},
+		},
+		{
+			name:    "mode for another target",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.PathInfo[0].TargetInfo.ID = 300 },
+			want:    []string{"pathInfo[0].targetInfo.id: is 300 but modeInfo[0] is for id 100"},
+		},
+		{
+			name:    "bad enums",
+			profile: validProfile(),
+			edit: func(p *Profile) {
+				p.PathInfo[0].TargetInfo.Rotation = 0
+				p.PathInfo[0].TargetInfo.Scaling = 6
+				p.PathInfo[0].TargetInfo.OutputTechnology = 7
+			},
+			want: []string{
+				"pathInfo[0].targetInfo.outputTechnology: unknown output technology 7",
+				"pathInfo[0].targetInfo.rotation: must be 1 (identity) to 4 (270 degrees), got 0",
+				"pathInfo[0].targetInfo.scaling: unknown scaling 6",
+			},
+		},
+		{
+			name:    "last value of each enum",
+			profile: validProfile(),
+			edit: func(p *Profile) {
+				p.PathInfo[0].TargetInfo.OutputTechnology = uint32(ccd.DisplayConfigVideoOutputTechnologyDisplayPortUsbTunnel)
+				p.PathInfo[0].TargetInfo.Rotation = uint32(ccd.DisplayConfigRotationRotate270)
+				p.PathInfo[0].TargetInfo.Scaling = uint32(ccd.DisplayConfigScalingPreferred)
+				p.PathInfo[0].TargetInfo.ScanLineOrdering = uint32(ccd.DisplayConfigScanLineOrderingInterlacedLowerFieldFirst)
+				for _, mode := range p.ModeInfo {
+					if mode.SourceMode != nil {
+						mode.SourceMode.PixelFormat = uint32(ccd.DisplayConfigPixelFormatNongdi)
+					}
+				}
+			},
+		},
+		{
+			name:    "inactive path without rotation",
+			profile: validProfile(),
+			edit: func(p *Profile) {
+				p.PathInfo = append(p.PathInfo, PathInfo{SourceInfo: PathSourceInfo{ModeInfoIdx: modeIdxInvalid}, TargetInfo: PathTargetInfo{ModeInfoIdx: modeIdxInvalid}})
+			},
+		},
+		{
+			name:    "mode union mismatch",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.ModeInfo[1].TargetMode = p.ModeInfo[0].TargetMode },
+			want:    []string{"modeInfo[1].targetMode: ignored for infoType 1"},
+		},
+		{
+			name:    "empty source and zero denominator",
+			profile: validProfile(),
+			edit: func(p *Profile) {
+				p.ModeInfo[3].SourceMode.Width = 0
+				p.ModeInfo[2].TargetMode.TargetVideoSignalInfo.VSyncFreq = Rational{60, 0}
+			},
+			want: []string{
+				"modeInfo[2].targetMode.targetVideoSignalInfo.vSyncFreq.denominator: zero denominator",
+				"modeInfo[3].sourceMode: size 0x1080 is empty",
+			},
+		},
+		{
+			name:    "additional info misaligned",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.AdditionalInfo = p.AdditionalInfo[1:] },
+			want: []string{
+				"additionalInfo: has 4 entries but modeInfo has 5; they must line up",
+				"additionalInfo[1]: describes a monitor but modeInfo[1] is not a target mode",
+			},
+		},
+		{
+			name:    "overlapping sources",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.ModeInfo[3].SourceMode.Position.X = 1000 },
+			want:    []string{"modeInfo[3].sourceMode.position: overlaps modeInfo[1]"},
+		},
+		{
+			name:    "no source at the origin",
+			profile: validProfile(),
+			edit:    func(p *Profile) { p.ModeInfo[1].SourceMode.Position.X = -1920 },
+			want:    []string{"modeInfo: no active source is at (0,0); the primary display must be"},
+		},
+		{
+			name:    "monitor without size",
+			profile: validLayout(),
+			edit:    func(p *Profile) { p.Monitors[1].Height = 0 },
+			want:    []string{"monitors[1]: width and height are required for an enabled monitor"},
+		},
+		{
+			name:    "disabled monitor without size",
+			profile: validLayout(),
+			edit:    func(p *Profile) { p.Monitors[2].Width = 1920 },
+		},
+		{
+			name:    "monitor errors",
+			profile: validLayout(),
+			edit: func(p *Profile) {
+				p.Monitors[1].Primary = true
+				p.Monitors[1].Rotation = 45
+				p.Monitors[1].Scaling = "zoom"
+				p.Monitors[1].RefreshHz = -1
+				p.Monitors[2].Name = ""
+			},
+			want: []string{
+				"monitors[1].refreshHz: must not be negative",
+				"monitors[1].rotation: must be 0, 90, 180 or 270, got 45",
+				`monitors[1].scaling: unknown scaling "zoom"`,
+				"monitors[2]: needs name, devicePath or manufactureId/productCodeId to identify the monitor",
+				"monitors: 2 monitors are marked primary",
+			},
+		},
+		{
+			name:    "no monitor enabled",
+			profile: validLayout(),
+			edit:    func(p *Profile) { p.Monitors[0].Enabled, p.Monitors[1].Enabled = false, false },
+			want:    []string{"monitors: no monitor is enabled"},
+		},
+		{
+			name:    "edited canonical profile",
+			profile: validLayout(),
+			edit: func(p *Profile) {
+				p.LayoutHash = LayoutHash(*p)
+				p.Monitors[0].Width = 1280
+			},
+			want: []string{"layoutHash: does not match the layout; the file was edited after a canonical save"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := tt.profile
+			if tt.edit != nil {
+				tt.edit(&profile)
+			}
+			var got []string
+			for _, issue := range Validate(profile) {
+				got = append(got, issue.String())
+			}
+			if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.want) {
+				t.Errorf("Validate() =\n  %q\nwant\n  %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The original C# MonitorSwitcher stores profiles as XML written with
+// XmlSerializer:
+//
+//	<displaySettings>
+//	  <pathInfoArray><DisplayConfigPathInfo>...</DisplayConfigPathInfo></pathInfoArray>
+//	  <modeInfoArray>
+//	    <modeInfo>
+//	      <id>..</id><LUID>..</LUID><DisplayConfigModeInfoType>Target</DisplayConfigModeInfoType>
+//	      <DisplayConfigTargetMode>..</DisplayConfigTargetMode>
+//	    </modeInfo>
+//	  </modeInfoArray>
+//	  <additionalInfo><MonitorAdditionalInfo>..</MonitorAdditionalInfo></additionalInfo>
+//	</displaySettings>
+//
+// The mode union is written as whichever of DisplayConfigTargetMode,
+// DisplayConfigSourceMode or DisplayConfigDesktopImageInfo the info type
+// selects. Enums are written by name ("InUse", "DisplayportExternal",
+// "Pixelformat32Bpp"); flag enums as space-separated names. Element names
+// are matched case-insensitively and numbers are accepted for every enum, so
+// files from older and newer releases of that tool also load.
+
+var (
+	xmlModeInfoTypes      = map[string]uint32{"zero": 0, "source": 1, "target": 2, "desktopimage": 3}
+	xmlOutputTechnologies = map[string]uint32{
+		"other": 0xFFFFFFFF, "hd15": 0, "svideo": 1, "compositevideo": 2, "componentvideo": 3,
+		"dvi": 4, "hdmi": 5, "lvds": 6, "djpn": 8, "sdi": 9, "displayportexternal": 10,
+		"displayportembedded": 11, "udiexternal": 12, "udiembedded": 13, "sdtvdongle": 14,
+		"miracast": 15, "indirectwired": 16, "indirectvirtual": 17, "displayportusbtunnel": 18,
+		"internal": 0x80000000,
+	}
+	xmlRotations = map[string]uint32{"zero": 0, "identity": 1, "rotate90": 2, "rotate180": 3, "rotate270": 4}
+	xmlScalings  = map[string]uint32{
+		"zero": 0, "identity": 1, "centered": 2, "stretched": 3, "aspectratiocenteredmax": 4,
+		"custom": 5, "preferred": 128,
+	}
+	xmlScanLineOrderings = map[string]uint32{
+		"unspecified": 0, "progressive": 1, "interlaced": 2, "interlacedupperfieldfirst": 2,
+		"interlacedlowerfieldfirst": 3,
+	}
+	xmlPixelFormats = map[string]uint32{
+		"zero": 0, "pixelformat8bpp": 1, "pixelformat16bpp": 2, "pixelformat24bpp": 3,
+		"pixelformat32bpp": 4, "pixelformatnongdi": 5,
+	}
+	xmlSourceStatus = map[string]uint32{"zero": 0, "inuse": 1}
+	xmlTargetStatus = map[string]uint32{
+		"zero": 0, "inuse": 1, "forcible": 2, "forcedavailabilityboot": 4,
+		"forcedavailabilitypath": 8, "forcedavailabilitysystem": 16, "ishmd": 32,
+	}
+	xmlPathFlags = map[string]uint32{"zero": 0, "pathactive": 1, "supportvirtualmode": 8}
+	// xmlVideoStandards are the D3DKMDT_VIDEO_SIGNAL_STANDARD names.
+	xmlVideoStandards = map[string]uint32{
+		"uninitialized": 0, "vesadmt": 1, "vesagtf": 2, "vesacvt": 3, "ibm": 4, "apple": 5,
+		"ntscm": 6, "ntscj": 7, "ntsc443": 8, "palb": 9, "palb1": 10, "palg": 11, "palh": 12,
+		"pali": 13, "pald": 14, "paln": 15, "palnc": 16, "secamb": 17, "secamd": 18,
+		"secamg": 19, "secamh": 20, "secamk": 21, "secamk1": 22, "secaml": 23, "secaml1": 24,
+		"eia861": 25, "eia861a": 26, "eia861b": 27, "palk": 28, "palk1": 29, "pall": 30,
+		"palm": 31, "other": 255,
+	}
+)
+
+// isXML reports whether data looks like an XML document.
+func isXML(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("<"))
+}
+
+type xmlNode struct {
+	XMLName  xml.Name
+	Content  string    `xml:",chardata"`
+	Children []xmlNode `xml:",any"`
+}
+
+// child returns the first child element with any of the given names.
+func (n *xmlNode) child(names ...string) *xmlNode {
+	for i := range n.Children {
+		for _, name := range names {
+			if strings.EqualFold(n.Children[i].XMLName.Local, name) {
+				return &n.Children[i]
+			}
+		}
+	}
+	return nil
+}
+
+// xmlDecoder converts nodes to values, remembering the first error with the
+// element path it happened at.
+type xmlDecoder struct {
+	err error
+}
+
+func (d *xmlDecoder) fail(node *xmlNode, format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("<%s>: %s", node.XMLName.Local, fmt.Sprintf(format, args...))
+	}
+}
+
+// enum reads a child holding a number or names from names; several names
+// separated by spaces are ORed together, as XmlSerializer writes flags.
+func (d *xmlDecoder) enum(parent *xmlNode, names map[string]uint32, element ...string) uint32 {
+	node := parent.child(element...)
+	if node == nil {
+		return 0
+	}
+	text := strings.TrimSpace(node.Content)
+	if text == "" {
+		return 0
+	}
+	if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return uint32(value)
+	}
+	var value uint32
+	for _, name := range strings.Fields(text) {
+		v, ok := names[strings.ToLower(name)]
+		if !ok {
+			d.fail(node, "unknown value %q", name)
+			return 0
+		}
+		value |= v
+	}
+	return value
+}
+
+func (d *xmlDecoder) int64(parent *xmlNode, element ...string) int64 {
+	node := parent.child(element...)
+	if node == nil {
+		return 0
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(node.Content), 10, 64)
+	if err != nil {
+		// Unsigned fields such as pixelRate can exceed int64 only in
+		// theory; a uint64 parse still gives the right bits.
+		unsigned, uerr := strconv.ParseUint(strings.TrimSpace(node.Content), 10, 64)
+		if uerr != nil {
+			d.fail(node, "not a number: %q", node.Content)
+			return 0
+		}
+		return int64(unsigned)
+	}
+	return value
+}
+
+func (d *xmlDecoder) uint32(parent *xmlNode, element ...string) uint32 {
+	return uint32(d.int64(parent, element...))
+}
+
+func (d *xmlDecoder) bool(parent *xmlNode, element ...string) bool {
+	node := parent.child(element...)
+	if node == nil {
+		return false
+	}
+	value, err := strconv.ParseBool(strings.TrimSpace(node.Content))
+	if err != nil {
+		d.fail(node, "not a boolean: %q", node.Content)
+	}
+	return value
+}
+
+func (d *xmlDecoder) text(parent *xmlNode, element ...string) string {
+	if node := parent.child(element...); node != nil {
+		return node.Content
+	}
+	return ""
+}
+
+func (d *xmlDecoder) luid(parent *xmlNode, element ...string) LUID {
+	node := parent.child(element...)
+	if node == nil {
+		return LUID{}
+	}
+	return LUID{LowPart: d.uint32(node, "LowPart"), HighPart: d.uint32(node, "HighPart")}
+}
+
+func (d *xmlDecoder) rational(parent *xmlNode, element string) Rational {
+	node := parent.child(element)
+	if node == nil {
+		return Rational{}
+	}
+	return Rational{Numerator: d.uint32(node, "numerator"), Denominator: d.uint32(node, "denominator")}
+}
+
+func (d *xmlDecoder) region(parent *xmlNode, element string) Region {
+	node := parent.child(element)
+	if node == nil {
+		return Region{}
+	}
+	return Region{Cx: d.uint32(node, "cx"), Cy: d.uint32(node, "cy")}
+}
+
+func (d *xmlDecoder) point(parent *xmlNode, element string) PointL {
+	node := parent.child(element)
+	if node == nil {
+		return PointL{}
+	}
+	return PointL{X: int32(d.int64(node, "x")), Y: int32(d.int64(node, "y"))}
+}
+
+func (d *xmlDecoder) rect(parent *xmlNode, element string) RectL {
+	node := parent.child(element)
+	if node == nil {
+		return RectL{}
+	}
+	return RectL{
+		Left:   int32(d.int64(node, "left")),
+		Top:    int32(d.int64(node, "top")),
+		Right:  int32(d.int64(node, "right")),
+		Bottom: int32(d.int64(node, "bottom")),
+	}
+}
+
+func (d *xmlDecoder) path(node *xmlNode) PathInfo {
+	var path PathInfo
+	if source := node.child("sourceInfo"); source != nil {
+		path.SourceInfo = PathSourceInfo{
+			AdapterID:   d.luid(source, "adapterId"),
+			ID:          d.uint32(source, "id"),
+			ModeInfoIdx: d.uint32(source, "modeInfoIdx"),
+			StatusFlags: d.enum(source, xmlSourceStatus, "statusFlags"),
+		}
+	}
+	if target := node.child("targetInfo"); target != nil {
+		path.TargetInfo = PathTargetInfo{
+			AdapterID:        d.luid(target, "adapterId"),
+			ID:               d.uint32(target, "id"),
+			ModeInfoIdx:      d.uint32(target, "modeInfoIdx"),
+			OutputTechnology: d.enum(target, xmlOutputTechnologies, "outputTechnology"),
+			Rotation:         d.enum(target, xmlRotations, "rotation"),
+			Scaling:          d.enum(target, xmlScalings, "scaling"),
+			RefreshRate:      d.rational(target, "refreshRate"),
+			ScanLineOrdering: d.enum(target, xmlScanLineOrderings, "scanLineOrdering"),
+			TargetAvailable:  d.bool(target, "targetAvailable"),
+			StatusFlags:      d.enum(target, xmlTargetStatus, "statusFlags"),
+		}
+	}
+	path.Flags = d.enum(node, xmlPathFlags, "flags")
+	return path
+}
+
+func (d *xmlDecoder) mode(node *xmlNode) ModeInfo {
+	mode := ModeInfo{
+		InfoType:  d.enum(node, xmlModeInfoTypes, "DisplayConfigModeInfoType", "infoType"),
+		ID:        d.uint32(node, "id"),
+		AdapterID: d.luid(node, "LUID", "adapterId"),
+	}
+	// Some releases nest the union in a modeInfo element.
+	union := node
+	if nested := node.child("modeInfo"); nested != nil {
+		union = nested
+	}
+	switch mode.InfoType {
+	case 1:
+		source := union.child("DisplayConfigSourceMode", "sourceMode")
+		if source == nil {
+			d.fail(node, "source mode without DisplayConfigSourceMode")
+			break
+		}
+		mode.SourceMode = &SourceMode{
+			Width:       d.uint32(source, "width"),
+			Height:      d.uint32(source, "height"),
+			PixelFormat: d.enum(source, xmlPixelFormats, "pixelFormat"),
+			Position:    d.point(source, "position"),
+		}
+	case 2:
+		target := union.child("DisplayConfigTargetMode", "targetMode")
+		if target == nil {
+			d.fail(node, "target mode without DisplayConfigTargetMode")
+			break
+		}
+		signal := target.child("targetVideoSignalInfo")
+		if signal == nil {
+			d.fail(target, "missing targetVideoSignalInfo")
+			break
+		}
+		mode.TargetMode = &TargetMode{TargetVideoSignalInfo: VideoSignalInfo{
+			PixelRate:        d.int64(signal, "pixelRate"),
+			HSyncFreq:        d.rational(signal, "hSyncFreq"),
+			VSyncFreq:        d.rational(signal, "vSyncFreq"),
+			ActiveSize:       d.region(signal, "activeSize"),
+			TotalSize:        d.region(signal, "totalSize"),
+			VideoStandard:    d.enum(signal, xmlVideoStandards, "videoStandard"),
+			ScanLineOrdering: d.enum(signal, xmlScanLineOrderings, "scanLineOrdering"),
+		}}
+	case 3:
+		desktop := union.child("DisplayConfigDesktopImageInfo", "desktopImageInfo")
+		if desktop == nil {
+			d.fail(node, "desktop image mode without DisplayConfigDesktopImageInfo")
+			break
+		}
+		mode.DesktopImageInfo = &DesktopImageInfo{
+			PathSourceSize:     d.point(desktop, "pathSourceSize"),
+			DesktopImageRegion: d.rect(desktop, "desktopImageRegion"),
+			DesktopImageClip:   d.rect(desktop, "desktopImageClip"),
+		}
+	}
+	return mode
+}
+
+func (d *xmlDecoder) additional(node *xmlNode) AdditionalInfo {
+	return AdditionalInfo{
+		ManufactureID:         uint16(d.uint32(node, "manufactureId")),
+		ProductCodeID:         uint16(d.uint32(node, "productCodeId")),
+		Valid:                 d.bool(node, "valid"),
+		MonitorDevicePath:     d.text(node, "monitorDevicePath"),
+		MonitorFriendlyDevice: d.text(node, "monitorFriendlyDevice"),
+	}
+}
+
+// decodeXML converts a C# MonitorSwitcher profile.
+func decodeXML(data []byte) (Profile, error) {
+	var profile Profile
+	var root xmlNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return profile, fmt.Errorf("parse MonitorSwitcher XML: %w", err)
+	}
+	if !strings.EqualFold(root.XMLName.Local, "displaySettings") {
+		return profile, fmt.Errorf("parse MonitorSwitcher XML: root element is <%s>, not <displaySettings>", root.XMLName.Local)
+	}
+
+	d := &xmlDecoder{}
+	if paths := root.child("pathInfoArray"); paths != nil {
+		for i := range paths.Children {
+			profile.PathInfo = append(profile.PathInfo, d.path(&paths.Children[i]))
+		}
+	}
+	if modes := root.child("modeInfoArray"); modes != nil {
+		for i := range modes.Children {
+			profile.ModeInfo = append(profile.ModeInfo, d.mode(&modes.Children[i]))
+		}
+	}
+	if additional := root.child("additionalInfo"); additional != nil {
+		for i := range additional.Children {
+			profile.AdditionalInfo = append(profile.AdditionalInfo, d.additional(&additional.Children[i]))
+		}
+	}
+	if d.err != nil {
+		return profile, fmt.Errorf("parse MonitorSwitcher XML: %w", d.err)
+	}
+	if len(profile.PathInfo) == 0 {
+		return profile, errors.New("parse MonitorSwitcher XML: no paths in <pathInfoArray>")
+	}
+
+	// The C# tool writes additional info only for target modes, in mode
+	// order; spread it out so it lines up with modeInfo like ours.
+	if len(profile.AdditionalInfo) != len(profile.ModeInfo) {
+		aligned := make([]AdditionalInfo, len(profile.ModeInfo))
+		next := 0
+		for i, mode := range profile.ModeInfo {
+			if mode.InfoType == 2 && next < len(profile.AdditionalInfo) {
+				aligned[i] = profile.AdditionalInfo[next]
+				next++
+			}
+		}
+		profile.AdditionalInfo = aligned
+	}
+	profile.SchemaVersion = SchemaVersion
+	return profile, nil
+}
@@ -0,0 +1,275 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// monitorSwitcherXML is a two-monitor profile as the C# MonitorSwitcher
+// writes it, with enums by name and additional info for target modes only.
+const monitorSwitcherXML = `<?xml version="1.0"?>
+<displaySettings xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <pathInfoArray>
+    <DisplayConfigPathInfo>
+      <sourceInfo>
+        <adapterId><LowPart>52731</LowPart><HighPart>0</HighPart></adapterId>
+        <id>0</id>
+        <modeInfoIdx>1</modeInfoIdx>
+        <statusFlags>InUse</statusFlags>
+      </sourceInfo>
+      <targetInfo>
+        <adapterId><LowPart>52731</LowPart><HighPart>0</HighPart></adapterId>
+        <id>4352</id>
+        <modeInfoIdx>0</modeInfoIdx>
+        <outputTechnology>DisplayportExternal</outputTechnology>
+        <rotation>Identity</rotation>
+        <scaling>Preferred</scaling>
+        <refreshRate><numerator>60</numerator><denominator>1</denominator></refreshRate>
+        <scanLineOrdering>Progressive</scanLineOrdering>
+        <targetAvailable>true</targetAvailable>
+        <statusFlags>InUse Forcible</statusFlags>
+      </targetInfo>
+      <flags>PathActive</flags>
+    </DisplayConfigPathInfo>
+    <DisplayConfigPathInfo>
+      <sourceInfo>
+        <adapterId><LowPart>52731</LowPart><HighPart>0</HighPart></adapterId>
+        <id>1</id>
+        <modeInfoIdx>3</modeInfoIdx>
+        <statusFlags>InUse</statusFlags>
+      </sourceInfo>
+      <targetInfo>
+        <adapterId><LowPart>52731</LowPart><HighPart>0</HighPart></adapterId>
+        <id>4353</id>
+        <modeInfoIdx>2</modeInfoIdx>
+        <outputTechnology>HDMI</outputTechnology>
+        <rotation>Rotate90</rotation>
+        <scaling>Identity</scaling>
+        <refreshRate><numerator>59940</numerator><denominator>1000</denominator></refreshRate>
+        <scanLineOrdering>Progressive</scanLineOrdering>
+        <targetAvailable>true</targetAvailable>
+        <statusFlags>InUse</statusFlags>
+      </targetInfo>
+      <flags>PathActive</flags>
+    </DisplayConfigPathInfo>
+  </pathInfoArray>
+  <modeInfoArray>
+    <modeInfo>
+      <id>4352</id>
+      <LUID><LowPart>52731</LowPart><HighPart>0</HighPart></LUID>
+      <DisplayConfigModeInfoType>Target</DisplayConfigModeInfoType>
+      <DisplayConfigTargetMode>
+        <targetVideoSignalInfo>
+          <pixelRate>148500000</pixelRate>
+          <hSyncFreq><numerator>67500</numerator><denominator>1</denominator></hSyncFreq>
+          <vSyncFreq><numerator>60</numerator><denominator>1</denominator></vSyncFreq>
+          <activeSize><cx>1920</cx><cy>1080</cy></activeSize>
+          <totalSize><cx>2200</cx><cy>1125</cy></totalSize>
+          <videoStandard>VesaDmt</videoStandard>
+          <scanLineOrdering>Progressive</scanLineOrdering>
+        </targetVideoSignalInfo>
+      </DisplayConfigTargetMode>
+    </modeInfo>
+    <modeInfo>
+      <id>0</id>
+      <LUID><LowPart>52731</LowPart><HighPart>0</HighPart></LUID>
+      <DisplayConfigModeInfoType>Source</DisplayConfigModeInfoType>
+      <DisplayConfigSourceMode>
+        <width>1920</width>
+        <height>1080</height>
+        <pixelFormat>Pixelformat32Bpp</pixelFormat>
+        <position><x>0</x><y>0</y></position>
+      </DisplayConfigSourceMode>
+    </modeInfo>
+    <modeInfo>
+      <id>4353</id>
+      <LUID><LowPart>52731</LowPart><HighPart>0</HighPart></LUID>
+      <DisplayConfigModeInfoType>Target</DisplayConfigModeInfoType>
+      <DisplayConfigTargetMode>
+        <targetVideoSignalInfo>
+          <pixelRate>148352000</pixelRate>
+          <hSyncFreq><numerator>67433</numerator><denominator>1</denominator></hSyncFreq>
+          <vSyncFreq><numerator>59940</numerator><denominator>1000</denominator></vSyncFreq>
+          <activeSize><cx>1920</cx><cy>1080</cy></activeSize>
+          <totalSize><cx>2200</cx><cy>1125</cy></totalSize>
+          <videoStandard>255</videoStandard>
+          <scanLineOrdering>1</scanLineOrdering>
+        </targetVideoSignalInfo>
+      </DisplayConfigTargetMode>
+    </modeInfo>
+    <modeInfo>
+      <id>1</id>
+      <LUID><LowPart>52731</LowPart><HighPart>0</HighPart></LUID>
+      <DisplayConfigModeInfoType>Source</DisplayConfigModeInfoType>
+      <DisplayConfigSourceMode>
+        <width>1080</width>
+        <height>1920</height>
+        <pixelFormat>Pixelformat32Bpp</pixelFormat>
+        <position><x>1920</x><y>-420</y></position>
+      </DisplayConfigSourceMode>
+    </modeInfo>
+  </modeInfoArray>
+  <additionalInfo>
+    <MonitorAdditionalInfo>
+      <manufactureId>4268</manufactureId>
+      <productCodeId>16675</productCodeId>
+      <valid>true</valid>
+      <monitorDevicePath>\\?\DISPLAY#DEL4123#5&amp;1a&amp;0&amp;UID4352#{e6f07b5f-ee97-4a90-b076-33f57bf4eaa7}</monitorDevicePath>
+      <monitorFriendlyDevice>DELL P2419H</monitorFriendlyDevice>
+    </MonitorAdditionalInfo>
+    <MonitorAdditionalInfo>
+      <manufactureId>7789</manufactureId>
+      <productCodeId>30471</productCodeId>
+      <valid>true</valid>
+      <monitorDevicePath>\\?\DISPLAY#GSM7707#5&amp;1a&amp;0&amp;UID4353#{e6f07b5f-ee97-4a90-b076-33f57bf4eaa7}</monitorDevicePath>
+      <monitorFriendlyDevice>LG HDR 4K</monitorFriendlyDevice>
+    </MonitorAdditionalInfo>
+  </additionalInfo>
+</displaySettings>
+`
+
+func TestDecodeXML(t *testing.T) {
+	profile, err := decodeXML([]byte(monitorSwitcherXML))
+	if err != nil {
+		t.Fatalf("decodeXML() = %v", err)
+	}
+
+	adapter := LUID{LowPart: 52731}
+	wantPaths := []PathInfo{
+		{
+			SourceInfo: PathSourceInfo{AdapterID: adapter, ID: 0, ModeInfoIdx: 1, StatusFlags: 1},
+			TargetInfo: PathTargetInfo{
+				AdapterID: adapter, ID: 4352, ModeInfoIdx: 0, OutputTechnology: 10, Rotation: 1, Scaling: 128,
+				RefreshRate: Rational{60, 1}, ScanLineOrdering: 1, TargetAvailable: true, StatusFlags: 3,
+			},
+			Flags: 1,
+		},
+		{
+			SourceInfo: PathSourceInfo{AdapterID: adapter, ID: 1, ModeInfoIdx: 3, StatusFlags: 1},
+			TargetInfo: PathTargetInfo{
+				AdapterID: adapter, ID: 4353, ModeInfoIdx: 2, OutputTechnology: 5, Rotation: 2, Scaling: 1,
+				RefreshRate: Rational{59940, 1000}, ScanLineOrdering: 1, TargetAvailable: true, StatusFlags: 1,
+			},
+			Flags: 1,
+		},
+	}
+	if !reflect.DeepEqual(profile.PathInfo, wantPaths) {
+		t.Errorf("paths =\n%+v\nwant\n%+v", profile.PathInfo, wantPaths)
+	}
+
+	if len(profile.ModeInfo) != 4 {
+		t.Fatalf("%d modes, want 4", len(profile.ModeInfo))
+	}
+	wantSignal := VideoSignalInfo{
+		PixelRate: 148500000, HSyncFreq: Rational{67500, 1}, VSyncFreq: Rational{60, 1},
+		ActiveSize: Region{1920, 1080}, TotalSize: Region{2200, 1125}, VideoStandard: 1, ScanLineOrdering: 1,
+	}
+	if mode := profile.ModeInfo[0]; mode.InfoType != 2 || mode.ID != 4352 || mode.AdapterID != adapter ||
+		mode.TargetMode == nil || mode.TargetMode.TargetVideoSignalInfo != wantSignal {
+		t.Errorf("mode 0 = %+v, want target mode %+v", mode, wantSignal)
+	}
+	if mode := profile.ModeInfo[2]; mode.TargetMode == nil || mode.TargetMode.TargetVideoSignalInfo.VideoStandard != 255 {
+		t.Errorf("mode 2 = %+v, want a target mode with numeric enums read", mode)
+	}
+	wantSource := SourceMode{Width: 1080, Height: 1920, PixelFormat: 4, Position: PointL{1920, -420}}
+	if mode := profile.ModeInfo[3]; mode.InfoType != 1 || mode.SourceMode == nil || *mode.SourceMode != wantSource ||
+		mode.TargetMode != nil || mode.DesktopImageInfo != nil {
+		t.Errorf("mode 3 = %+v, want only source mode %+v", mode, wantSource)
+	}
+
+	// Additional info is written for target modes only and spread out to
+	// line up with modeInfo.
+	if len(profile.AdditionalInfo) != len(profile.ModeInfo) {
+		t.Fatalf("%d additional entries for %d modes", len(profile.AdditionalInfo), len(profile.ModeInfo))
+	}
+	for i, want := range []string{"DELL P2419H", "", "LG HDR 4K", ""} {
+		if got := profile.AdditionalInfo[i].MonitorFriendlyDevice; got != want {
+			t.Errorf("additionalInfo[%d] is for %q, want %q", i, got, want)
+		}
+	}
+	if path := profile.AdditionalInfo[0].MonitorDevicePath; path != `\\?\DISPLAY#DEL4123#5&1a&0&UID4352#{e6f07b5f-ee97-4a90-b076-33f57bf4eaa7}` {
+		t.Errorf("device path = %q", path)
+	}
+	if profile.SchemaVersion != SchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", profile.SchemaVersion, SchemaVersion)
+	}
+	if issues := Validate(profile); len(issues) > 0 {
+		t.Errorf("imported profile does not validate: %v", issues)
+	}
+}
+
+func TestDecodeXMLVariants(t *testing.T) {
+	// nested wraps the union in a modeInfo element and uses other casing,
+	// as some releases of the C# tool do.
+	nested := `<DisplaySettings><PathInfoArray><DisplayConfigPathInfo>
+		<sourceInfo><id>0</id><modeInfoIdx>0</modeInfoIdx></sourceInfo>
+		<targetInfo><id>7</id><modeInfoIdx>4294967295</modeInfoIdx><rotation>1</rotation></targetInfo>
+		<flags>1</flags>
+	</DisplayConfigPathInfo></PathInfoArray>
+	<ModeInfoArray><modeInfo><id>0</id><infoType>1</infoType><modeInfo>
+		<sourceMode><width>800</width><height>600</height></sourceMode>
+	</modeInfo></modeInfo></ModeInfoArray></DisplaySettings>`
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "nested union", data: nested},
+		{name: "other root", data: `<settings/>`, wantErr: "root element is <settings>"},
+		{name: "no paths", data: `<displaySettings><pathInfoArray/></displaySettings>`, wantErr: "no paths"},
+		{name: "unknown enum name", data: strings.Replace(monitorSwitcherXML, "Rotate90", "Sideways", 1), wantErr: `<rotation>: unknown value "Sideways"`},
+		{name: "bad number", data: strings.Replace(monitorSwitcherXML, "<id>4353</id>", "<id>x</id>", 1), wantErr: `<id>: not a number: "x"`},
+		{name: "bad boolean", data: strings.Replace(monitorSwitcherXML, "<targetAvailable>true", "<targetAvailable>yes", 1), wantErr: "not a boolean"},
+		{name: "target mode without union", data: strings.Replace(monitorSwitcherXML, "DisplayConfigTargetMode>", "TargetModeX>", 2), wantErr: "target mode without DisplayConfigTargetMode"},
+		{name: "malformed", data: `<displaySettings>`, wantErr: "parse MonitorSwitcher XML"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := decodeXML([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeXML() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeXML() = %v", err)
+			}
+			if len(profile.ModeInfo) != 1 || profile.ModeInfo[0].SourceMode == nil || profile.ModeInfo[0].SourceMode.Width != 800 {
+				t.Errorf("modes = %+v, want the nested 800x600 source mode", profile.ModeInfo)
+			}
+		})
+	}
+}
+
+func TestLoadAndConvertXML(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "Home.xml")
+	// A UTF-8 byte order mark, as .NET writes it.
+	if err := os.WriteFile(in, []byte("\xef\xbb\xbf"+monitorSwitcherXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(in)
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+
+	out := filepath.Join(dir, "Home.monitorprofile")
+	if err := Convert(in, out); err != nil {
+		t.Fatalf("Convert() = %v", err)
+	}
+	converted, err := Load(out)
+	if err != nil {
+		t.Fatalf("Load() of the converted profile = %v", err)
+	}
+	if !reflect.DeepEqual(converted, loaded) {
+		t.Errorf("converted profile =\n%+v\nwant\n%+v", converted, loaded)
+	}
+	if _, _, err := Migrate(in); err == nil || !strings.Contains(err.Error(), "use -convert") {
+		t.Errorf("Migrate() of an XML profile = %v, want a pointer to -convert", err)
+	}
+}
@@ -0,0 +1,253 @@
+package switcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/profile"
+)
+
+var (
+	// ErrNoProfileMatch is returned by AutoLoad when no profile's monitors
+	// are all connected.
+	ErrNoProfileMatch = errors.New("no profile matches the connected monitors")
+	// ErrAmbiguousProfile is returned by AutoLoad when the best profiles
+	// score the same.
+	ErrAmbiguousProfile = errors.New("several profiles match equally well")
+)
+
+// Points for AutoLoad's ranking. Every monitor in a profile must be
+// connected for it to be considered at all.
+const (
+	autoScoreExact       = 100 // same device path
+	autoScoreModel       = 40  // same EDID or hardware ID
+	autoScoreWeak        = 10  // matched by name or target ID only
+	autoScoreFingerprint = 50  // saved with exactly the monitors now connected
+	autoScoreSameCount   = 20  // uses as many monitors as are connected
+	autoPenaltyExtra     = 5   // per connected monitor the profile leaves out
+)
+
+// profileExtensions are the files ListProfiles returns.
+var profileExtensions = map[string]bool{
+	".monitorprofile": true, ".json": true, ".jsonc": true,
+	".yaml": true, ".yml": true, ".toml": true, ".xml": true,
+}
+
+// ListProfiles returns the paths of the profiles in dir, by name.
+func ListProfiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read profile dir: %w", err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() || !profileExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	return paths, nil
+}
+
+// ProfileScore is how well one profile fits the connected monitors.
+type ProfileScore struct {
+	Path string
+	// Monitors is the number of monitors the profile uses; Exact, Model and
+	// Weak count how each was matched and Missing those not connected.
+	Monitors, Exact, Model, Weak, Missing int
+	Fingerprint                           bool
+	SameCount                             bool
+	Score                                 int
+	// Err is set when the profile could not be read.
+	Err error
+}
+
+// Matches reports whether every monitor of the profile is connected.
+func (p ProfileScore) Matches() bool {
+	return p.Err == nil && p.Monitors > 0 && p.Missing == 0
+}
+
+// MarshalJSON writes the score for -format:json.
+func (p ProfileScore) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Path        string `json:"path"`
+		Matches     bool   `json:"matches"`
+		Score       int    `json:"score"`
+		Monitors    int    `json:"monitors"`
+		Exact       int    `json:"exact"`
+		Model       int    `json:"model"`
+		Weak        int    `json:"weak"`
+		Missing     int    `json:"missing"`
+		Fingerprint bool   `json:"fingerprint"`
+		SameCount   bool   `json:"sameCount"`
+		Error       string `json:"error,omitempty"`
+	}{p.Path, p.Matches(), p.Score, p.Monitors, p.Exact, p.Model, p.Weak, p.Missing, p.Fingerprint, p.SameCount, ""}
+	if p.Err != nil {
+		out.Error = p.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+func (p ProfileScore) String() string {
+	name := filepath.Base(p.Path)
+	switch {
+	case p.Err != nil:
+		return fmt.Sprintf("%s: skipped: %v", name, p.Err)
+	case p.Monitors == 0:
+		return fmt.Sprintf("%s: not a match (no monitors)", name)
+	case p.Missing > 0:
+		return fmt.Sprintf("%s: not a match (%d of %d monitors not connected)", name, p.Missing, p.Monitors)
+	}
+	reasons := []string{fmt.Sprintf("%d exact", p.Exact)}
+	if p.Model > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d same model", p.Model))
+	}
+	if p.Weak > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d by name or target ID", p.Weak))
+	}
+	if p.Fingerprint {
+		reasons = append(reasons, "fingerprint match")
+	}
+	if p.SameCount {
+		reasons = append(reasons, "same monitor count")
+	}
+	return fmt.Sprintf("%s: score %d (%s)", name, p.Score, strings.Join(reasons, ", "))
+}
+
+// ScoreProfiles rates every profile in dir against the connected monitors,
+// best first.
+func (s *Switcher) ScoreProfiles(dir string) ([]ProfileScore, error) {
+	profiles, err := ListProfiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	currentPaths, _, _, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsAllPaths)
+	if err != nil {
+		return nil, fmt.Errorf("get display settings: %w", err)
+	}
+	targets := currentTargets(s.backend, currentPaths)
+	fingerprint := fingerprintOf(targets).ID
+
+	var scores []ProfileScore
+	for _, path := range profiles {
+		score := ProfileScore{Path: path}
+		prof, err := profile.Load(path)
+		if err != nil {
+			score.Err = err
+		} else {
+			score.rate(savedIdentities(prof), targets)
+			score.Fingerprint = prof.Fingerprint != "" && prof.Fingerprint == fingerprint
+			if score.Matches() && score.Fingerprint {
+				score.Score += autoScoreFingerprint
+			}
+		}
+		scores = append(scores, score)
+	}
+	sort.SliceStable(scores, func(a, b int) bool {
+		if scores[a].Matches() != scores[b].Matches() {
+			return scores[a].Matches()
+		}
+		return scores[a].Score > scores[b].Score
+	})
+	return scores, nil
+}
+
+// rate fills in the match counts and score for a profile's monitors.
+func (p *ProfileScore) rate(saved []monitorIdentity, targets []currentTarget) {
+	p.Monitors = len(saved)
+	match := matchIdentities(saved, targets)
+	p.Missing = len(match.unmatched)
+	for i, target := range match.targets {
+		info, current := saved[i].info, target.identity.info
+		switch {
+		case info.MonitorDevicePath != "" && strings.EqualFold(info.MonitorDevicePath, current.MonitorDevicePath):
+			p.Exact++
+		case info.ManufactureID != 0 && info.ManufactureID == current.ManufactureID && info.ProductCodeID == current.ProductCodeID:
+			p.Model++
+		default:
+			savedHW, _ := devicePathParts(info.MonitorDevicePath)
+			currentHW, _ := devicePathParts(current.MonitorDevicePath)
+			if savedHW != "" && strings.EqualFold(savedHW, currentHW) {
+				p.Model++
+			} else {
+				p.Weak++
+			}
+		}
+	}
+	if !p.Matches() {
+		return
+	}
+	p.Score = p.Exact*autoScoreExact + p.Model*autoScoreModel + p.Weak*autoScoreWeak
+	if extra := len(targets) - p.Monitors; extra > 0 {
+		p.Score -= extra * autoPenaltyExtra
+	} else {
+		p.SameCount = true
+		p.Score += autoScoreSameCount
+	}
+}
+
+// savedIdentities lists the monitors a profile uses: its active paths, or
+// its enabled monitors in the per-monitor format.
+func savedIdentities(prof profile.Profile) []monitorIdentity {
+	var identities []monitorIdentity
+	if prof.IsLayout() {
+		for _, monitor := range prof.Monitors {
+			if monitor.Enabled {
+				identities = append(identities, monitorIdentityOf(monitor))
+			}
+		}
+		return identities
+	}
+	paths, modes, additional := ccdFromProfile(prof)
+	for i := range paths {
+		if paths[i].Flags&uint32(ccd.DisplayConfigFlagPathActive) != 0 {
+			identities = append(identities, profileIdentity(&paths[i], modes, additional))
+		}
+	}
+	return identities
+}
+
+// AutoResult is what AutoLoad chose and why.
+type AutoResult struct {
+	// Applied is the path of the profile applied, or "".
+	Applied string         `json:"applied"`
+	Scores  []ProfileScore `json:"scores"`
+	// Load reports how the applied profile was loaded (or planned).
+	Load *LoadReport `json:"load,omitempty"`
+}
+
+// AutoLoad applies the profile in dir that best fits the connected
+// monitors, explaining the ranking on opts.Output.
+func (s *Switcher) AutoLoad(dir string, opts LoadOptions) (AutoResult, error) {
+	scores, err := s.ScoreProfiles(dir)
+	if err != nil {
+		return AutoResult{}, err
+	}
+	result := AutoResult{Scores: scores}
+	if result.Scores == nil {
+		result.Scores = []ProfileScore{}
+	}
+	out := opts.output()
+	fmt.Fprintf(out, "Profiles in %s:\n", dir)
+	for _, score := range scores {
+		fmt.Fprintf(out, "  %s\n", score)
+	}
+	if len(scores) == 0 || !scores[0].Matches() {
+		return result, ErrNoProfileMatch
+	}
+	best := scores[0]
+	if len(scores) > 1 && scores[1].Matches() && scores[1].Score == best.Score {
+		return result, fmt.Errorf("%w: %s and %s (score %d)", ErrAmbiguousProfile, filepath.Base(best.Path), filepath.Base(scores[1].Path), best.Score)
+	}
+	fmt.Fprintf(out, "Applying %s\n", filepath.Base(best.Path))
+	result.Applied = best.Path
+	report, err := s.LoadProfileWithReport(best.Path, opts)
+	result.Load = &report
+	return result, err
+}
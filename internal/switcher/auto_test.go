@@ -0,0 +1,188 @@
+package switcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+func TestProfileScoreRate(t *testing.T) {
+	luid := ccd.LUID{LowPart: 1}
+	current := func(identities ...monitorIdentity) []currentTarget {
+		targets := make([]currentTarget, len(identities))
+		for i, identity := range identities {
+			targets[i] = currentTarget{adapterID: luid, identity: identity}
+		}
+		return targets
+	}
+	noEDID := func(targetID uint32) monitorIdentity {
+		return monitorIdentity{outputTechnology: ccd.DisplayConfigVideoOutputTechnologyHdmi, targetID: targetID}
+	}
+	hardwareOnly := identityOf(100, 0, 0, "", dell("UID1").DevicePath)
+
+	tests := []struct {
+		name    string
+		saved   []monitorIdentity
+		targets []currentTarget
+		want    ProfileScore
+	}{
+		{
+			name:    "same monitors",
+			saved:   []monitorIdentity{dellIdentity(100, "UID4352"), dellIdentity(200, "UID4353")},
+			targets: current(dellIdentity(200, "UID4353"), dellIdentity(100, "UID4352")),
+			want:    ProfileScore{Monitors: 2, Exact: 2, SameCount: true, Score: 2*autoScoreExact + autoScoreSameCount},
+		},
+		{
+			name:    "same model, other serial",
+			saved:   []monitorIdentity{dellIdentity(100, "UID1")},
+			targets: current(dellIdentity(100, "UID4352")),
+			want:    ProfileScore{Monitors: 1, Model: 1, SameCount: true, Score: autoScoreModel + autoScoreSameCount},
+		},
+		{
+			name:    "hardware ID only",
+			saved:   []monitorIdentity{hardwareOnly},
+			targets: current(dellIdentity(100, "UID4352")),
+			want:    ProfileScore{Monitors: 1, Model: 1, SameCount: true, Score: autoScoreModel + autoScoreSameCount},
+		},
+		{
+			name:    "no EDID",
+			saved:   []monitorIdentity{noEDID(100)},
+			targets: current(noEDID(100)),
+			want:    ProfileScore{Monitors: 1, Weak: 1, SameCount: true, Score: autoScoreWeak + autoScoreSameCount},
+		},
+		{
+			name:    "connected monitors left out",
+			saved:   []monitorIdentity{dellIdentity(100, "UID4352")},
+			targets: current(dellIdentity(100, "UID4352"), dellIdentity(200, "UID4353"), noEDID(300)),
+			want:    ProfileScore{Monitors: 1, Exact: 1, Score: autoScoreExact - 2*autoPenaltyExtra},
+		},
+		{
+			name:    "monitor not connected",
+			saved:   []monitorIdentity{dellIdentity(100, "UID4352"), dellIdentity(200, "UID4353")},
+			targets: current(dellIdentity(100, "UID4352")),
+			want:    ProfileScore{Monitors: 2, Exact: 1, Missing: 1},
+		},
+		{
+			name:    "no monitors",
+			targets: current(dellIdentity(100, "UID4352")),
+			want:    ProfileScore{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ProfileScore
+			got.rate(tt.saved, tt.targets)
+			if got != tt.want {
+				t.Errorf("rate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// profileDir saves the active configuration of twin machines to dir under
+// the given names: "both" with both monitors active, "left" with only
+// UID4352. Other names are copied from "both".
+func profileDir(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		path := filepath.Join(dir, name+".monitorprofile")
+		switch name {
+		case "left":
+			m := twin(1, 100, 200, true)
+			m.SetPresent(ccd.LUID{LowPart: 1}, 200, false)
+			if err := New(m).SaveProfile(path, SaveOptions{}); err != nil {
+				t.Fatalf("save %s: %v", name, err)
+			}
+		default:
+			if err := New(twin(1, 100, 200, true)).SaveProfile(path, SaveOptions{}); err != nil {
+				t.Fatalf("save %s: %v", name, err)
+			}
+		}
+	}
+	return dir
+}
+
+func TestScoreProfiles(t *testing.T) {
+	dir := profileDir(t, "both", "left")
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a profile"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A profile for a monitor that is not connected.
+	if err := os.WriteFile(filepath.Join(dir, "office.yaml"), []byte("monitors:\n  - name: LG HDR 4K\n    manufactureId: 0x6D1E\n    productCodeId: 0x7707\n    enabled: true\n    width: 3840\n    height: 2160\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scores, err := New(twin(1, 100, 200, true)).ScoreProfiles(dir)
+	if err != nil {
+		t.Fatalf("ScoreProfiles() = %v", err)
+	}
+	var got []string
+	for _, score := range scores {
+		got = append(got, fmt.Sprintf("%s %v %d", filepath.Base(score.Path), score.Matches(), score.Score))
+	}
+	want := []string{
+		fmt.Sprintf("both.monitorprofile true %d", 2*autoScoreExact+autoScoreSameCount+autoScoreFingerprint),
+		fmt.Sprintf("left.monitorprofile true %d", autoScoreExact-autoPenaltyExtra),
+		"broken.json false 0",
+		"office.yaml false 0",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("scores = %q, want %q", got, want)
+	}
+	if scores[2].Err == nil {
+		t.Error("broken.json was scored without an error")
+	}
+	if !scores[0].Fingerprint || scores[1].Fingerprint {
+		t.Errorf("fingerprint matches = %v, %v, want true, false", scores[0].Fingerprint, scores[1].Fingerprint)
+	}
+}
+
+func TestAutoLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []string
+		wantErr  error
+		// want is the profile applied.
+		want string
+	}{
+		{name: "best match", profiles: []string{"left", "both"}, want: "both.monitorprofile"},
+		{name: "fewer monitors than connected", profiles: []string{"left"}, want: "left.monitorprofile"},
+		{name: "tie", profiles: []string{"both", "copy"}, wantErr: ErrAmbiguousProfile},
+		{name: "no profiles", wantErr: ErrNoProfileMatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := profileDir(t, tt.profiles...)
+			m := twin(1, 100, 200, true)
+			var out bytes.Buffer
+			result, err := New(m).AutoLoad(dir, LoadOptions{Output: &out})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AutoLoad() = %v, want %v", err, tt.wantErr)
+			}
+			if got := filepath.Base(result.Applied); tt.want != "" && got != tt.want {
+				t.Errorf("applied %s, want %s", got, tt.want)
+			}
+			if tt.want == "" && result.Applied != "" {
+				t.Errorf("applied %s, want none", result.Applied)
+			}
+			if (result.Load != nil) != (tt.want != "") || result.Load != nil && result.Load.Profile != result.Applied {
+				t.Errorf("load report = %+v for applied %q", result.Load, result.Applied)
+			}
+			if len(result.Scores) != len(tt.profiles) {
+				t.Errorf("%d scores, want %d", len(result.Scores), len(tt.profiles))
+			}
+			if !bytes.Contains(out.Bytes(), []byte("Profiles in ")) {
+				t.Errorf("ranking not explained:\n%s", out.String())
+			}
+		})
+	}
+}
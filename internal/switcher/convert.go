@@ -90,6 +90,8 @@ func profileFromCCD(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfig
 			Valid:                 info.Valid,
 			MonitorDevicePath:     info.MonitorDevicePath,
 			MonitorFriendlyDevice: info.MonitorFriendlyDevice,
+			ConnectorInstance:     info.ConnectorInstance,
+			OutputTechnology:      uint32(info.OutputTechnology),
 		}
 	}
 
@@ -182,6 +184,8 @@ func ccdFromProfile(prof profile.Profile) ([]ccd.DisplayConfigPathInfo, []ccd.Di
 			Valid:                 info.Valid,
 			MonitorDevicePath:     info.MonitorDevicePath,
 			MonitorFriendlyDevice: info.MonitorFriendlyDevice,
+			ConnectorInstance:     info.ConnectorInstance,
+			OutputTechnology:      ccd.DisplayConfigVideoOutputTechnology(info.OutputTechnology),
 		}
 	}
 
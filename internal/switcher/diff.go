@@ -0,0 +1,276 @@
+package switcher
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/profile"
+)
+
+// CurrentConfiguration names the live configuration in a ProfileDiff.
+const CurrentConfiguration = "current configuration"
+
+// Change is one property of a monitor that differs between two layouts.
+// Property is one of enabled, resolution, refresh, position, rotation,
+// scaling, virtualMode, cloneGroup and desktopImage.
+type Change struct {
+	Property string `json:"property"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+// MonitorDiff lists the changes of one monitor.
+type MonitorDiff struct {
+	Monitor string   `json:"monitor"`
+	Changes []Change `json:"changes"`
+}
+
+// ProfileDiff is how layout To differs from layout From, per monitor.
+type ProfileDiff struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Monitors []MonitorDiff `json:"monitors"`
+	// Ambiguous lists monitors that could be aligned more than one way.
+	Ambiguous []string `json:"ambiguous,omitempty"`
+}
+
+// Write prints the diff as text, one indented line per change.
+func (d ProfileDiff) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s -> %s\n", d.From, d.To)
+	for _, issue := range d.Ambiguous {
+		fmt.Fprintf(&b, "Ambiguous: %s\n", issue)
+	}
+	if len(d.Monitors) == 0 {
+		b.WriteString("No differences\n")
+	}
+	for _, monitor := range d.Monitors {
+		fmt.Fprintf(&b, "%s:\n", monitor.Monitor)
+		for _, change := range monitor.Changes {
+			fmt.Fprintf(&b, "  %s %s -> %s\n", change.Property, change.From, change.To)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// DiffProfiles compares the profiles at paths a and b.
+func DiffProfiles(a, b string) (ProfileDiff, error) {
+	from, err := profileLayouts(a)
+	if err != nil {
+		return ProfileDiff{}, err
+	}
+	to, err := profileLayouts(b)
+	if err != nil {
+		return ProfileDiff{}, err
+	}
+	return diffMonitors(a, b, from, to), nil
+}
+
+// DiffCurrent compares the profile at path with the live configuration.
+func (s *Switcher) DiffCurrent(path string) (ProfileDiff, error) {
+	from, err := profileLayouts(path)
+	if err != nil {
+		return ProfileDiff{}, err
+	}
+	paths, modes, additional, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsAllPaths|ccd.QueryDisplayFlagsVirtualModeAware)
+	if err != nil {
+		paths, modes, additional, err = ccd.GetDisplaySettings(s.backend, false)
+		if err != nil {
+			return ProfileDiff{}, fmt.Errorf("get display settings: %w", err)
+		}
+	}
+	to := diffableLayouts(layoutsFromCCD(paths, modes, additional))
+	// Inactive paths carry no target mode, so their identity comes from
+	// the monitor itself.
+	targets := currentTargets(s.backend, paths)
+	for i := range to {
+		if to[i].identity.info.Valid {
+			continue
+		}
+		for _, target := range targets {
+			if target.adapterID == to[i].adapterID && target.identity.targetID == to[i].targetID {
+				to[i].identity = target.identity
+				to[i].name = target.identity.info.MonitorFriendlyDevice
+				break
+			}
+		}
+	}
+	return diffMonitors(path, CurrentConfiguration, from, to), nil
+}
+
+// profileLayouts loads a profile and lists its monitors.
+func profileLayouts(path string) ([]monitorLayout, error) {
+	prof, err := profile.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if !prof.IsLayout() {
+		paths, modes, additional := ccdFromProfile(prof)
+		return diffableLayouts(layoutsFromCCD(paths, modes, additional)), nil
+	}
+	var offset profile.PointL
+	for _, monitor := range prof.Monitors {
+		if monitor.Enabled && monitor.Primary {
+			offset = profile.PointL{X: -monitor.Position.X, Y: -monitor.Position.Y}
+			break
+		}
+	}
+	var layouts []monitorLayout
+	for _, monitor := range prof.Monitors {
+		layouts = append(layouts, layoutOfMonitor(monitor, offset))
+	}
+	return layouts, nil
+}
+
+// diffableLayouts keeps one layout per target, the active one if any, and
+// drops inactive paths to targets that are not available.
+func diffableLayouts(layouts []monitorLayout) []monitorLayout {
+	index := make(map[layoutKey]int)
+	var out []monitorLayout
+	for _, layout := range layouts {
+		if !layout.active && !layout.available {
+			continue
+		}
+		key := layoutKey{adapterID: layout.adapterID, targetID: layout.targetID}
+		if i, ok := index[key]; ok {
+			if layout.active && !out[i].active {
+				out[i] = layout
+			}
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, layout)
+	}
+	return out
+}
+
+// diffMonitors aligns the monitors of two layouts by identity and lists
+// what changed for each. Monitors that are off on both sides are skipped.
+func diffMonitors(fromName, toName string, from, to []monitorLayout) ProfileDiff {
+	result := ProfileDiff{From: fromName, To: toName, Monitors: []MonitorDiff{}}
+
+	saved := make([]monitorIdentity, len(from))
+	for i := range from {
+		saved[i] = from[i].identity
+	}
+	targets := make([]currentTarget, len(to))
+	for i := range to {
+		targets[i] = currentTarget{adapterID: to[i].adapterID, identity: to[i].identity}
+	}
+	match := matchIdentities(saved, targets)
+	result.Ambiguous = match.ambiguous
+
+	// Pair by index: the monitors of a per-monitor profile share the zero
+	// adapter and usually target ID 0.
+	paired := make(map[int]bool)
+	for i := range from {
+		prev := &from[i]
+		var next *monitorLayout
+		if j, ok := match.indices[i]; ok {
+			next = &to[j]
+			paired[j] = true
+		}
+		if changes := compareMonitors(prev, next); len(changes) > 0 {
+			result.Monitors = append(result.Monitors, MonitorDiff{Monitor: prev.identity.String(), Changes: changes})
+		}
+	}
+	for j := range to {
+		if paired[j] {
+			continue
+		}
+		if changes := compareMonitors(nil, &to[j]); len(changes) > 0 {
+			result.Monitors = append(result.Monitors, MonitorDiff{Monitor: to[j].identity.String(), Changes: changes})
+		}
+	}
+	sort.SliceStable(result.Monitors, func(a, b int) bool {
+		return result.Monitors[a].Monitor < result.Monitors[b].Monitor
+	})
+	return result
+}
+
+// compareMonitors lists the changes from prev to next; either may be nil
+// when the monitor only appears on one side.
+func compareMonitors(prev, next *monitorLayout) []Change {
+	prevState, nextState := enabledState(prev), enabledState(next)
+	if prevState != nextState {
+		if (prev == nil || !prev.active) && (next == nil || !next.active) {
+			return nil
+		}
+		return []Change{{Property: "enabled", From: prevState, To: nextState}}
+	}
+	if prev == nil || next == nil || !prev.active {
+		return nil
+	}
+
+	var changes []Change
+	add := func(property, from, to string) {
+		if from != to {
+			changes = append(changes, Change{Property: property, From: from, To: to})
+		}
+	}
+	if prev.hasSource && next.hasSource {
+		add("resolution", fmt.Sprintf("%dx%d", prev.width, prev.height), fmt.Sprintf("%dx%d", next.width, next.height))
+	}
+	if !sameRefresh(*prev, *next) {
+		add("refresh", formatRefreshRate(prev.refresh), formatRefreshRate(next.refresh))
+	}
+	if prev.hasSource && next.hasSource {
+		add("position", formatPoint(prev.position), formatPoint(next.position))
+	}
+	add("rotation", strconv.Itoa(rotationDegrees(prev.rotation)), strconv.Itoa(rotationDegrees(next.rotation)))
+	add("scaling", scalingName(prev.scaling), scalingName(next.scaling))
+	if !prev.perMonitor && !next.perMonitor && (prev.virtual || next.virtual) {
+		add("virtualMode", yesNo(prev.virtual), yesNo(next.virtual))
+		if prev.virtual && next.virtual {
+			add("cloneGroup", formatCloneGroup(prev.cloneGroup), formatCloneGroup(next.cloneGroup))
+			add("desktopImage", formatDesktopImage(prev.desktop), formatDesktopImage(next.desktop))
+		}
+	}
+	return changes
+}
+
+func enabledState(layout *monitorLayout) string {
+	switch {
+	case layout == nil:
+		return "absent"
+	case layout.active:
+		return "yes"
+	}
+	return "no"
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+func formatPoint(p ccd.PointL) string {
+	return fmt.Sprintf("(%d,%d)", p.X, p.Y)
+}
+
+func formatCloneGroup(group uint32) string {
+	if group == ccd.DisplayConfigPathPackedIdxInvalid {
+		return "none"
+	}
+	return strconv.Itoa(int(group))
+}
+
+func formatDesktopImage(info *ccd.DisplayConfigDesktopImageInfo) string {
+	if info == nil {
+		return "none"
+	}
+	return fmt.Sprintf("source %dx%d, region %s, clip %s",
+		info.PathSourceSize.X, info.PathSourceSize.Y,
+		formatRect(info.DesktopImageRegion), formatRect(info.DesktopImageClip))
+}
+
+func formatRect(r ccd.RectL) string {
+	return fmt.Sprintf("(%d,%d)-(%d,%d)", r.Left, r.Top, r.Right, r.Bottom)
+}
@@ -0,0 +1,191 @@
+package switcher
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/profile"
+)
+
+func TestCompareMonitors(t *testing.T) {
+	base := func() *monitorLayout {
+		return &monitorLayout{
+			targetID:   100,
+			active:     true,
+			hasSource:  true,
+			width:      1920,
+			height:     1080,
+			refresh:    ccd.DisplayConfigRational{Numerator: 60, Denominator: 1},
+			rotation:   ccd.DisplayConfigRotationIdentity,
+			scaling:    ccd.DisplayConfigScalingPreferred,
+			cloneGroup: ccd.DisplayConfigPathPackedIdxInvalid,
+		}
+	}
+	with := func(edit func(l *monitorLayout)) *monitorLayout {
+		l := base()
+		edit(l)
+		return l
+	}
+	off := with(func(l *monitorLayout) { l.active = false })
+
+	tests := []struct {
+		name       string
+		prev, next *monitorLayout
+		// want lists "property from -> to".
+		want []string
+	}{
+		{name: "same", prev: base(), next: base()},
+		{name: "turned off", prev: base(), next: off, want: []string{"enabled yes -> no"}},
+		{name: "connected", prev: nil, next: base(), want: []string{"enabled absent -> yes"}},
+		{name: "off on both sides", prev: off, next: nil},
+		{
+			name: "resolution and position",
+			prev: base(),
+			next: with(func(l *monitorLayout) { l.width, l.height, l.position = 2560, 1440, ccd.PointL{X: 1920} }),
+			want: []string{"resolution 1920x1080 -> 2560x1440", "position (0,0) -> (1920,0)"},
+		},
+		{
+			name: "refresh",
+			prev: base(),
+			next: with(func(l *monitorLayout) { l.refresh = ccd.DisplayConfigRational{Numerator: 144, Denominator: 1} }),
+			want: []string{"refresh 60.00 Hz (60/1) -> 144.00 Hz (144/1)"},
+		},
+		{
+			name: "refresh left to the driver",
+			prev: with(func(l *monitorLayout) {
+				l.refresh, l.anyRefresh, l.perMonitor = ccd.DisplayConfigRational{}, true, true
+			}),
+			next: with(func(l *monitorLayout) { l.refresh = ccd.DisplayConfigRational{Numerator: 144, Denominator: 1} }),
+		},
+		{
+			name: "rotation and scaling",
+			prev: base(),
+			next: with(func(l *monitorLayout) {
+				l.rotation, l.scaling = ccd.DisplayConfigRotationRotate90, ccd.DisplayConfigScalingStretched
+			}),
+			want: []string{"rotation 0 -> 90", "scaling preferred -> stretched"},
+		},
+		{
+			name: "virtual mode",
+			prev: base(),
+			next: with(func(l *monitorLayout) { l.virtual = true }),
+			want: []string{"virtualMode no -> yes"},
+		},
+		{
+			name: "clone group",
+			prev: with(func(l *monitorLayout) { l.virtual = true }),
+			next: with(func(l *monitorLayout) { l.virtual, l.cloneGroup = true, 1 }),
+			want: []string{"cloneGroup none -> 1"},
+		},
+		{
+			name: "virtual mode not compared with the per-monitor format",
+			prev: with(func(l *monitorLayout) { l.perMonitor = true }),
+			next: with(func(l *monitorLayout) { l.virtual = true }),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, change := range compareMonitors(tt.prev, tt.next) {
+				got = append(got, fmt.Sprintf("%s %s -> %s", change.Property, change.From, change.To))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("compareMonitors() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// diffLines flattens a diff to "monitor: property from -> to" lines.
+func diffLines(d ProfileDiff) []string {
+	var lines []string
+	for _, monitor := range d.Monitors {
+		for _, change := range monitor.Changes {
+			lines = append(lines, fmt.Sprintf("%s: %s %s -> %s", monitor.Monitor, change.Property, change.From, change.To))
+		}
+	}
+	return lines
+}
+
+func TestDiffProfiles(t *testing.T) {
+	dir := profileDir(t, "both", "left")
+	both, left := filepath.Join(dir, "both.monitorprofile"), filepath.Join(dir, "left.monitorprofile")
+	desk := filepath.Join(dir, "desk.yaml")
+	monitors := profile.Profile{Monitors: []profile.Monitor{dellMonitor("UID4353", 0, 1920, 1080), dellMonitor("UID4352", 1920, 1920, 1080)}}
+	if err := profile.Save(desk, monitors, profile.SaveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{name: "same profile", a: both, b: both},
+		{name: "monitor removed", a: both, b: left, want: []string{"DELL P2419H (target id 200): enabled yes -> absent"}},
+		{name: "monitor added", a: left, b: both, want: []string{"DELL P2419H (target id 200): enabled absent -> yes"}},
+		{
+			name: "swapped in the per-monitor format",
+			a:    both,
+			b:    desk,
+			want: []string{
+				"DELL P2419H (target id 100): position (0,0) -> (1920,0)",
+				"DELL P2419H (target id 200): position (1920,0) -> (0,0)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := DiffProfiles(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("DiffProfiles() = %v", err)
+			}
+			if got := diffLines(diff); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("diff = %q, want %q", got, tt.want)
+			}
+			if len(diff.Ambiguous) != 0 {
+				t.Errorf("ambiguous = %q", diff.Ambiguous)
+			}
+		})
+	}
+
+	if _, err := DiffProfiles(both, filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("DiffProfiles() with a missing profile succeeded")
+	}
+}
+
+func TestDiffCurrent(t *testing.T) {
+	dir := profileDir(t, "both")
+	path := filepath.Join(dir, "both.monitorprofile")
+	m := twin(1, 100, 200, true)
+
+	diff, err := New(m).DiffCurrent(path)
+	if err != nil {
+		t.Fatalf("DiffCurrent() = %v", err)
+	}
+	if len(diff.Monitors) != 0 || diff.To != CurrentConfiguration {
+		t.Errorf("diff against the saved machine = %+v, want no differences", diff)
+	}
+	var out bytes.Buffer
+	if err := diff.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if want := path + " -> current configuration\nNo differences\n"; out.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	// The second monitor is still connected but off.
+	m = twin(1, 100, 200, false)
+	activate(m, ccd.LUID{LowPart: 1}, 0, 100, 0, 1920, 1080)
+	diff, err = New(m).DiffCurrent(path)
+	if err != nil {
+		t.Fatalf("DiffCurrent() = %v", err)
+	}
+	want := []string{"DELL P2419H (target id 200): enabled yes -> no"}
+	if got := diffLines(diff); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package switcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// Fingerprint identifies the set of monitors connected to the machine, so a
+// dock or room can be recognized no matter how its monitors are arranged.
+type Fingerprint struct {
+	// ID is a short hash of Monitors, e.g. "3f9a0c1d2b4e5f60".
+	ID string `json:"id"`
+	// Monitors has one sorted entry per connected monitor:
+	// manufacturer:product:device path instance:output technology.
+	Monitors []string `json:"monitors"`
+}
+
+// Fingerprint computes the fingerprint of the monitors currently connected,
+// active or not.
+func (s *Switcher) Fingerprint() (Fingerprint, error) {
+	paths, _, _, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsAllPaths)
+	if err != nil {
+		return Fingerprint{}, fmt.Errorf("get display settings: %w", err)
+	}
+	return fingerprintOf(currentTargets(s.backend, paths)), nil
+}
+
+func fingerprintOf(targets []currentTarget) Fingerprint {
+	var fp Fingerprint
+	for _, target := range targets {
+		fp.Monitors = append(fp.Monitors, fingerprintEntry(target.identity))
+	}
+	sort.Strings(fp.Monitors)
+	sum := sha256.Sum256([]byte(strings.Join(fp.Monitors, "\n")))
+	fp.ID = hex.EncodeToString(sum[:8])
+	return fp
+}
+
+// fingerprintEntry describes one monitor by values that do not depend on
+// target IDs, adapter LUIDs or the order paths are reported in: its EDID
+// manufacturer and product, the instance part of its device path and how
+// it is connected. CCD does not expose the EDID serial number; the instance
+// (5&2a2e2a3f&0&UID4352) ends in the UID of the connector, so moving a
+// monitor to another port changes its entry. Targets without a readable
+// identity fall back to their target ID.
+func fingerprintEntry(identity monitorIdentity) string {
+	if !identity.info.Valid {
+		return fmt.Sprintf("unknown:target%d:%d", identity.targetID, uint32(identity.outputTechnology))
+	}
+	_, instance := devicePathParts(identity.info.MonitorDevicePath)
+	return fmt.Sprintf("%04X:%04X:%s:%d",
+		identity.info.ManufactureID, identity.info.ProductCodeID,
+		strings.ToUpper(instance), uint32(identity.outputTechnology))
+}
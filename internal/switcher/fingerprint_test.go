@@ -0,0 +1,108 @@
+package switcher
+
+import (
+	"fmt"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+)
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(m *ccdsim.Machine) Fingerprint {
+		t.Helper()
+		fp, err := New(m).Fingerprint()
+		if err != nil {
+			t.Fatalf("Fingerprint() = %v", err)
+		}
+		return fp
+	}
+	want := fingerprint(twin(1, 100, 200, true))
+	if len(want.Monitors) != 2 || len(want.ID) != 16 {
+		t.Fatalf("Fingerprint() = %+v, want two monitors and a 16-character ID", want)
+	}
+
+	unplugged := target(300, lg())
+	unplugged.Present = false
+	tests := []struct {
+		name string
+		m    *ccdsim.Machine
+		same bool
+	}{
+		{name: "same machine", m: twin(1, 100, 200, true), same: true},
+		{name: "monitors off", m: twin(1, 100, 200, false), same: true},
+		{
+			name: "targets reordered on another adapter",
+			m: ccdsim.New(ccdsim.Adapter{ID: ccd.LUID{LowPart: 7}, Sources: []uint32{0, 1}, Targets: []ccdsim.Target{
+				target(400, dell("UID4353")),
+				target(300, dell("UID4352")),
+			}}),
+			same: true,
+		},
+		{
+			name: "unplugged target ignored",
+			m: ccdsim.New(ccdsim.Adapter{ID: ccd.LUID{LowPart: 1}, Sources: []uint32{0, 1}, Targets: []ccdsim.Target{
+				target(100, dell("UID4352")),
+				unplugged,
+				target(200, dell("UID4353")),
+			}}),
+			same: true,
+		},
+		{
+			name: "monitor on another port",
+			m: ccdsim.New(ccdsim.Adapter{ID: ccd.LUID{LowPart: 1}, Sources: []uint32{0, 1}, Targets: []ccdsim.Target{
+				target(100, dell("UID4352")),
+				target(200, dell("UID4354")),
+			}}),
+		},
+		{
+			name: "monitor added",
+			m: ccdsim.New(ccdsim.Adapter{ID: ccd.LUID{LowPart: 1}, Sources: []uint32{0, 1}, Targets: []ccdsim.Target{
+				target(100, dell("UID4352")),
+				target(200, dell("UID4353")),
+				target(300, lg()),
+			}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fingerprint(tt.m)
+			if (got.ID == want.ID) != tt.same {
+				t.Errorf("Fingerprint() = %+v, want same as %+v: %v", got, want, tt.same)
+			}
+			if tt.same && fmt.Sprint(got.Monitors) != fmt.Sprint(want.Monitors) {
+				t.Errorf("monitors = %q, want %q", got.Monitors, want.Monitors)
+			}
+		})
+	}
+}
+
+func TestFingerprintEntry(t *testing.T) {
+	noEDID := monitorIdentity{outputTechnology: ccd.DisplayConfigVideoOutputTechnologyHdmi, targetID: 7}
+	tests := []struct {
+		name     string
+		identity monitorIdentity
+		want     string
+	}{
+		{name: "EDID", identity: dellIdentity(100, "uid4352"), want: "10AC:4123:5&1A&0&UID4352:10"},
+		{name: "no EDID", identity: noEDID, want: "unknown:target7:5"},
+	}
+	for _, tt := range tests {
+		if got := fingerprintEntry(tt.identity); got != tt.want {
+			t.Errorf("%s: fingerprintEntry() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Without an identity the target ID is all that tells monitors apart.
+	moved := noEDID
+	moved.targetID = 8
+	a := fingerprintOf([]currentTarget{{identity: dellIdentity(100, "UID4352")}, {identity: noEDID}})
+	b := fingerprintOf([]currentTarget{{identity: noEDID}, {identity: dellIdentity(200, "UID4352")}})
+	c := fingerprintOf([]currentTarget{{identity: dellIdentity(100, "UID4352")}, {identity: moved}})
+	if a.ID != b.ID {
+		t.Errorf("fingerprintOf() = %+v and %+v, want the same ID", a, b)
+	}
+	if a.ID == c.ID {
+		t.Errorf("fingerprintOf() = %+v for a target without EDID under another ID, want a new ID", c)
+	}
+}
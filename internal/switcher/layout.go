@@ -0,0 +1,184 @@
+package switcher
+
+import (
+	"fmt"
+	"sort"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// monitorLayout is the user-visible state of one target: the things a
+// person would notice changing on screen.
+type monitorLayout struct {
+	adapterID ccd.LUID
+	targetID  uint32
+	name      string
+	active    bool
+	hasSource bool
+	width     uint32
+	height    uint32
+	position  ccd.PointL
+	refresh   ccd.DisplayConfigRational
+	// anyRefresh marks a per-monitor entry without refreshHz, for which
+	// Windows picks the rate; it matches any rate.
+	anyRefresh bool
+	rotation   ccd.DisplayConfigRotation
+	scaling    ccd.DisplayConfigScaling
+	// identity and the virtual-mode fields are only compared by -diff.
+	// perMonitor marks layouts read from the per-monitor format, which has
+	// no virtual-mode data.
+	identity   monitorIdentity
+	available  bool
+	perMonitor bool
+	virtual    bool
+	cloneGroup uint32
+	desktop    *ccd.DisplayConfigDesktopImageInfo
+}
+
+func (l monitorLayout) label() string {
+	if l.name != "" {
+		return fmt.Sprintf("%s (target id %d)", l.name, l.targetID)
+	}
+	return fmt.Sprintf("target id %d", l.targetID)
+}
+
+type layoutKey struct {
+	adapterID ccd.LUID
+	targetID  uint32
+}
+
+// layoutsFromCCD extracts one monitorLayout per path, resolving plain and
+// packed mode indices. additional is aligned with modes and may be nil.
+func layoutsFromCCD(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, additional []ccd.MonitorAdditionalInfo) []monitorLayout {
+	layouts := make([]monitorLayout, 0, len(paths))
+	for i := range paths {
+		path := &paths[i]
+		identity := profileIdentity(path, modes, additional)
+		layout := monitorLayout{
+			adapterID:  path.TargetInfo.AdapterID,
+			targetID:   path.TargetInfo.ID,
+			name:       identity.info.MonitorFriendlyDevice,
+			active:     path.Flags&uint32(ccd.DisplayConfigFlagPathActive) != 0,
+			refresh:    path.TargetInfo.RefreshRate,
+			rotation:   path.TargetInfo.Rotation,
+			scaling:    path.TargetInfo.Scaling,
+			identity:   identity,
+			available:  path.TargetInfo.TargetAvailable != 0,
+			virtual:    path.VirtualModeAware(),
+			cloneGroup: ccd.DisplayConfigPathPackedIdxInvalid,
+		}
+		if group, ok := path.CloneGroupID(); ok {
+			layout.cloneGroup = group
+		}
+		if idx, ok := path.DesktopModeIdx(); ok && idx < len(modes) && modes[idx].InfoType == ccd.DisplayConfigModeInfoTypeDesktopImage {
+			desktop := *modes[idx].DesktopImageInfo()
+			layout.desktop = &desktop
+		}
+		if idx, ok := path.SourceModeIdx(); ok && idx < len(modes) && modes[idx].InfoType == ccd.DisplayConfigModeInfoTypeSource {
+			source := modes[idx].SourceMode()
+			layout.hasSource = true
+			layout.width = source.Width
+			layout.height = source.Height
+			layout.position = source.Position
+		}
+		if layout.refresh.Denominator == 0 {
+			if idx, ok := path.TargetModeIdx(); ok && idx < len(modes) && modes[idx].InfoType == ccd.DisplayConfigModeInfoTypeTarget {
+				layout.refresh = modes[idx].TargetMode().TargetVideoSignalInfo.VSyncFreq
+			}
+		}
+		layouts = append(layouts, layout)
+	}
+	return layouts
+}
+
+// requestedLayouts describes what an attempt asked for. perMonitor marks
+// arrays compiled from a per-monitor profile, whose paths carry no rate
+// (0/0) for entries without refreshHz.
+func requestedLayouts(attempt Attempt, perMonitor bool) []monitorLayout {
+	layouts := layoutsFromCCD(attempt.Paths, attempt.Modes, attempt.Additional)
+	if perMonitor {
+		for i := range layouts {
+			layouts[i].anyRefresh = layouts[i].refresh.Denominator == 0
+		}
+	}
+	return layouts
+}
+
+// diffLayouts describes how after differs from before, one line per changed
+// property, matching active monitors by adapter and target ID. Inactive
+// paths only mean a monitor is off.
+func diffLayouts(before []monitorLayout, after []monitorLayout) []string {
+	beforeByKey := activeLayouts(before)
+	afterByKey := activeLayouts(after)
+
+	var lines []string
+	for key, next := range afterByKey {
+		prev, ok := beforeByKey[key]
+		if !ok {
+			lines = append(lines, fmt.Sprintf("%s: enabled at %dx%d @ (%d,%d), %s", next.label(), next.width, next.height, next.position.X, next.position.Y, formatRefreshRate(next.refresh)))
+			continue
+		}
+		lines = append(lines, compareLayouts(prev, next)...)
+	}
+	for key, prev := range beforeByKey {
+		if _, ok := afterByKey[key]; !ok {
+			lines = append(lines, fmt.Sprintf("%s: disabled", prev.label()))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func activeLayouts(layouts []monitorLayout) map[layoutKey]monitorLayout {
+	byKey := make(map[layoutKey]monitorLayout, len(layouts))
+	for _, layout := range layouts {
+		if layout.active {
+			byKey[layoutKey{adapterID: layout.adapterID, targetID: layout.targetID}] = layout
+		}
+	}
+	return byKey
+}
+
+// compareLayouts lists the property changes between two active layouts of
+// the same monitor.
+func compareLayouts(prev monitorLayout, next monitorLayout) []string {
+	var lines []string
+	label := next.label()
+	if prev.hasSource && next.hasSource {
+		if prev.width != next.width || prev.height != next.height {
+			lines = append(lines, fmt.Sprintf("%s: resolution %dx%d -> %dx%d", label, prev.width, prev.height, next.width, next.height))
+		}
+		if prev.position != next.position {
+			lines = append(lines, fmt.Sprintf("%s: position (%d,%d) -> (%d,%d)", label, prev.position.X, prev.position.Y, next.position.X, next.position.Y))
+		}
+	}
+	if !sameRefresh(prev, next) {
+		lines = append(lines, fmt.Sprintf("%s: refresh %s -> %s", label, formatRefreshRate(prev.refresh), formatRefreshRate(next.refresh)))
+	}
+	if prev.rotation != next.rotation {
+		lines = append(lines, fmt.Sprintf("%s: rotation %s -> %s", label, prev.rotation, next.rotation))
+	}
+	if prev.scaling != next.scaling {
+		lines = append(lines, fmt.Sprintf("%s: scaling %s -> %s", label, prev.scaling, next.scaling))
+	}
+	return lines
+}
+
+// sameRefresh reports whether two layouts of a monitor run at the same
+// refresh rate.
+func sameRefresh(prev monitorLayout, next monitorLayout) bool {
+	return prev.anyRefresh || next.anyRefresh || sameRefreshRate(prev.refresh, next.refresh)
+}
+
+// sameRefreshRate compares two rates to within 0.01 Hz, since the same rate
+// is reported with different denominators. A rate with a zero denominator
+// only matches the identical rate.
+func sameRefreshRate(a ccd.DisplayConfigRational, b ccd.DisplayConfigRational) bool {
+	if a.Denominator == 0 || b.Denominator == 0 {
+		return a == b
+	}
+	hzA := float64(a.Numerator) / float64(a.Denominator)
+	hzB := float64(b.Numerator) / float64(b.Denominator)
+	diff := hzA - hzB
+	return diff < 0.01 && diff > -0.01
+}
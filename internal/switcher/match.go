@@ -0,0 +1,366 @@
+package switcher
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// Scores for each piece of monitor identity two targets have in common. A
+// full device path match means the same monitor on the same connector; the
+// instance portion alone still tells identical monitors apart.
+const (
+	scoreDevicePath   = 100
+	scoreInstance     = 40
+	scoreEDID         = 30
+	scoreHardwareID   = 20
+	scoreFriendlyName = 10
+	scoreConnector    = 8
+	scoreOutputTech   = 4
+	scoreTargetID     = 2
+)
+
+// monitorIdentity is what a target reports about the attached monitor.
+type monitorIdentity struct {
+	info             ccd.MonitorAdditionalInfo
+	outputTechnology ccd.DisplayConfigVideoOutputTechnology
+	targetID         uint32
+}
+
+func (m monitorIdentity) String() string {
+	if m.info.Valid && m.info.MonitorFriendlyDevice != "" {
+		return fmt.Sprintf("%s (target id %d)", m.info.MonitorFriendlyDevice, m.targetID)
+	}
+	return fmt.Sprintf("target id %d", m.targetID)
+}
+
+// currentTarget is an available target on the running machine.
+type currentTarget struct {
+	adapterID ccd.LUID
+	identity  monitorIdentity
+}
+
+func (t currentTarget) String() string {
+	return fmt.Sprintf("%s on adapter %s", t.identity, formatAdapterID(t.adapterID))
+}
+
+// monitorMatch maps profile paths (by index) to current targets.
+type monitorMatch struct {
+	targets   map[int]currentTarget
+	scores    map[int]int
+	ambiguous []string
+	unmatched []int
+	// indices maps profile paths to the index of their target in the
+	// targets matched against.
+	indices map[int]int
+}
+
+// complete reports whether every profile path found exactly one target.
+func (m monitorMatch) complete() bool {
+	return len(m.targets) > 0 && len(m.unmatched) == 0 && len(m.ambiguous) == 0
+}
+
+// currentTargets lists the available targets in a query result, with the
+// monitor identity reported for each.
+func currentTargets(backend ccd.DisplayBackend, paths []ccd.DisplayConfigPathInfo) []currentTarget {
+	type key struct {
+		adapter ccd.LUID
+		id      uint32
+	}
+	seen := make(map[key]bool)
+	var targets []currentTarget
+	for _, path := range paths {
+		if path.TargetInfo.TargetAvailable == 0 {
+			continue
+		}
+		k := key{adapter: path.TargetInfo.AdapterID, id: path.TargetInfo.ID}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		info, err := ccd.GetMonitorAdditionalInfo(backend, path.TargetInfo.AdapterID, path.TargetInfo.ID)
+		if err != nil {
+			info = ccd.MonitorAdditionalInfo{Valid: false}
+		}
+		targets = append(targets, currentTarget{
+			adapterID: path.TargetInfo.AdapterID,
+			identity: monitorIdentity{
+				info:             info,
+				outputTechnology: path.TargetInfo.OutputTechnology,
+				targetID:         path.TargetInfo.ID,
+			},
+		})
+	}
+	return targets
+}
+
+// profileIdentity returns the identity saved for a profile path's target.
+func profileIdentity(path *ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, additional []ccd.MonitorAdditionalInfo) monitorIdentity {
+	identity := monitorIdentity{
+		outputTechnology: path.TargetInfo.OutputTechnology,
+		targetID:         path.TargetInfo.ID,
+	}
+	if idx, ok := path.TargetModeIdx(); ok && idx < len(additional) && idx < len(modes) && additional[idx].Valid {
+		identity.info = additional[idx]
+		return identity
+	}
+	for i := range modes {
+		if modes[i].InfoType == ccd.DisplayConfigModeInfoTypeTarget && modes[i].ID == path.TargetInfo.ID && i < len(additional) && additional[i].Valid {
+			identity.info = additional[i]
+			break
+		}
+	}
+	return identity
+}
+
+// scoreIdentity rates how likely two identities are the same monitor. Zero
+// means they cannot be: conflicting EDID or hardware IDs, or no shared
+// evidence at all.
+func scoreIdentity(saved monitorIdentity, current monitorIdentity) int {
+	if !saved.info.Valid || !current.info.Valid {
+		if saved.targetID == current.targetID && saved.outputTechnology == current.outputTechnology {
+			return scoreTargetID + scoreOutputTech
+		}
+		return 0
+	}
+
+	savedEDID := saved.info.ManufactureID != 0 || saved.info.ProductCodeID != 0
+	currentEDID := current.info.ManufactureID != 0 || current.info.ProductCodeID != 0
+	sameEDID := savedEDID && currentEDID &&
+		saved.info.ManufactureID == current.info.ManufactureID &&
+		saved.info.ProductCodeID == current.info.ProductCodeID
+	if savedEDID && currentEDID && !sameEDID {
+		return 0
+	}
+
+	savedHW, savedInstance := devicePathParts(saved.info.MonitorDevicePath)
+	currentHW, currentInstance := devicePathParts(current.info.MonitorDevicePath)
+	sameHW := savedHW != "" && strings.EqualFold(savedHW, currentHW)
+	if savedHW != "" && currentHW != "" && !sameHW {
+		return 0
+	}
+	sameName := saved.info.MonitorFriendlyDevice != "" && saved.info.MonitorFriendlyDevice == current.info.MonitorFriendlyDevice
+	samePath := saved.info.MonitorDevicePath != "" && strings.EqualFold(saved.info.MonitorDevicePath, current.info.MonitorDevicePath)
+	if !sameEDID && !sameHW && !sameName && !samePath {
+		return 0
+	}
+
+	score := 0
+	if samePath {
+		score += scoreDevicePath
+	}
+	if savedInstance != "" && strings.EqualFold(savedInstance, currentInstance) {
+		score += scoreInstance
+	}
+	if sameEDID {
+		score += scoreEDID
+	}
+	if sameHW {
+		score += scoreHardwareID
+	}
+	if sameName {
+		score += scoreFriendlyName
+	}
+	if saved.info.ConnectorInstance != 0 && saved.info.ConnectorInstance == current.info.ConnectorInstance {
+		score += scoreConnector
+	}
+	if saved.outputTechnology == current.outputTechnology {
+		score += scoreOutputTech
+	}
+	if saved.targetID == current.targetID {
+		score += scoreTargetID
+	}
+	return score
+}
+
+// devicePathParts splits a monitor device path such as
+// \\?\DISPLAY#DEL4123#5&2a2e2a3f&0&UID4352#{e6f07b5f-...} into its hardware
+// ID (DEL4123) and instance (5&2a2e2a3f&0&UID4352).
+func devicePathParts(path string) (string, string) {
+	parts := strings.Split(path, "#")
+	if len(parts) < 3 {
+		return "", ""
+	}
+	return parts[1], parts[2]
+}
+
+// matchMonitors assigns each profile path to at most one current target and
+// each current target to at most one profile path, best scores first.
+// Clone paths are separate monitors and are matched independently.
+func matchMonitors(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, additional []ccd.MonitorAdditionalInfo, targets []currentTarget) monitorMatch {
+	saved := make([]monitorIdentity, len(paths))
+	for i := range paths {
+		saved[i] = profileIdentity(&paths[i], modes, additional)
+	}
+	return matchIdentities(saved, targets)
+}
+
+// matchIdentities is matchMonitors for identities already extracted; the
+// result is keyed by index into saved.
+func matchIdentities(saved []monitorIdentity, targets []currentTarget) monitorMatch {
+	type candidate struct {
+		path   int
+		target int
+		score  int
+	}
+
+	var candidates []candidate
+	for i := range saved {
+		for j := range targets {
+			if score := scoreIdentity(saved[i], targets[j].identity); score > 0 {
+				candidates = append(candidates, candidate{path: i, target: j, score: score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].score > candidates[b].score
+	})
+
+	result := monitorMatch{
+		targets: make(map[int]currentTarget),
+		indices: make(map[int]int),
+		scores:  make(map[int]int),
+	}
+	pathTaken := make(map[int]bool)
+	targetTaken := make(map[int]bool)
+	for _, c := range candidates {
+		if pathTaken[c.path] || targetTaken[c.target] {
+			continue
+		}
+		for _, other := range candidates {
+			if other.score != c.score || other == c || pathTaken[other.path] || targetTaken[other.target] {
+				continue
+			}
+			if other.path == c.path {
+				result.ambiguous = append(result.ambiguous, fmt.Sprintf("%s matches both %s and %s equally well", saved[c.path], targets[c.target], targets[other.target]))
+				break
+			}
+			if other.target == c.target {
+				result.ambiguous = append(result.ambiguous, fmt.Sprintf("%s and %s both match %s equally well", saved[c.path], saved[other.path], targets[c.target]))
+				break
+			}
+		}
+		pathTaken[c.path] = true
+		targetTaken[c.target] = true
+		result.targets[c.path] = targets[c.target]
+		result.indices[c.path] = c.target
+		result.scores[c.path] = c.score
+	}
+
+	for i := range saved {
+		if !pathTaken[i] {
+			result.unmatched = append(result.unmatched, i)
+		}
+	}
+	return result
+}
+
+// report writes the match result: assignments to debug output, ambiguous
+// and unmatched monitors as warnings.
+func (m monitorMatch) report(log logger, paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, additional []ccd.MonitorAdditionalInfo) {
+	for i := range paths {
+		if target, ok := m.targets[i]; ok {
+			log.debugf("Monitor %s -> %s (score %d)", profileIdentity(&paths[i], modes, additional), target, m.scores[i])
+		}
+	}
+	for _, issue := range m.ambiguous {
+		log.warnf("ambiguous monitor match: %s", issue)
+	}
+	for _, i := range m.unmatched {
+		log.warnf("no connected monitor matches %s", profileIdentity(&paths[i], modes, additional))
+	}
+}
+
+// rebindToMatchedTargets moves every matched path, and the modes it
+// references, onto its matched target. Source IDs are kept when the new
+// adapter has them free; paths that shared a source keep sharing one. It
+// fails when an adapter has fewer free sources than the profile needs,
+// rather than turning separate desktops into clones.
+func rebindToMatchedTargets(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, match monitorMatch, currentPaths []ccd.DisplayConfigPathInfo) error {
+	type sourceKey struct {
+		adapter ccd.LUID
+		id      uint32
+	}
+	adapterSources := make(map[ccd.LUID][]uint32)
+	seenSource := make(map[sourceKey]bool)
+	for _, path := range currentPaths {
+		k := sourceKey{adapter: path.SourceInfo.AdapterID, id: path.SourceInfo.ID}
+		if !seenSource[k] {
+			seenSource[k] = true
+			adapterSources[k.adapter] = append(adapterSources[k.adapter], k.id)
+		}
+	}
+	for _, ids := range adapterSources {
+		sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	}
+
+	// newSource maps an old (adapter, source) pair to its source ID on the
+	// new adapter; usedSource tracks new sources already handed out.
+	newSource := make(map[[2]sourceKey]uint32)
+	usedSource := make(map[sourceKey]bool)
+	pickSource := func(old sourceKey, adapter ccd.LUID) (uint32, bool) {
+		k := [2]sourceKey{old, {adapter: adapter}}
+		if id, ok := newSource[k]; ok {
+			return id, true
+		}
+		id, found := old.id, false
+		if !usedSource[sourceKey{adapter: adapter, id: id}] && containsUint32(adapterSources[adapter], id) {
+			found = true
+		} else {
+			for _, candidate := range adapterSources[adapter] {
+				if !usedSource[sourceKey{adapter: adapter, id: candidate}] {
+					id, found = candidate, true
+					break
+				}
+			}
+		}
+		if !found {
+			return 0, false
+		}
+		newSource[k] = id
+		usedSource[sourceKey{adapter: adapter, id: id}] = true
+		return id, true
+	}
+
+	for i := range paths {
+		target, ok := match.targets[i]
+		if !ok {
+			continue
+		}
+		path := &paths[i]
+		sourceID, ok := pickSource(sourceKey{adapter: path.SourceInfo.AdapterID, id: path.SourceInfo.ID}, target.adapterID)
+		if !ok {
+			return fmt.Errorf("monitor %s: no free source on adapter %s", target.identity, formatAdapterID(target.adapterID))
+		}
+
+		path.SourceInfo.AdapterID = target.adapterID
+		path.SourceInfo.ID = sourceID
+		path.TargetInfo.AdapterID = target.adapterID
+		path.TargetInfo.ID = target.identity.targetID
+
+		if idx, ok := path.SourceModeIdx(); ok && idx < len(modes) {
+			modes[idx].AdapterID = target.adapterID
+			modes[idx].ID = sourceID
+		}
+		if idx, ok := path.TargetModeIdx(); ok && idx < len(modes) {
+			modes[idx].AdapterID = target.adapterID
+			modes[idx].ID = target.identity.targetID
+		}
+		if idx, ok := path.DesktopModeIdx(); ok && idx < len(modes) {
+			modes[idx].AdapterID = target.adapterID
+			modes[idx].ID = target.identity.targetID
+		}
+	}
+	return nil
+}
+
+func containsUint32(values []uint32, value uint32) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package switcher
+
+import (
+	"fmt"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// identityOf returns the identity a target with monitor reports.
+func identityOf(targetID uint32, manufacture, product uint16, name, devicePath string) monitorIdentity {
+	return monitorIdentity{
+		info: ccd.MonitorAdditionalInfo{
+			Valid:                 true,
+			ManufactureID:         manufacture,
+			ProductCodeID:         product,
+			MonitorFriendlyDevice: name,
+			MonitorDevicePath:     devicePath,
+		},
+		outputTechnology: ccd.DisplayConfigVideoOutputTechnologyDisplayPortExt,
+		targetID:         targetID,
+	}
+}
+
+func dellIdentity(targetID uint32, serial string) monitorIdentity {
+	monitor := dell(serial)
+	return identityOf(targetID, monitor.ManufactureID, monitor.ProductCodeID, monitor.FriendlyName, monitor.DevicePath)
+}
+
+func TestScoreIdentity(t *testing.T) {
+	lgMonitor := lg()
+	lgIdentity := identityOf(100, lgMonitor.ManufactureID, lgMonitor.ProductCodeID, lgMonitor.FriendlyName, lgMonitor.DevicePath)
+	noEDID := func(targetID uint32) monitorIdentity {
+		return monitorIdentity{outputTechnology: ccd.DisplayConfigVideoOutputTechnologyHdmi, targetID: targetID}
+	}
+
+	tests := []struct {
+		name  string
+		saved monitorIdentity
+		found monitorIdentity
+		want  int
+	}{
+		{
+			name:  "same monitor and target",
+			saved: dellIdentity(100, "UID4352"),
+			found: dellIdentity(100, "UID4352"),
+			want:  scoreDevicePath + scoreInstance + scoreEDID + scoreHardwareID + scoreFriendlyName + scoreOutputTech + scoreTargetID,
+		},
+		{
+			name:  "same monitor on a new target ID",
+			saved: dellIdentity(100, "UID4352"),
+			found: dellIdentity(200, "UID4352"),
+			want:  scoreDevicePath + scoreInstance + scoreEDID + scoreHardwareID + scoreFriendlyName + scoreOutputTech,
+		},
+		{
+			name:  "identical model, other serial",
+			saved: dellIdentity(100, "UID4352"),
+			found: dellIdentity(100, "UID4353"),
+			want:  scoreEDID + scoreHardwareID + scoreFriendlyName + scoreOutputTech + scoreTargetID,
+		},
+		{
+			name:  "different model",
+			saved: dellIdentity(100, "UID4352"),
+			found: lgIdentity,
+		},
+		{
+			name:  "no EDID, same target ID",
+			saved: noEDID(100),
+			found: noEDID(100),
+			want:  scoreTargetID + scoreOutputTech,
+		},
+		{
+			name:  "no EDID, other target ID",
+			saved: noEDID(100),
+			found: noEDID(200),
+		},
+		{
+			name:  "saved without EDID, found with",
+			saved: noEDID(100),
+			found: dellIdentity(100, "UID4352"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scoreIdentity(tt.saved, tt.found); got != tt.want {
+				t.Errorf("scoreIdentity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDevicePathParts(t *testing.T) {
+	tests := []struct {
+		path, hardware, instance string
+	}{
+		{dell("UID4352").DevicePath, "DEL4123", "5&1a&0&UID4352"},
+		{`\\?\DISPLAY#DEL4123`, "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		hardware, instance := devicePathParts(tt.path)
+		if hardware != tt.hardware || instance != tt.instance {
+			t.Errorf("devicePathParts(%q) = %q, %q; want %q, %q", tt.path, hardware, instance, tt.hardware, tt.instance)
+		}
+	}
+}
+
+func TestMatchIdentities(t *testing.T) {
+	luid := ccd.LUID{LowPart: 1}
+	current := func(identities ...monitorIdentity) []currentTarget {
+		targets := make([]currentTarget, len(identities))
+		for i, identity := range identities {
+			targets[i] = currentTarget{adapterID: luid, identity: identity}
+		}
+		return targets
+	}
+
+	tests := []struct {
+		name          string
+		saved         []monitorIdentity
+		targets       []currentTarget
+		want          map[int]uint32
+		wantUnmatched []int
+		wantAmbiguous int
+	}{
+		{
+			name:    "target IDs swapped",
+			saved:   []monitorIdentity{dellIdentity(100, "UID4352"), dellIdentity(200, "UID4353")},
+			targets: current(dellIdentity(100, "UID4353"), dellIdentity(200, "UID4352")),
+			want:    map[int]uint32{0: 200, 1: 100},
+		},
+		{
+			name:          "one monitor missing",
+			saved:         []monitorIdentity{dellIdentity(100, "UID4352"), dellIdentity(200, "UID4353")},
+			targets:       current(dellIdentity(300, "UID4353")),
+			want:          map[int]uint32{1: 300},
+			wantUnmatched: []int{0},
+		},
+		{
+			name:          "identical monitors with new serials",
+			saved:         []monitorIdentity{dellIdentity(100, "UID1"), dellIdentity(200, "UID2")},
+			targets:       current(dellIdentity(300, "UID3"), dellIdentity(400, "UID4")),
+			want:          map[int]uint32{0: 300, 1: 400},
+			wantAmbiguous: 1,
+		},
+		{
+			name:          "other model",
+			saved:         []monitorIdentity{dellIdentity(100, "UID4352")},
+			targets:       current(identityOf(100, 0x6D1E, 0x7707, "LG HDR 4K", "")),
+			want:          map[int]uint32{},
+			wantUnmatched: []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := matchIdentities(tt.saved, tt.targets)
+			got := make(map[int]uint32)
+			for i, target := range match.targets {
+				got[i] = target.identity.targetID
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("targets = %v, want %v", got, tt.want)
+			}
+			if fmt.Sprint(match.unmatched) != fmt.Sprint(tt.wantUnmatched) {
+				t.Errorf("unmatched = %v, want %v", match.unmatched, tt.wantUnmatched)
+			}
+			if len(match.ambiguous) != tt.wantAmbiguous {
+				t.Errorf("ambiguous = %q, want %d entries", match.ambiguous, tt.wantAmbiguous)
+			}
+		})
+	}
+}
+
+func TestRebindToMatchedTargets(t *testing.T) {
+	source, target := ccd.DisplayConfigModeInfoTypeSource, ccd.DisplayConfigModeInfoTypeTarget
+	to := func(luid uint32, targetID uint32) currentTarget {
+		return currentTarget{adapterID: ccd.LUID{LowPart: luid}, identity: monitorIdentity{targetID: targetID}}
+	}
+	// available lists one all-paths query entry per (source, target) pair.
+	available := func(luid uint32, sources []uint32, targets ...uint32) []ccd.DisplayConfigPathInfo {
+		var paths []ccd.DisplayConfigPathInfo
+		for _, s := range sources {
+			for _, t := range targets {
+				paths = append(paths, testPath(luid, s, t, -1, -1))
+			}
+		}
+		return paths
+	}
+
+	tests := []struct {
+		name    string
+		paths   []ccd.DisplayConfigPathInfo
+		modes   []ccd.DisplayConfigModeInfo
+		match   map[int]currentTarget
+		current []ccd.DisplayConfigPathInfo
+		// want lists "source->target" per path, on adapter 2.
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "sources kept on the new adapter",
+			paths:   []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1), testPath(1, 1, 200, 2, 3)},
+			modes:   []ccd.DisplayConfigModeInfo{testMode(source, 1, 0), testMode(target, 1, 100), testMode(source, 1, 1), testMode(target, 1, 200)},
+			match:   map[int]currentTarget{0: to(2, 201), 1: to(2, 101)},
+			current: available(2, []uint32{0, 1}, 101, 201),
+			want:    []string{"0->201", "1->101"},
+		},
+		{
+			name:    "clones keep sharing a source",
+			paths:   []ccd.DisplayConfigPathInfo{testPath(1, 3, 100, 0, 1), testPath(1, 3, 200, 0, 2)},
+			modes:   []ccd.DisplayConfigModeInfo{testMode(source, 1, 3), testMode(target, 1, 100), testMode(target, 1, 200)},
+			match:   map[int]currentTarget{0: to(2, 101), 1: to(2, 201)},
+			current: available(2, []uint32{0, 1}, 101, 201),
+			want:    []string{"0->101", "0->201"},
+		},
+		{
+			name:    "missing source replaced by a free one",
+			paths:   []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1), testPath(1, 5, 200, 2, 3)},
+			modes:   []ccd.DisplayConfigModeInfo{testMode(source, 1, 0), testMode(target, 1, 100), testMode(source, 1, 5), testMode(target, 1, 200)},
+			match:   map[int]currentTarget{0: to(2, 101), 1: to(2, 201)},
+			current: available(2, []uint32{0, 1}, 101, 201),
+			want:    []string{"0->101", "1->201"},
+		},
+		{
+			name:    "no free source",
+			paths:   []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1), testPath(1, 1, 200, 2, 3)},
+			modes:   []ccd.DisplayConfigModeInfo{testMode(source, 1, 0), testMode(target, 1, 100), testMode(source, 1, 1), testMode(target, 1, 200)},
+			match:   map[int]currentTarget{0: to(2, 101), 1: to(2, 201)},
+			current: available(2, []uint32{0}, 101, 201),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rebindToMatchedTargets(tt.paths, tt.modes, monitorMatch{targets: tt.match}, tt.current)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rebindToMatchedTargets() = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []string
+			for _, path := range tt.paths {
+				got = append(got, fmt.Sprintf("%d->%d", path.SourceInfo.ID, path.TargetInfo.ID))
+				if path.SourceInfo.AdapterID.LowPart != 2 || path.TargetInfo.AdapterID.LowPart != 2 {
+					t.Errorf("path %d->%d kept its old adapter", path.SourceInfo.ID, path.TargetInfo.ID)
+				}
+				sourceIdx, _ := path.SourceModeIdx()
+				targetIdx, _ := path.TargetModeIdx()
+				if mode := tt.modes[sourceIdx]; mode.ID != path.SourceInfo.ID || mode.AdapterID.LowPart != 2 {
+					t.Errorf("source mode %+v not moved with path %d->%d", mode, path.SourceInfo.ID, path.TargetInfo.ID)
+				}
+				if mode := tt.modes[targetIdx]; mode.ID != path.TargetInfo.ID || mode.AdapterID.LowPart != 2 {
+					t.Errorf("target mode %+v not moved with path %d->%d", mode, path.SourceInfo.ID, path.TargetInfo.ID)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("paths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMatchesMonitorsByIdentity(t *testing.T) {
+	path := saveProfile(t, twin(1, 100, 200, true), SaveOptions{})
+
+	// After a reboot the adapter has a new LUID and the monitors swapped
+	// target IDs.
+	m := twin(7, 200, 100, false)
+	report, err := New(m).LoadProfileWithReport(path, quiet(LoadOptions{}))
+	if err != nil {
+		t.Fatalf("LoadProfile() = %v", err)
+	}
+	if applied, _ := report.Applied(); applied.Strategy != StrategyIdentity {
+		t.Errorf("applied strategy %q, want %q", applied.Strategy, StrategyIdentity)
+	}
+	// UID4352 was on the left as target 100 and is now target 200.
+	want := map[uint32]int32{200: 0, 100: 1920}
+	if got := layout(m); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("layout = %v, want %v", got, want)
+	}
+}
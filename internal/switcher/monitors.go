@@ -0,0 +1,359 @@
+package switcher
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/profile"
+)
+
+// scalings are the values a per-monitor profile can name.
+var scalings = []ccd.DisplayConfigScaling{
+	ccd.DisplayConfigScalingIdentity,
+	ccd.DisplayConfigScalingCentered,
+	ccd.DisplayConfigScalingStretched,
+	ccd.DisplayConfigScalingAspectRatioCenteredMax,
+	ccd.DisplayConfigScalingCustom,
+	ccd.DisplayConfigScalingPreferred,
+}
+
+func scalingName(scaling ccd.DisplayConfigScaling) string {
+	for _, known := range scalings {
+		if scaling == known {
+			return scaling.String()
+		}
+	}
+	return ccd.DisplayConfigScalingPreferred.String()
+}
+
+func parseScaling(name string) (ccd.DisplayConfigScaling, error) {
+	if name == "" {
+		return ccd.DisplayConfigScalingPreferred, nil
+	}
+	for _, scaling := range scalings {
+		if strings.EqualFold(name, scaling.String()) {
+			return scaling, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown scaling %q", name)
+}
+
+func rotationDegrees(rotation ccd.DisplayConfigRotation) int {
+	switch rotation {
+	case ccd.DisplayConfigRotationRotate90:
+		return 90
+	case ccd.DisplayConfigRotationRotate180:
+		return 180
+	case ccd.DisplayConfigRotationRotate270:
+		return 270
+	}
+	return 0
+}
+
+// rotatesQuarter reports whether rotation swaps width and height.
+func rotatesQuarter(rotation ccd.DisplayConfigRotation) bool {
+	return rotation == ccd.DisplayConfigRotationRotate90 || rotation == ccd.DisplayConfigRotationRotate270
+}
+
+func parseRotation(degrees int) (ccd.DisplayConfigRotation, error) {
+	switch degrees {
+	case 0:
+		return ccd.DisplayConfigRotationIdentity, nil
+	case 90:
+		return ccd.DisplayConfigRotationRotate90, nil
+	case 180:
+		return ccd.DisplayConfigRotationRotate180, nil
+	case 270:
+		return ccd.DisplayConfigRotationRotate270, nil
+	}
+	return 0, fmt.Errorf("rotation must be 0, 90, 180 or 270, not %d", degrees)
+}
+
+// refreshHz converts a refresh rate to Hz rounded to three decimals.
+func refreshHz(r ccd.DisplayConfigRational) float64 {
+	if r.Denominator == 0 {
+		return 0
+	}
+	return math.Round(float64(r.Numerator)/float64(r.Denominator)*1000) / 1000
+}
+
+// rationalFromHz converts Hz to a reduced rational with millihertz
+// precision.
+func rationalFromHz(hz float64) ccd.DisplayConfigRational {
+	if hz <= 0 {
+		return ccd.DisplayConfigRational{}
+	}
+	num := uint32(math.Round(hz * 1000))
+	den := uint32(1000)
+	for a, b := num, den; ; {
+		if b == 0 {
+			return ccd.DisplayConfigRational{Numerator: num / a, Denominator: den / a}
+		}
+		a, b = b, a%b
+	}
+}
+
+// monitorsFromCCD describes every active path as a per-monitor profile entry.
+func monitorsFromCCD(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, additional []ccd.MonitorAdditionalInfo) []profile.Monitor {
+	var monitors []profile.Monitor
+	for i := range paths {
+		path := &paths[i]
+		if path.Flags&uint32(ccd.DisplayConfigFlagPathActive) == 0 {
+			continue
+		}
+		identity := profileIdentity(path, modes, additional)
+		monitor := profile.Monitor{
+			TargetID:         path.TargetInfo.ID,
+			OutputTechnology: uint32(path.TargetInfo.OutputTechnology),
+			Enabled:          true,
+			Rotation:         rotationDegrees(path.TargetInfo.Rotation),
+			Scaling:          scalingName(path.TargetInfo.Scaling),
+		}
+		if identity.info.Valid {
+			monitor.Name = identity.info.MonitorFriendlyDevice
+			monitor.DevicePath = identity.info.MonitorDevicePath
+			monitor.ManufactureID = identity.info.ManufactureID
+			monitor.ProductCodeID = identity.info.ProductCodeID
+			monitor.ConnectorInstance = identity.info.ConnectorInstance
+		}
+		if idx, ok := path.SourceModeIdx(); ok && idx < len(modes) && modes[idx].InfoType == ccd.DisplayConfigModeInfoTypeSource {
+			source := modes[idx].SourceMode()
+			monitor.Width, monitor.Height = source.Width, source.Height
+			if rotatesQuarter(path.TargetInfo.Rotation) {
+				monitor.Width, monitor.Height = source.Height, source.Width
+			}
+			monitor.Position = profile.PointL{X: source.Position.X, Y: source.Position.Y}
+			monitor.Primary = source.Position.X == 0 && source.Position.Y == 0
+		}
+		monitor.RefreshHz = refreshHz(path.TargetInfo.RefreshRate)
+		if monitor.RefreshHz == 0 {
+			if idx, ok := path.TargetModeIdx(); ok && idx < len(modes) && modes[idx].InfoType == ccd.DisplayConfigModeInfoTypeTarget {
+				monitor.RefreshHz = refreshHz(modes[idx].TargetMode().TargetVideoSignalInfo.VSyncFreq)
+			}
+		}
+		monitors = append(monitors, monitor)
+	}
+	return monitors
+}
+
+// monitorIdentityOf returns the identity a per-monitor entry asks for.
+func monitorIdentityOf(monitor profile.Monitor) monitorIdentity {
+	return monitorIdentity{
+		info: ccd.MonitorAdditionalInfo{
+			ManufactureID:         monitor.ManufactureID,
+			ProductCodeID:         monitor.ProductCodeID,
+			Valid:                 true,
+			MonitorDevicePath:     monitor.DevicePath,
+			MonitorFriendlyDevice: monitor.Name,
+			ConnectorInstance:     monitor.ConnectorInstance,
+		},
+		outputTechnology: ccd.DisplayConfigVideoOutputTechnology(monitor.OutputTechnology),
+		targetID:         monitor.TargetID,
+	}
+}
+
+// compileMonitors turns a per-monitor profile into CCD arrays for the
+// running machine. Each enabled monitor is matched to a connected target by
+// identity and given a source on that target's adapter (monitors with the
+// same position and size share one, as clones). Target timings are reused
+// from the live configuration when the target already runs the requested
+// mode; otherwise the target mode is left out and Windows picks timings
+// for the requested resolution and refresh rate.
+func (s *Switcher) compileMonitors(log logger, monitors []profile.Monitor) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo, []ccd.MonitorAdditionalInfo, error) {
+	var enabled []profile.Monitor
+	var identities []monitorIdentity
+	primary := -1
+	for i, monitor := range monitors {
+		if !monitor.Enabled {
+			continue
+		}
+		if monitor.Name == "" && monitor.DevicePath == "" && monitor.ManufactureID == 0 && monitor.ProductCodeID == 0 {
+			return nil, nil, nil, fmt.Errorf("monitor %d: set name, devicePath or manufactureId/productCodeId to identify it", i+1)
+		}
+		if monitor.Width == 0 || monitor.Height == 0 {
+			return nil, nil, nil, fmt.Errorf("monitor %d (%s): width and height are required", i+1, monitor.Name)
+		}
+		if monitor.Primary {
+			if primary >= 0 {
+				return nil, nil, nil, errors.New("more than one monitor is marked primary")
+			}
+			primary = len(enabled)
+		}
+		enabled = append(enabled, monitor)
+		identities = append(identities, monitorIdentityOf(monitor))
+	}
+	if len(enabled) == 0 {
+		return nil, nil, nil, errors.New("no monitor in the profile is enabled")
+	}
+
+	currentPaths, currentModes, _, err := ccd.GetDisplaySettings(s.backend, false)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("get current display settings: %w", err)
+	}
+	match := matchIdentities(identities, currentTargets(s.backend, currentPaths))
+	for i := range enabled {
+		if target, ok := match.targets[i]; ok {
+			log.debugf("Monitor %s -> %s (score %d)", identities[i], target, match.scores[i])
+		}
+	}
+	for _, issue := range match.ambiguous {
+		log.warnf("ambiguous monitor match: %s", issue)
+	}
+	for _, i := range match.unmatched {
+		log.warnf("monitor %s is not connected; skipping it", identities[i])
+	}
+	if len(match.targets) == 0 {
+		return nil, nil, nil, fmt.Errorf("none of the %d enabled monitors in the profile are connected", len(enabled))
+	}
+
+	var offset profile.PointL
+	if primary >= 0 {
+		if _, ok := match.targets[primary]; ok {
+			offset = profile.PointL{X: -enabled[primary].Position.X, Y: -enabled[primary].Position.Y}
+		}
+	}
+
+	type deviceKey struct {
+		adapter ccd.LUID
+		id      uint32
+	}
+	type routeKey struct {
+		adapter  ccd.LUID
+		sourceID uint32
+		targetID uint32
+	}
+	routes := make(map[routeKey]ccd.DisplayConfigPathInfo)
+	activeRoute := make(map[deviceKey]ccd.DisplayConfigPathInfo)
+	adapterSources := make(map[ccd.LUID][]uint32)
+	for _, path := range currentPaths {
+		key := routeKey{adapter: path.TargetInfo.AdapterID, sourceID: path.SourceInfo.ID, targetID: path.TargetInfo.ID}
+		if _, seen := routes[key]; !seen {
+			routes[key] = path
+		}
+		if path.Flags&uint32(ccd.DisplayConfigFlagPathActive) != 0 {
+			activeRoute[deviceKey{adapter: path.TargetInfo.AdapterID, id: path.TargetInfo.ID}] = path
+		}
+		if !containsUint32(adapterSources[path.SourceInfo.AdapterID], path.SourceInfo.ID) {
+			adapterSources[path.SourceInfo.AdapterID] = append(adapterSources[path.SourceInfo.AdapterID], path.SourceInfo.ID)
+		}
+	}
+
+	type cloneKey struct {
+		adapter  ccd.LUID
+		position profile.PointL
+		width    uint32
+		height   uint32
+	}
+	groupSource := make(map[cloneKey]uint32)
+	groupMode := make(map[cloneKey]int)
+	usedSource := make(map[deviceKey]bool)
+
+	var paths []ccd.DisplayConfigPathInfo
+	var modes []ccd.DisplayConfigModeInfo
+	var additional []ccd.MonitorAdditionalInfo
+	for i, monitor := range enabled {
+		target, ok := match.targets[i]
+		if !ok {
+			continue
+		}
+		rotation, err := parseRotation(monitor.Rotation)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("monitor %s: %w", identities[i], err)
+		}
+		scaling, err := parseScaling(monitor.Scaling)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("monitor %s: %w", identities[i], err)
+		}
+		adapter := target.adapterID
+		targetID := target.identity.targetID
+		position := profile.PointL{X: monitor.Position.X + offset.X, Y: monitor.Position.Y + offset.Y}
+		// Width and height are the monitor's resolution; the desktop area
+		// (source mode) turns with the monitor.
+		desktopWidth, desktopHeight := monitor.Width, monitor.Height
+		if rotatesQuarter(rotation) {
+			desktopWidth, desktopHeight = monitor.Height, monitor.Width
+		}
+		group := cloneKey{adapter: adapter, position: position, width: desktopWidth, height: desktopHeight}
+
+		current, isActive := activeRoute[deviceKey{adapter: adapter, id: targetID}]
+		sourceID, grouped := groupSource[group]
+		if _, routed := routes[routeKey{adapter: adapter, sourceID: sourceID, targetID: targetID}]; grouped && !routed {
+			grouped = false
+		}
+		if !grouped {
+			candidates := adapterSources[adapter]
+			if isActive {
+				candidates = append([]uint32{current.SourceInfo.ID}, candidates...)
+			}
+			found := false
+			for _, candidate := range candidates {
+				if usedSource[deviceKey{adapter: adapter, id: candidate}] {
+					continue
+				}
+				if _, routed := routes[routeKey{adapter: adapter, sourceID: candidate, targetID: targetID}]; routed {
+					sourceID = candidate
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, nil, nil, fmt.Errorf("monitor %s: no free source on adapter %s", identities[i], formatAdapterID(adapter))
+			}
+			usedSource[deviceKey{adapter: adapter, id: sourceID}] = true
+		}
+
+		path := routes[routeKey{adapter: adapter, sourceID: sourceID, targetID: targetID}]
+		path.Flags = uint32(ccd.DisplayConfigFlagPathActive)
+		path.TargetInfo.Rotation = rotation
+		path.TargetInfo.Scaling = scaling
+		path.TargetInfo.RefreshRate = rationalFromHz(monitor.RefreshHz)
+
+		var sourceIdx int
+		if grouped {
+			sourceIdx = groupMode[group]
+		} else {
+			pixelFormat := ccd.DisplayConfigPixelFormat32Bpp
+			if isActive {
+				if idx, ok := current.SourceModeIdx(); ok && idx < len(currentModes) && currentModes[idx].InfoType == ccd.DisplayConfigModeInfoTypeSource {
+					pixelFormat = currentModes[idx].SourceMode().PixelFormat
+				}
+			}
+			mode := ccd.DisplayConfigModeInfo{InfoType: ccd.DisplayConfigModeInfoTypeSource, ID: sourceID, AdapterID: adapter}
+			mode.SetSourceMode(ccd.DisplayConfigSourceMode{
+				Width:       desktopWidth,
+				Height:      desktopHeight,
+				PixelFormat: pixelFormat,
+				Position:    ccd.PointL{X: position.X, Y: position.Y},
+			})
+			sourceIdx = len(modes)
+			modes = append(modes, mode)
+			additional = append(additional, ccd.MonitorAdditionalInfo{})
+			groupSource[group] = sourceID
+			groupMode[group] = sourceIdx
+		}
+		path.SourceInfo.ModeInfoIdx = uint32(sourceIdx)
+		path.TargetInfo.ModeInfoIdx = ccd.DisplayConfigPathModeIdxInvalid
+
+		// Reuse the live timings when the target already shows this mode.
+		if isActive {
+			if idx, ok := current.TargetModeIdx(); ok && idx < len(currentModes) && currentModes[idx].InfoType == ccd.DisplayConfigModeInfoTypeTarget {
+				signal := currentModes[idx].TargetMode().TargetVideoSignalInfo
+				sameRate := monitor.RefreshHz == 0 || sameRefreshRate(signal.VSyncFreq, rationalFromHz(monitor.RefreshHz))
+				if signal.ActiveSize.Cx == monitor.Width && signal.ActiveSize.Cy == monitor.Height && sameRate {
+					path.TargetInfo.ModeInfoIdx = uint32(len(modes))
+					path.TargetInfo.RefreshRate = signal.VSyncFreq
+					modes = append(modes, currentModes[idx])
+					additional = append(additional, target.identity.info)
+				}
+			}
+		}
+		if path.TargetInfo.ModeInfoIdx == ccd.DisplayConfigPathModeIdxInvalid {
+			log.debugf("Monitor %s: no live timings for %dx%d, letting Windows choose", identities[i], monitor.Width, monitor.Height)
+		}
+		paths = append(paths, path)
+	}
+	return paths, modes, additional, nil
+}
@@ -0,0 +1,253 @@
+package switcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/profile"
+)
+
+// dellMonitor is a per-monitor entry for the DELL with the given serial at
+// (x, 0), running width x height at 60 Hz.
+func dellMonitor(serial string, x int32, width, height uint32) profile.Monitor {
+	monitor := dell(serial)
+	return profile.Monitor{
+		Name:          monitor.FriendlyName,
+		DevicePath:    monitor.DevicePath,
+		ManufactureID: monitor.ManufactureID,
+		ProductCodeID: monitor.ProductCodeID,
+		Enabled:       true,
+		Width:         width,
+		Height:        height,
+		RefreshHz:     60,
+		Position:      profile.PointL{X: x},
+	}
+}
+
+func TestSameRefreshRate(t *testing.T) {
+	rate := func(num, den uint32) ccd.DisplayConfigRational {
+		return ccd.DisplayConfigRational{Numerator: num, Denominator: den}
+	}
+	tests := []struct {
+		a, b ccd.DisplayConfigRational
+		want bool
+	}{
+		{rate(60, 1), rate(60000, 1000), true},
+		{rate(60, 1), rate(59940, 1000), false},
+		{rate(148500000, 2475000), rate(60, 1), true},
+		{rate(0, 0), rate(0, 0), true},
+		{rate(0, 0), rate(60, 1), false},
+		{rate(60, 1), rate(0, 0), false},
+		{rate(60, 0), rate(0, 0), false},
+	}
+	for _, tt := range tests {
+		if got := sameRefreshRate(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameRefreshRate(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCompileMonitors(t *testing.T) {
+	edit := func(monitor profile.Monitor, change func(*profile.Monitor)) profile.Monitor {
+		change(&monitor)
+		return monitor
+	}
+	other := profile.Monitor{Name: "LG HDR 4K", ManufactureID: 0x6D1E, ProductCodeID: 0x7707, Enabled: true, Width: 3840, Height: 2160}
+
+	tests := []struct {
+		name     string
+		monitors []profile.Monitor
+		// want describes each compiled path as "target: source, desktop
+		// size and position, and whether the live target timings are
+		// reused or Windows picks them".
+		want    []string
+		wantErr string
+	}{
+		{
+			name:     "as running",
+			monitors: []profile.Monitor{dellMonitor("UID4352", 0, 1920, 1080), dellMonitor("UID4353", 1920, 1920, 1080)},
+			want:     []string{"100: source 0 1920x1080 @ (0,0) live", "200: source 1 1920x1080 @ (1920,0) live"},
+		},
+		{
+			name:     "positions swapped",
+			monitors: []profile.Monitor{dellMonitor("UID4352", 1920, 1920, 1080), dellMonitor("UID4353", 0, 1920, 1080)},
+			want:     []string{"100: source 0 1920x1080 @ (1920,0) live", "200: source 1 1920x1080 @ (0,0) live"},
+		},
+		{
+			name: "primary moved to the origin",
+			monitors: []profile.Monitor{
+				edit(dellMonitor("UID4352", 100, 1920, 1080), func(m *profile.Monitor) { m.Primary, m.Position.Y = true, 50 }),
+				edit(dellMonitor("UID4353", 2020, 1920, 1080), func(m *profile.Monitor) { m.Position.Y = 50 }),
+			},
+			want: []string{"100: source 0 1920x1080 @ (0,0) live", "200: source 1 1920x1080 @ (1920,0) live"},
+		},
+		{
+			name:     "rotated desktop",
+			monitors: []profile.Monitor{edit(dellMonitor("UID4352", 0, 1920, 1080), func(m *profile.Monitor) { m.Rotation = 90 })},
+			want:     []string{"100: source 0 1080x1920 @ (0,0) live"},
+		},
+		{
+			name:     "new resolution",
+			monitors: []profile.Monitor{dellMonitor("UID4352", 0, 1280, 720)},
+			want:     []string{"100: source 0 1280x720 @ (0,0) windows"},
+		},
+		{
+			name:     "new refresh rate",
+			monitors: []profile.Monitor{edit(dellMonitor("UID4352", 0, 1920, 1080), func(m *profile.Monitor) { m.RefreshHz = 75 })},
+			want:     []string{"100: source 0 1920x1080 @ (0,0) windows"},
+		},
+		{
+			name:     "any refresh rate",
+			monitors: []profile.Monitor{edit(dellMonitor("UID4352", 0, 1920, 1080), func(m *profile.Monitor) { m.RefreshHz = 0 })},
+			want:     []string{"100: source 0 1920x1080 @ (0,0) live"},
+		},
+		{
+			name:     "clones share a source",
+			monitors: []profile.Monitor{dellMonitor("UID4352", 0, 1920, 1080), dellMonitor("UID4353", 0, 1920, 1080)},
+			want:     []string{"100: source 0 1920x1080 @ (0,0) live", "200: source 0 1920x1080 @ (0,0) live"},
+		},
+		{
+			name:     "disconnected and disabled monitors skipped",
+			monitors: []profile.Monitor{other, dellMonitor("UID4352", 0, 1920, 1080), edit(dellMonitor("UID4353", 1920, 1920, 1080), func(m *profile.Monitor) { m.Enabled = false })},
+			want:     []string{"100: source 0 1920x1080 @ (0,0) live"},
+		},
+		{
+			name:     "no size",
+			monitors: []profile.Monitor{dellMonitor("UID4352", 0, 1920, 0)},
+			wantErr:  "width and height are required",
+		},
+		{
+			name:     "no identity",
+			monitors: []profile.Monitor{{Enabled: true, Width: 1920, Height: 1080}},
+			wantErr:  "set name, devicePath or manufactureId/productCodeId",
+		},
+		{
+			name: "two primaries",
+			monitors: []profile.Monitor{
+				edit(dellMonitor("UID4352", 0, 1920, 1080), func(m *profile.Monitor) { m.Primary = true }),
+				edit(dellMonitor("UID4353", 1920, 1920, 1080), func(m *profile.Monitor) { m.Primary = true }),
+			},
+			wantErr: "more than one monitor is marked primary",
+		},
+		{
+			name:     "bad rotation",
+			monitors: []profile.Monitor{edit(dellMonitor("UID4352", 0, 1920, 1080), func(m *profile.Monitor) { m.Rotation = 45 })},
+			wantErr:  "rotation must be 0, 90, 180 or 270",
+		},
+		{
+			name:     "none enabled",
+			monitors: []profile.Monitor{edit(dellMonitor("UID4352", 0, 1920, 1080), func(m *profile.Monitor) { m.Enabled = false })},
+			wantErr:  "no monitor in the profile is enabled",
+		},
+		{
+			name:     "none connected",
+			monitors: []profile.Monitor{other},
+			wantErr:  "none of the 1 enabled monitors",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, modes, additional, err := New(twin(1, 100, 200, true)).compileMonitors(quiet(LoadOptions{}).logger(), tt.monitors)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("compileMonitors() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compileMonitors() = %v", err)
+			}
+			if len(additional) != len(modes) {
+				t.Errorf("%d additional entries for %d modes", len(additional), len(modes))
+			}
+			var got []string
+			for _, path := range paths {
+				source := modes[path.SourceInfo.ModeInfoIdx].SourceMode()
+				timings := "live"
+				if path.TargetInfo.ModeInfoIdx == ccd.DisplayConfigPathModeIdxInvalid {
+					timings = "windows"
+				}
+				got = append(got, fmt.Sprintf("%d: source %d %dx%d @ (%d,%d) %s", path.TargetInfo.ID, path.SourceInfo.ID,
+					source.Width, source.Height, source.Position.X, source.Position.Y, timings))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("compiled\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+			}
+		})
+	}
+}
+
+func TestLoadPerMonitorProfile(t *testing.T) {
+	for _, ext := range []string{".monitorprofile", ".yaml", ".toml"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "desk"+ext)
+			if err := New(twin(1, 100, 200, true)).SaveProfile(path, SaveOptions{Monitors: true}); err != nil {
+				t.Fatal(err)
+			}
+			prof, err := profile.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !prof.IsLayout() {
+				t.Fatal("saved profile is not in the per-monitor format")
+			}
+			if issues := profile.Validate(prof); len(issues) > 0 {
+				t.Errorf("saved profile does not validate: %v", issues)
+			}
+
+			// A new resolution with no refresh rate leaves the rate to
+			// Windows, which is not drift.
+			prof.Monitors[1].Width, prof.Monitors[1].Height, prof.Monitors[1].RefreshHz = 1280, 720, 0
+			if err := profile.Save(path, prof, profile.SaveOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			m := twin(5, 100, 200, false)
+			if err := New(m).LoadProfile(path, quiet(LoadOptions{Strict: true})); err != nil {
+				t.Fatalf("LoadProfile() = %v", err)
+			}
+			want := map[uint32]int32{100: 0, 200: 1920}
+			if got := layout(m); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("layout = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSaveProfileWithNoActivePaths(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.monitorprofile")
+	if err := New(twin(1, 100, 200, false)).SaveProfile(path, SaveOptions{}); err == nil {
+		t.Error("SaveProfile() with no active paths succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("profile written: %v", err)
+	}
+}
+
+func TestLoadPerMonitorProfileWaitsBeforeCompiling(t *testing.T) {
+	adapter := ccd.LUID{LowPart: 1}
+	m := twin(1, 100, 200, true)
+	path := filepath.Join(t.TempDir(), "desk.monitorprofile")
+	if err := New(m).SaveProfile(path, SaveOptions{Monitors: true}); err != nil {
+		t.Fatal(err)
+	}
+	m.SetPresent(adapter, 200, false)
+
+	// Compiled before the wait, the profile would have dropped target 200.
+	backend := &hotplug{Machine: m, adapter: adapter, target: 200, after: 2}
+	var out strings.Builder
+	if err := New(backend).LoadProfile(path, LoadOptions{Wait: 10 * time.Second, Output: &out}); err != nil {
+		t.Fatalf("LoadProfile() = %v", err)
+	}
+	if !strings.Contains(out.String(), "1 of 2 monitors not connected: DELL P2419H") {
+		t.Errorf("output %q does not report the missing monitor", out.String())
+	}
+	want := map[uint32]int32{100: 0, 200: 1920}
+	if got := layout(m); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("layout = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+package switcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/profile"
+)
+
+// DocumentVersion is the version of the JSON output format. It changes only
+// when a field is removed or changes meaning; new fields may be added.
+const DocumentVersion = 1
+
+// Document is what a command prints with -format:json, one per command.
+type Document struct {
+	Version  int    `json:"version"`
+	Command  string `json:"command"`
+	OK       bool   `json:"ok"`
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+	// Warnings are the warnings the command would print to stderr.
+	Warnings []string `json:"warnings"`
+	// Profile is the profile file the command read or wrote.
+	Profile string `json:"profile,omitempty"`
+	// Monitors is the configuration the command printed, saved or left
+	// active.
+	Monitors []MonitorState `json:"monitors,omitempty"`
+	// Result is specific to the command.
+	Result any `json:"result,omitempty"`
+}
+
+// Write prints the document as indented JSON.
+func (d Document) Write(w io.Writer) error {
+	if d.Warnings == nil {
+		d.Warnings = []string{}
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return fmt.Errorf("serialize output: %w", err)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Point is a desktop coordinate.
+type Point struct {
+	X int32 `json:"x"`
+	Y int32 `json:"y"`
+}
+
+// MonitorState describes one path (or, for per-monitor profiles, one
+// monitor entry) with its enums decoded.
+type MonitorState struct {
+	// Path is the index in the path array or monitor list.
+	Path          int    `json:"path"`
+	Active        bool   `json:"active"`
+	Available     bool   `json:"available"`
+	Primary       bool   `json:"primary"`
+	Name          string `json:"name"`
+	DevicePath    string `json:"devicePath"`
+	ManufactureID uint16 `json:"manufactureId"`
+	ProductCodeID uint16 `json:"productCodeId"`
+	// Adapter is the adapter LUID as HIGH:LOW hex; empty for per-monitor
+	// profiles.
+	Adapter          string `json:"adapter"`
+	SourceID         uint32 `json:"sourceId"`
+	TargetID         uint32 `json:"targetId"`
+	OutputTechnology string `json:"outputTechnology"`
+	// Width and Height are the desktop area, i.e. the source mode; they are
+	// swapped relative to the signal for 90 and 270 degree rotations.
+	Width            uint32  `json:"width"`
+	Height           uint32  `json:"height"`
+	Position         Point   `json:"position"`
+	PixelFormat      string  `json:"pixelFormat"`
+	RefreshHz        float64 `json:"refreshHz"`
+	ActiveWidth      uint32  `json:"activeWidth"`
+	ActiveHeight     uint32  `json:"activeHeight"`
+	ScanLineOrdering string  `json:"scanLineOrdering"`
+	Rotation         int     `json:"rotation"`
+	Scaling          string  `json:"scaling"`
+	VirtualMode      bool    `json:"virtualMode"`
+	// CloneGroup is set for virtual-mode-aware paths that have one.
+	CloneGroup *uint32 `json:"cloneGroup,omitempty"`
+}
+
+// CurrentMonitors describes the active configuration.
+func (s *Switcher) CurrentMonitors() ([]MonitorState, error) {
+	paths, modes, additional, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsOnlyActivePaths|ccd.QueryDisplayFlagsVirtualModeAware)
+	if err != nil {
+		paths, modes, additional, err = ccd.GetDisplaySettings(s.backend, true)
+		if err != nil {
+			return nil, fmt.Errorf("get display settings: %w", err)
+		}
+	}
+	return monitorStates(paths, modes, additional), nil
+}
+
+// ProfileMonitors describes the profile at path.
+func ProfileMonitors(path string) ([]MonitorState, error) {
+	prof, err := profile.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if prof.IsLayout() {
+		return monitorStatesFromLayout(prof.Monitors), nil
+	}
+	paths, modes, additional := ccdFromProfile(prof)
+	return monitorStates(paths, modes, additional), nil
+}
+
+func monitorStates(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, additional []ccd.MonitorAdditionalInfo) []MonitorState {
+	states := make([]MonitorState, 0, len(paths))
+	for i := range paths {
+		path := &paths[i]
+		identity := profileIdentity(path, modes, additional)
+		state := MonitorState{
+			Path:             i,
+			Active:           path.Flags&uint32(ccd.DisplayConfigFlagPathActive) != 0,
+			Available:        path.TargetInfo.TargetAvailable != 0,
+			Name:             identity.info.MonitorFriendlyDevice,
+			DevicePath:       identity.info.MonitorDevicePath,
+			ManufactureID:    identity.info.ManufactureID,
+			ProductCodeID:    identity.info.ProductCodeID,
+			Adapter:          formatAdapterID(path.TargetInfo.AdapterID),
+			SourceID:         path.SourceInfo.ID,
+			TargetID:         path.TargetInfo.ID,
+			OutputTechnology: path.TargetInfo.OutputTechnology.String(),
+			RefreshHz:        refreshHz(path.TargetInfo.RefreshRate),
+			ScanLineOrdering: path.TargetInfo.ScanLineOrdering.String(),
+			Rotation:         rotationDegrees(path.TargetInfo.Rotation),
+			Scaling:          scalingName(path.TargetInfo.Scaling),
+			VirtualMode:      path.VirtualModeAware(),
+		}
+		if group, ok := path.CloneGroupID(); ok {
+			state.CloneGroup = &group
+		}
+		if idx, ok := path.SourceModeIdx(); ok && idx < len(modes) && modes[idx].InfoType == ccd.DisplayConfigModeInfoTypeSource {
+			source := modes[idx].SourceMode()
+			state.Width, state.Height = source.Width, source.Height
+			state.Position = Point{X: source.Position.X, Y: source.Position.Y}
+			state.PixelFormat = source.PixelFormat.String()
+			state.Primary = state.Active && source.Position.X == 0 && source.Position.Y == 0
+		}
+		if idx, ok := path.TargetModeIdx(); ok && idx < len(modes) && modes[idx].InfoType == ccd.DisplayConfigModeInfoTypeTarget {
+			signal := modes[idx].TargetMode().TargetVideoSignalInfo
+			state.ActiveWidth, state.ActiveHeight = signal.ActiveSize.Cx, signal.ActiveSize.Cy
+			if state.RefreshHz == 0 {
+				state.RefreshHz = refreshHz(signal.VSyncFreq)
+			}
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+func monitorStatesFromLayout(monitors []profile.Monitor) []MonitorState {
+	states := make([]MonitorState, 0, len(monitors))
+	for i, monitor := range monitors {
+		layout := layoutOfMonitor(monitor, profile.PointL{})
+		states = append(states, MonitorState{
+			Path:             i,
+			Active:           monitor.Enabled,
+			Available:        true,
+			Primary:          monitor.Primary,
+			Name:             monitor.Name,
+			DevicePath:       monitor.DevicePath,
+			ManufactureID:    monitor.ManufactureID,
+			ProductCodeID:    monitor.ProductCodeID,
+			TargetID:         monitor.TargetID,
+			OutputTechnology: ccd.DisplayConfigVideoOutputTechnology(monitor.OutputTechnology).String(),
+			Width:            layout.width,
+			Height:           layout.height,
+			Position:         Point{X: monitor.Position.X, Y: monitor.Position.Y},
+			RefreshHz:        monitor.RefreshHz,
+			ActiveWidth:      monitor.Width,
+			ActiveHeight:     monitor.Height,
+			Rotation:         rotationDegrees(layout.rotation),
+			Scaling:          scalingName(layout.scaling),
+		})
+	}
+	return states
+}
@@ -0,0 +1,92 @@
+package switcher
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// Plan is what a plan-mode LoadProfile would apply: the configuration of
+// the attempt that passed validation (LoadReport.Applied), the flags
+// SetDisplayConfig would be called with and how it differs from the
+// current configuration.
+type Plan struct {
+	Flags ccd.SdcFlags
+	// Changes has one line per changed property; nil when CurrentErr says
+	// why the current configuration could not be read.
+	Changes    []string
+	CurrentErr error
+}
+
+// plan works out the Plan of a plan-mode load, or nil when no strategy
+// passed validation. flags is what SetDisplayConfig would be called with
+// when applying. perMonitor marks a per-monitor profile.
+func (s *Switcher) plan(report LoadReport, flags ccd.SdcFlags, perMonitor bool) *Plan {
+	final, ok := report.Applied()
+	if !ok {
+		return nil
+	}
+	plan := &Plan{Flags: flags}
+	currentPaths, currentModes, currentAdditional, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsOnlyActivePaths|ccd.QueryDisplayFlagsVirtualModeAware)
+	if err != nil {
+		currentPaths, currentModes, currentAdditional, err = ccd.GetDisplaySettings(s.backend, true)
+	}
+	if err != nil {
+		plan.CurrentErr = err
+		return plan
+	}
+	plan.Changes = diffLayouts(layoutsFromCCD(currentPaths, currentModes, currentAdditional), requestedLayouts(final, perMonitor))
+	return plan
+}
+
+// writePlan prints what a plan-mode LoadProfile found: every attempt, the
+// strategy that passed validation, its differences from the current
+// configuration and the final arrays.
+func writePlan(w io.Writer, report LoadReport) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Plan for %s (nothing was applied)\n", report.Profile)
+	for _, attempt := range report.Attempts {
+		switch {
+		case attempt.Skipped != "":
+			fmt.Fprintf(&builder, "  Strategy %s: skipped: %s\n", attempt.Strategy, attempt.Skipped)
+		case attempt.Err != nil:
+			fmt.Fprintf(&builder, "  Strategy %s: rejected: %v\n", attempt.Strategy, attempt.Err)
+		default:
+			fmt.Fprintf(&builder, "  Strategy %s: validated\n", attempt.Strategy)
+		}
+	}
+	final, ok := report.Applied()
+	if !ok || report.Plan == nil {
+		builder.WriteString("No strategy produced a valid configuration.\n")
+		_, err := io.WriteString(w, builder.String())
+		return err
+	}
+	plan := report.Plan
+	fmt.Fprintf(&builder, "Would apply with strategy %s, flags 0x%X\n", final.Strategy, uint32(plan.Flags))
+
+	builder.WriteString("\nChanges against the current configuration:\n")
+	if plan.CurrentErr != nil {
+		fmt.Fprintf(&builder, "  unavailable: %v\n", plan.CurrentErr)
+	} else if len(plan.Changes) == 0 {
+		builder.WriteString("  none\n")
+	}
+	for _, change := range plan.Changes {
+		fmt.Fprintf(&builder, "  %s\n", change)
+	}
+
+	builder.WriteString("\nFinal configuration:\n")
+	builder.WriteString(formatSummary(final.Paths, final.Modes, final.Additional))
+
+	data, err := marshalArrays(final.Paths, final.Modes)
+	if err != nil {
+		return err
+	}
+	builder.WriteString("\nPath and mode arrays:\n")
+	builder.Write(data)
+	builder.WriteString("\n")
+
+	_, err = io.WriteString(w, builder.String())
+	return err
+}
@@ -0,0 +1,141 @@
+package switcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+)
+
+func TestPlan(t *testing.T) {
+	luid := ccd.LUID{LowPart: 1}
+	swapped := twin(1, 100, 200, false)
+	activate(swapped, luid, 0, 100, 1920, 1920, 1080)
+	activate(swapped, luid, 1, 200, 0, 1920, 1080)
+	path := saveProfile(t, swapped, SaveOptions{})
+
+	tests := []struct {
+		name    string
+		machine func() *ccdsim.Machine
+		wantErr bool
+		want    []string
+	}{
+		{
+			name:    "validated",
+			machine: func() *ccdsim.Machine { return twin(1, 100, 200, true) },
+			want: []string{
+				"Strategy identity: validated",
+				"Would apply with strategy identity",
+				"DELL P2419H (target id 100): position (0,0) -> (1920,0)",
+				"DELL P2419H (target id 200): position (1920,0) -> (0,0)",
+				`"pathInfo"`,
+			},
+		},
+		{
+			name: "unchanged",
+			machine: func() *ccdsim.Machine {
+				m := twin(1, 100, 200, false)
+				activate(m, luid, 0, 100, 1920, 1920, 1080)
+				activate(m, luid, 1, 200, 0, 1920, 1080)
+				return m
+			},
+			want: []string{"Changes against the current configuration:\n  none\n"},
+		},
+		{
+			name: "rejected",
+			machine: func() *ccdsim.Machine {
+				m := ccdsim.New(ccdsim.Adapter{ID: luid, Sources: []uint32{0, 1}, Targets: []ccdsim.Target{
+					target(100, dell("UID4352")),
+					{ID: 200, Present: true, OutputTechnology: ccd.DisplayConfigVideoOutputTechnologyDisplayPortExt, Monitor: dell("UID4353"), Resolutions: []ccd.DisplayConfig2DRegion{{Cx: 1280, Cy: 720}}},
+				}})
+				activate(m, luid, 0, 100, 0, 1920, 1080)
+				activate(m, luid, 1, 200, 1920, 1280, 720)
+				return m
+			},
+			wantErr: true,
+			want:    []string{"rejected: SetDisplayConfig failed: ERROR_GEN_FAILURE", "No strategy produced a valid configuration."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.machine()
+			before := fmt.Sprint(layout(m))
+			var out strings.Builder
+			report, err := New(m).LoadProfileWithReport(path, LoadOptions{Plan: true, Output: &out})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadProfile() = %v, want error %v", err, tt.wantErr)
+			}
+			if (report.Plan == nil) != tt.wantErr {
+				t.Errorf("Plan = %+v with error %v", report.Plan, err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("plan does not contain %q:\n%s", want, out.String())
+				}
+			}
+			if m.Applied() != 0 || fmt.Sprint(layout(m)) != before {
+				t.Errorf("plan mode changed the configuration: %d applied, layout %v", m.Applied(), layout(m))
+			}
+			if m.SetCalls() == 0 {
+				t.Error("plan mode did not validate with SetDisplayConfig")
+			}
+		})
+	}
+}
+
+func TestPlanJSON(t *testing.T) {
+	luid := ccd.LUID{LowPart: 1}
+	swapped := twin(1, 100, 200, false)
+	activate(swapped, luid, 0, 100, 1920, 1920, 1080)
+	activate(swapped, luid, 1, 200, 0, 1920, 1080)
+	path := saveProfile(t, swapped, SaveOptions{})
+
+	report, err := New(twin(1, 100, 200, true)).LoadProfileWithReport(path, quiet(LoadOptions{Plan: true}))
+	if err != nil {
+		t.Fatalf("LoadProfile() = %v", err)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Applied string `json:"applied"`
+		Plan    *struct {
+			Flags    uint32         `json:"flags"`
+			Changes  []string       `json:"changes"`
+			Monitors []MonitorState `json:"monitors"`
+		} `json:"plan"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Plan == nil {
+		t.Fatalf("report has no plan: %s", data)
+	}
+	want := []string{
+		"DELL P2419H (target id 100): position (0,0) -> (1920,0)",
+		"DELL P2419H (target id 200): position (1920,0) -> (0,0)",
+	}
+	if fmt.Sprint(decoded.Plan.Changes) != fmt.Sprint(want) {
+		t.Errorf("changes = %q, want %q", decoded.Plan.Changes, want)
+	}
+	if decoded.Plan.Flags != uint32(applyFlags) {
+		t.Errorf("flags = 0x%X, want the apply flags 0x%X", decoded.Plan.Flags, uint32(applyFlags))
+	}
+	positions := map[uint32]Point{}
+	for _, monitor := range decoded.Plan.Monitors {
+		positions[monitor.TargetID] = monitor.Position
+	}
+	if positions[100] != (Point{X: 1920}) || positions[200] != (Point{}) {
+		t.Errorf("planned monitors = %+v, want the saved layout", decoded.Plan.Monitors)
+	}
+
+	// Without a plan the field is left out.
+	report.Plan = nil
+	if data, _ := json.Marshal(report); strings.Contains(string(data), `"plan"`) {
+		t.Errorf("report without a plan = %s", data)
+	}
+}
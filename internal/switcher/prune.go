@@ -0,0 +1,135 @@
+package switcher
+
+import (
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// targetsPresent reports, per profile path, whether its monitor is
+// connected: either identity matching found it or the saved adapter still
+// has the saved target ID available and not matched to another monitor.
+// A target ID alone names a different output on another adapter.
+func targetsPresent(paths []ccd.DisplayConfigPathInfo, currentPaths []ccd.DisplayConfigPathInfo, match monitorMatch) []bool {
+	type targetKey struct {
+		adapter ccd.LUID
+		id      uint32
+	}
+	claimed := make(map[targetKey]bool, len(match.targets))
+	for _, target := range match.targets {
+		claimed[targetKey{adapter: target.adapterID, id: target.identity.targetID}] = true
+	}
+	available := make(map[targetKey]bool, len(currentPaths))
+	for _, path := range currentPaths {
+		k := targetKey{adapter: path.TargetInfo.AdapterID, id: path.TargetInfo.ID}
+		if path.TargetInfo.TargetAvailable != 0 && !claimed[k] {
+			available[k] = true
+		}
+	}
+	present := make([]bool, len(paths))
+	for i := range paths {
+		_, matched := match.targets[i]
+		present[i] = matched || available[targetKey{adapter: paths[i].TargetInfo.AdapterID, id: paths[i].TargetInfo.ID}]
+	}
+	return present
+}
+
+// pruneAbsentTargets removes the profile paths whose target is not present.
+// Modes referenced only by removed paths are dropped with them (keeping
+// additional aligned with modes), and the mode indices of the remaining
+// paths are renumbered, including the packed virtual-mode form.
+func pruneAbsentTargets(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, additional []ccd.MonitorAdditionalInfo, present []bool) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo, []ccd.MonitorAdditionalInfo, []monitorIdentity) {
+	var dropped []monitorIdentity
+	keptPaths := make([]ccd.DisplayConfigPathInfo, 0, len(paths))
+	for i := range paths {
+		if present[i] {
+			keptPaths = append(keptPaths, paths[i])
+			continue
+		}
+		dropped = append(dropped, profileIdentity(&paths[i], modes, additional))
+	}
+	if len(dropped) == 0 {
+		return paths, modes, additional, nil
+	}
+
+	keptRefs := make(map[int]bool)
+	droppedRefs := make(map[int]bool)
+	for i := range paths {
+		refs := keptRefs
+		if !present[i] {
+			refs = droppedRefs
+		}
+		for _, idx := range pathModeIndices(&paths[i]) {
+			refs[idx] = true
+		}
+	}
+
+	newIndex := make(map[int]int, len(modes))
+	keptModes := make([]ccd.DisplayConfigModeInfo, 0, len(modes))
+	var keptAdditional []ccd.MonitorAdditionalInfo
+	if additional != nil {
+		keptAdditional = make([]ccd.MonitorAdditionalInfo, 0, len(additional))
+	}
+	for i := range modes {
+		if droppedRefs[i] && !keptRefs[i] {
+			continue
+		}
+		newIndex[i] = len(keptModes)
+		keptModes = append(keptModes, modes[i])
+		if i < len(additional) {
+			keptAdditional = append(keptAdditional, additional[i])
+		}
+	}
+
+	for i := range keptPaths {
+		remapModeIndices(&keptPaths[i], newIndex)
+	}
+
+	return keptPaths, keptModes, keptAdditional, dropped
+}
+
+// pathModeIndices lists the mode indices a path references.
+func pathModeIndices(path *ccd.DisplayConfigPathInfo) []int {
+	var indices []int
+	if idx, ok := path.SourceModeIdx(); ok {
+		indices = append(indices, idx)
+	}
+	if idx, ok := path.TargetModeIdx(); ok {
+		indices = append(indices, idx)
+	}
+	if idx, ok := path.DesktopModeIdx(); ok {
+		indices = append(indices, idx)
+	}
+	return indices
+}
+
+// remapModeIndices rewrites a path's mode indices through newIndex. Indices
+// missing from the map become invalid.
+func remapModeIndices(path *ccd.DisplayConfigPathInfo, newIndex map[int]int) {
+	lookup := func(idx int, ok bool) int {
+		if !ok {
+			return -1
+		}
+		if mapped, found := newIndex[idx]; found {
+			return mapped
+		}
+		return -1
+	}
+
+	sourceIdx := lookup(path.SourceModeIdx())
+	targetIdx := lookup(path.TargetModeIdx())
+	if path.VirtualModeAware() {
+		cloneGroup := path.SourceInfo.ModeInfoIdx & 0xFFFF
+		desktopIdx := lookup(path.DesktopModeIdx())
+		path.SourceInfo.ModeInfoIdx = ccd.PackSourceModeIdx(cloneGroup, sourceIdx)
+		path.TargetInfo.ModeInfoIdx = ccd.PackTargetModeIdx(targetIdx, desktopIdx)
+		return
+	}
+	path.SourceInfo.ModeInfoIdx = plainModeIdx(sourceIdx)
+	path.TargetInfo.ModeInfoIdx = plainModeIdx(targetIdx)
+}
+
+func plainModeIdx(idx int) uint32 {
+	if idx < 0 {
+		return ccd.DisplayConfigPathModeIdxInvalid
+	}
+	return uint32(idx)
+}
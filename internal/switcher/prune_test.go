@@ -0,0 +1,201 @@
+package switcher
+
+import (
+	"reflect"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// testPath returns an active path from source to target on adapter luid
+// with plain mode indices.
+func testPath(luid uint32, source, target uint32, sourceIdx, targetIdx int) ccd.DisplayConfigPathInfo {
+	var path ccd.DisplayConfigPathInfo
+	path.Flags = uint32(ccd.DisplayConfigFlagPathActive)
+	path.SourceInfo.AdapterID = ccd.LUID{LowPart: luid}
+	path.SourceInfo.ID = source
+	path.SourceInfo.ModeInfoIdx = plainModeIdx(sourceIdx)
+	path.TargetInfo.AdapterID = ccd.LUID{LowPart: luid}
+	path.TargetInfo.ID = target
+	path.TargetInfo.ModeInfoIdx = plainModeIdx(targetIdx)
+	path.TargetInfo.TargetAvailable = 1
+	return path
+}
+
+func testMode(infoType ccd.DisplayConfigModeInfoType, luid uint32, id uint32) ccd.DisplayConfigModeInfo {
+	return ccd.DisplayConfigModeInfo{InfoType: infoType, ID: id, AdapterID: ccd.LUID{LowPart: luid}}
+}
+
+func TestTargetsPresent(t *testing.T) {
+	matchedTo := func(luid uint32, targetID uint32) currentTarget {
+		return currentTarget{adapterID: ccd.LUID{LowPart: luid}, identity: monitorIdentity{targetID: targetID}}
+	}
+	unavailable := testPath(1, 0, 300, -1, -1)
+	unavailable.TargetInfo.TargetAvailable = 0
+
+	tests := []struct {
+		name    string
+		paths   []ccd.DisplayConfigPathInfo
+		current []ccd.DisplayConfigPathInfo
+		match   map[int]currentTarget
+		want    []bool
+	}{
+		{
+			name:    "matched by identity",
+			paths:   []ccd.DisplayConfigPathInfo{testPath(9, 0, 100, 0, 1)},
+			current: []ccd.DisplayConfigPathInfo{testPath(1, 0, 200, 0, 1)},
+			match:   map[int]currentTarget{0: matchedTo(1, 200)},
+			want:    []bool{true},
+		},
+		{
+			name:    "same adapter and target",
+			paths:   []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1)},
+			current: []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1)},
+			want:    []bool{true},
+		},
+		{
+			name:    "same target ID on another adapter",
+			paths:   []ccd.DisplayConfigPathInfo{testPath(2, 0, 100, 0, 1)},
+			current: []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1)},
+			want:    []bool{false},
+		},
+		{
+			name:    "target claimed by another monitor",
+			paths:   []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1), testPath(1, 1, 200, 2, 3)},
+			current: []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1)},
+			match:   map[int]currentTarget{1: matchedTo(1, 100)},
+			want:    []bool{false, true},
+		},
+		{
+			name:    "target not available",
+			paths:   []ccd.DisplayConfigPathInfo{testPath(1, 0, 300, 0, 1)},
+			current: []ccd.DisplayConfigPathInfo{unavailable},
+			want:    []bool{false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := monitorMatch{targets: tt.match}
+			if match.targets == nil {
+				match.targets = map[int]currentTarget{}
+			}
+			if got := targetsPresent(tt.paths, tt.current, match); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("targetsPresent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneAbsentTargets(t *testing.T) {
+	source, target, desktop := ccd.DisplayConfigModeInfoTypeSource, ccd.DisplayConfigModeInfoTypeTarget, ccd.DisplayConfigModeInfoTypeDesktopImage
+	packed := func(path ccd.DisplayConfigPathInfo, cloneGroup uint32, sourceIdx, targetIdx, desktopIdx int) ccd.DisplayConfigPathInfo {
+		path.Flags |= uint32(ccd.DisplayConfigFlagPathSupportVirtualMode)
+		path.SourceInfo.ModeInfoIdx = ccd.PackSourceModeIdx(cloneGroup, sourceIdx)
+		path.TargetInfo.ModeInfoIdx = ccd.PackTargetModeIdx(targetIdx, desktopIdx)
+		return path
+	}
+
+	tests := []struct {
+		name        string
+		paths       []ccd.DisplayConfigPathInfo
+		modes       []ccd.DisplayConfigModeInfo
+		present     []bool
+		wantPaths   []ccd.DisplayConfigPathInfo
+		wantModes   []ccd.DisplayConfigModeInfo
+		wantDropped []uint32
+	}{
+		{
+			name:      "all present",
+			paths:     []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1)},
+			modes:     []ccd.DisplayConfigModeInfo{testMode(source, 1, 0), testMode(target, 1, 100)},
+			present:   []bool{true},
+			wantPaths: []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1)},
+			wantModes: []ccd.DisplayConfigModeInfo{testMode(source, 1, 0), testMode(target, 1, 100)},
+		},
+		{
+			name:  "first of two dropped",
+			paths: []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1), testPath(1, 1, 200, 2, 3)},
+			modes: []ccd.DisplayConfigModeInfo{
+				testMode(source, 1, 0), testMode(target, 1, 100),
+				testMode(source, 1, 1), testMode(target, 1, 200),
+			},
+			present:     []bool{false, true},
+			wantPaths:   []ccd.DisplayConfigPathInfo{testPath(1, 1, 200, 0, 1)},
+			wantModes:   []ccd.DisplayConfigModeInfo{testMode(source, 1, 1), testMode(target, 1, 200)},
+			wantDropped: []uint32{100},
+		},
+		{
+			name:  "clone keeps the shared source mode",
+			paths: []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1), testPath(1, 0, 200, 0, 2)},
+			modes: []ccd.DisplayConfigModeInfo{
+				testMode(source, 1, 0), testMode(target, 1, 100), testMode(target, 1, 200),
+			},
+			present:     []bool{true, false},
+			wantPaths:   []ccd.DisplayConfigPathInfo{testPath(1, 0, 100, 0, 1)},
+			wantModes:   []ccd.DisplayConfigModeInfo{testMode(source, 1, 0), testMode(target, 1, 100)},
+			wantDropped: []uint32{200},
+		},
+		{
+			name: "packed indices are renumbered",
+			paths: []ccd.DisplayConfigPathInfo{
+				packed(testPath(1, 0, 100, -1, -1), 0, 0, 1, 2),
+				packed(testPath(1, 1, 200, -1, -1), 3, 3, 4, 5),
+			},
+			modes: []ccd.DisplayConfigModeInfo{
+				testMode(source, 1, 0), testMode(target, 1, 100), testMode(desktop, 1, 100),
+				testMode(source, 1, 1), testMode(target, 1, 200), testMode(desktop, 1, 200),
+			},
+			present:     []bool{false, true},
+			wantPaths:   []ccd.DisplayConfigPathInfo{packed(testPath(1, 1, 200, -1, -1), 3, 0, 1, 2)},
+			wantModes:   []ccd.DisplayConfigModeInfo{testMode(source, 1, 1), testMode(target, 1, 200), testMode(desktop, 1, 200)},
+			wantDropped: []uint32{100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			additional := make([]ccd.MonitorAdditionalInfo, len(tt.modes))
+			for i, mode := range tt.modes {
+				additional[i] = ccd.MonitorAdditionalInfo{Valid: true, ConnectorInstance: mode.ID}
+			}
+			paths, modes, gotAdditional, dropped := pruneAbsentTargets(tt.paths, tt.modes, additional, tt.present)
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("paths = %+v, want %+v", paths, tt.wantPaths)
+			}
+			if !reflect.DeepEqual(modes, tt.wantModes) {
+				t.Errorf("modes = %+v, want %+v", modes, tt.wantModes)
+			}
+			for i := range modes {
+				if gotAdditional[i].ConnectorInstance != modes[i].ID {
+					t.Errorf("additional[%d] belongs to mode %d, not %d", i, gotAdditional[i].ConnectorInstance, modes[i].ID)
+				}
+			}
+			var droppedIDs []uint32
+			for _, identity := range dropped {
+				droppedIDs = append(droppedIDs, identity.targetID)
+			}
+			if !reflect.DeepEqual(droppedIDs, tt.wantDropped) {
+				t.Errorf("dropped = %v, want %v", droppedIDs, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestLoadSkipsDisconnectedMonitor(t *testing.T) {
+	m := twin(1, 100, 200, true)
+	path := saveProfile(t, m, SaveOptions{})
+	m.SetPresent(ccd.LUID{LowPart: 1}, 200, false)
+
+	report, err := New(m).LoadProfileWithReport(path, quiet(LoadOptions{}))
+	if err != nil {
+		t.Fatalf("LoadProfile() = %v", err)
+	}
+	applied, _ := report.Applied()
+	if len(applied.Paths) != 1 || applied.Paths[0].TargetInfo.ID != 100 {
+		t.Errorf("applied paths = %+v, want only target 100", applied.Paths)
+	}
+
+	m.SetPresent(ccd.LUID{LowPart: 1}, 100, false)
+	if err := New(m).LoadProfile(path, quiet(LoadOptions{})); err == nil {
+		t.Error("LoadProfile() with no monitor connected succeeded")
+	}
+}
@@ -0,0 +1,131 @@
+package switcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// Attempt is one strategy LoadProfile tried.
+type Attempt struct {
+	Strategy string
+	// Skipped is why the strategy made no SetDisplayConfig call, or "".
+	Skipped string
+	Flags   ccd.SdcFlags
+	Paths   []ccd.DisplayConfigPathInfo
+	Modes   []ccd.DisplayConfigModeInfo
+	// Additional is aligned with Modes.
+	Additional []ccd.MonitorAdditionalInfo
+	Err        error
+}
+
+// LoadReport describes how LoadProfile went about applying a profile.
+type LoadReport struct {
+	Profile string
+	// Strategies is the order strategies were configured to run in.
+	Strategies []string
+	Attempts   []Attempt
+	// Succeeded is the index in Attempts of the strategy that was applied
+	// (or validated, in plan mode), or -1.
+	Succeeded int
+	// Plan is set in plan mode when a strategy passed validation.
+	Plan *Plan
+}
+
+// Applied returns the attempt that succeeded.
+func (r LoadReport) Applied() (Attempt, bool) {
+	if r.Succeeded < 0 || r.Succeeded >= len(r.Attempts) {
+		return Attempt{}, false
+	}
+	return r.Attempts[r.Succeeded], true
+}
+
+// Write prints one line per attempt. With arrays, the path and mode arrays
+// each attempt passed to SetDisplayConfig follow its line.
+func (r LoadReport) Write(w io.Writer, arrays bool) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Strategies for %s: %s\n", r.Profile, strings.Join(r.Strategies, ", "))
+	for i, attempt := range r.Attempts {
+		switch {
+		case attempt.Skipped != "":
+			fmt.Fprintf(&builder, "  %d. %s: skipped: %s\n", i+1, attempt.Strategy, attempt.Skipped)
+			continue
+		case attempt.Err != nil:
+			fmt.Fprintf(&builder, "  %d. %s (flags 0x%X): failed: %v\n", i+1, attempt.Strategy, uint32(attempt.Flags), attempt.Err)
+		default:
+			fmt.Fprintf(&builder, "  %d. %s (flags 0x%X): succeeded\n", i+1, attempt.Strategy, uint32(attempt.Flags))
+		}
+		if arrays {
+			data, err := marshalArrays(attempt.Paths, attempt.Modes)
+			if err != nil {
+				return err
+			}
+			builder.Write(data)
+			builder.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// MarshalJSON writes the report for -format:json, without the arrays of
+// each attempt.
+func (r LoadReport) MarshalJSON() ([]byte, error) {
+	type attempt struct {
+		Strategy string `json:"strategy"`
+		Skipped  string `json:"skipped,omitempty"`
+		Flags    uint32 `json:"flags"`
+		Error    string `json:"error,omitempty"`
+	}
+	// plan is the Plan with the configuration it would apply.
+	type plan struct {
+		Flags        uint32         `json:"flags"`
+		Changes      []string       `json:"changes"`
+		CurrentError string         `json:"currentError,omitempty"`
+		Monitors     []MonitorState `json:"monitors"`
+	}
+	out := struct {
+		Profile    string    `json:"profile"`
+		Strategies []string  `json:"strategies"`
+		Attempts   []attempt `json:"attempts"`
+		Applied    string    `json:"applied,omitempty"`
+		Plan       *plan     `json:"plan,omitempty"`
+	}{Profile: r.Profile, Strategies: r.Strategies, Attempts: []attempt{}}
+	for _, a := range r.Attempts {
+		entry := attempt{Strategy: a.Strategy, Skipped: a.Skipped, Flags: uint32(a.Flags)}
+		if a.Err != nil {
+			entry.Error = a.Err.Error()
+		}
+		out.Attempts = append(out.Attempts, entry)
+	}
+	if applied, ok := r.Applied(); ok {
+		out.Applied = applied.Strategy
+		if r.Plan != nil {
+			out.Plan = &plan{
+				Flags:    uint32(r.Plan.Flags),
+				Changes:  append([]string{}, r.Plan.Changes...),
+				Monitors: monitorStates(applied.Paths, applied.Modes, applied.Additional),
+			}
+			if r.Plan.CurrentErr != nil {
+				out.Plan.CurrentError = r.Plan.CurrentErr.Error()
+			}
+		}
+	}
+	return json.Marshal(out)
+}
+
+// marshalArrays renders path and mode arrays in the profile JSON format.
+func marshalArrays(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo) ([]byte, error) {
+	arrays := profileFromCCD(paths, modes, nil)
+	data, err := json.MarshalIndent(struct {
+		PathInfo any `json:"pathInfo"`
+		ModeInfo any `json:"modeInfo"`
+	}{arrays.PathInfo, arrays.ModeInfo}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("serialize attempt: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,66 @@
+package switcher
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+func testReport() LoadReport {
+	return LoadReport{
+		Profile:    "Home.monitorprofile",
+		Strategies: []string{StrategyIdentity, StrategyAdapterID, StrategyAsSaved},
+		Attempts: []Attempt{
+			{Strategy: StrategyIdentity, Skipped: "no monitor in the profile matched a connected target"},
+			{Strategy: StrategyAdapterID, Flags: ccd.SdcFlagsApply, Err: ccd.ErrGenFailure},
+			{Strategy: StrategyAsSaved, Flags: ccd.SdcFlagsApply},
+		},
+		Succeeded: 2,
+	}
+}
+
+func TestLoadReportWrite(t *testing.T) {
+	var out strings.Builder
+	if err := testReport().Write(&out, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "Strategies for Home.monitorprofile: identity, adapter-id, as-saved\n" +
+		"  1. identity: skipped: no monitor in the profile matched a connected target\n" +
+		"  2. adapter-id (flags 0x80): failed: " + ccd.ErrGenFailure.Error() + "\n" +
+		"  3. as-saved (flags 0x80): succeeded\n"
+	if out.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestLoadReportMarshalJSON(t *testing.T) {
+	report := testReport()
+	want := `{"profile":"Home.monitorprofile","strategies":["identity","adapter-id","as-saved"],` +
+		`"attempts":[{"strategy":"identity","skipped":"no monitor in the profile matched a connected target","flags":0},` +
+		`{"strategy":"adapter-id","flags":128,"error":"` + ccd.ErrGenFailure.Error() + `"},` +
+		`{"strategy":"as-saved","flags":128}],"applied":"as-saved"}`
+
+	// The CLI stores the report by value in an interface.
+	for _, v := range []any{report, &report} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("json.Marshal(%T) =\n%s\nwant\n%s", v, data, want)
+		}
+	}
+}
+
+func TestLoadReportApplied(t *testing.T) {
+	report := testReport()
+	if applied, ok := report.Applied(); !ok || applied.Strategy != StrategyAsSaved {
+		t.Errorf("Applied() = %q, %v; want %q", applied.Strategy, ok, StrategyAsSaved)
+	}
+	report.Succeeded = -1
+	if _, ok := report.Applied(); ok {
+		t.Error("Applied() of a failed load reported an attempt")
+	}
+}
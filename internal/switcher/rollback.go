@@ -0,0 +1,59 @@
+package switcher
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// Confirmer decides whether an applied profile is kept.
+type Confirmer interface {
+	// Wait reports whether the user kept the new settings within timeout.
+	Wait(timeout time.Duration) (bool, error)
+}
+
+// snapshot is the active configuration before a profile was applied.
+type snapshot struct {
+	paths []ccd.DisplayConfigPathInfo
+	modes []ccd.DisplayConfigModeInfo
+	flags ccd.SdcFlags
+}
+
+func (s *Switcher) takeSnapshot() (snapshot, error) {
+	paths, modes, _, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsOnlyActivePaths|ccd.QueryDisplayFlagsVirtualModeAware)
+	if err == nil {
+		return snapshot{paths: paths, modes: modes, flags: applyFlags | ccd.SdcFlagsVirtualModeAware}, nil
+	}
+	paths, modes, _, err = ccd.GetDisplaySettings(s.backend, true)
+	if err != nil {
+		return snapshot{}, fmt.Errorf("snapshot current display settings: %w", err)
+	}
+	return snapshot{paths: paths, modes: modes, flags: applyFlags}, nil
+}
+
+func (s *Switcher) restore(snap snapshot) error {
+	if err := ccd.SetDisplayConfig(s.backend, snap.paths, snap.modes, snap.flags); err != nil {
+		return fmt.Errorf("restore previous display settings: %w", err)
+	}
+	return nil
+}
+
+// confirmOrRevert waits for the user to keep the applied profile and puts
+// the snapshot back when they do not.
+func (s *Switcher) confirmOrRevert(opts LoadOptions, snap snapshot) error {
+	keep, err := opts.confirmer().Wait(opts.Confirm)
+	if err != nil {
+		opts.logger().warnf("confirmation unavailable: %v", err)
+	}
+	if keep {
+		fmt.Fprintln(opts.output(), "Display settings kept.")
+		return nil
+	}
+	fmt.Fprintln(opts.output(), "Reverting to the previous display settings.")
+	if err := s.restore(snap); err != nil {
+		return err
+	}
+	return errors.New("profile was not confirmed; previous display settings restored")
+}
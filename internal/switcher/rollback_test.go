@@ -0,0 +1,57 @@
+package switcher
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// answer is a Confirmer with a fixed answer.
+type answer struct {
+	keep bool
+	err  error
+}
+
+func (a answer) Wait(time.Duration) (bool, error) {
+	return a.keep, a.err
+}
+
+func TestConfirmOrRevert(t *testing.T) {
+	luid := ccd.LUID{LowPart: 1}
+	swapped := twin(1, 100, 200, false)
+	activate(swapped, luid, 0, 100, 1920, 1920, 1080)
+	activate(swapped, luid, 1, 200, 0, 1920, 1080)
+	path := saveProfile(t, swapped, SaveOptions{})
+
+	before := map[uint32]int32{100: 0, 200: 1920}
+	after := map[uint32]int32{100: 1920, 200: 0}
+	tests := []struct {
+		name       string
+		confirmer  Confirmer
+		wantErr    bool
+		want       map[uint32]int32
+		wantCommit int
+	}{
+		{name: "kept", confirmer: answer{keep: true}, want: after, wantCommit: 1},
+		{name: "reverted", confirmer: answer{keep: false}, wantErr: true, want: before, wantCommit: 2},
+		{name: "confirmation unavailable", confirmer: answer{err: errors.New("no console")}, wantErr: true, want: before, wantCommit: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := twin(1, 100, 200, true)
+			err := New(m).LoadProfile(path, quiet(LoadOptions{Confirm: time.Second, Confirmer: tt.confirmer}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadProfile() = %v, want error %v", err, tt.wantErr)
+			}
+			if got := layout(m); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("layout = %v, want %v", got, tt.want)
+			}
+			if m.Applied() != tt.wantCommit {
+				t.Errorf("%d configurations applied, want %d", m.Applied(), tt.wantCommit)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package switcher
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+)
+
+// dell returns the EDID identity of one of two identical DELL P2419H
+// monitors, told apart only by the serial in their device path.
+func dell(serial string) ccdsim.Monitor {
+	return ccdsim.Monitor{
+		ManufactureID: 0x10AC,
+		ProductCodeID: 0x4123,
+		FriendlyName:  "DELL P2419H",
+		DevicePath:    `\\?\DISPLAY#DEL4123#5&1a&0&` + serial + `#{e6f07b5f-ee97-4a90-b076-33f57bf4eaa7}`,
+	}
+}
+
+// lg returns the EDID identity of a monitor of another model.
+func lg() ccdsim.Monitor {
+	return ccdsim.Monitor{
+		ManufactureID: 0x6D1E,
+		ProductCodeID: 0x7707,
+		FriendlyName:  "LG HDR 4K",
+		DevicePath:    `\\?\DISPLAY#GSM7707#5&1a&0&UID4354#{e6f07b5f-ee97-4a90-b076-33f57bf4eaa7}`,
+	}
+}
+
+func target(id uint32, monitor ccdsim.Monitor) ccdsim.Target {
+	return ccdsim.Target{ID: id, Present: true, OutputTechnology: ccd.DisplayConfigVideoOutputTechnologyDisplayPortExt, Monitor: monitor}
+}
+
+// twin returns a machine with two DELL monitors on adapter luid, as targets
+// idA (serial UID4352) and idB (serial UID4353). With active set they show
+// sources 0 and 1 side by side at 1920x1080, idA on the left.
+func twin(luid uint32, idA, idB uint32, active bool) *ccdsim.Machine {
+	adapter := ccd.LUID{LowPart: luid}
+	m := ccdsim.New(ccdsim.Adapter{ID: adapter, Sources: []uint32{0, 1}, Targets: []ccdsim.Target{
+		target(idA, dell("UID4352")),
+		target(idB, dell("UID4353")),
+	}})
+	if active {
+		activate(m, adapter, 0, idA, 0, 1920, 1080)
+		activate(m, adapter, 1, idB, 1920, 1920, 1080)
+	}
+	return m
+}
+
+// activate drives target from source with a width x height desktop at x.
+func activate(m *ccdsim.Machine, adapter ccd.LUID, source, target uint32, x int32, width, height uint32) {
+	var path ccd.DisplayConfigPathInfo
+	path.Flags = uint32(ccd.DisplayConfigFlagPathActive)
+	path.SourceInfo.AdapterID = adapter
+	path.SourceInfo.ID = source
+	path.TargetInfo.AdapterID = adapter
+	path.TargetInfo.ID = target
+	path.TargetInfo.Rotation = ccd.DisplayConfigRotationIdentity
+	path.TargetInfo.Scaling = ccd.DisplayConfigScalingPreferred
+	path.TargetInfo.RefreshRate = ccd.DisplayConfigRational{Numerator: 60, Denominator: 1}
+	var mode ccd.DisplayConfigTargetMode
+	mode.TargetVideoSignalInfo.ActiveSize = ccd.DisplayConfig2DRegion{Cx: width, Cy: height}
+	mode.TargetVideoSignalInfo.TotalSize = mode.TargetVideoSignalInfo.ActiveSize
+	mode.TargetVideoSignalInfo.VSyncFreq = path.TargetInfo.RefreshRate
+	code := m.Activate(ccdsim.ActivePath{
+		Path:   path,
+		Source: ccd.DisplayConfigSourceMode{Width: width, Height: height, PixelFormat: ccd.DisplayConfigPixelFormat32Bpp, Position: ccd.PointL{X: x}},
+		Target: mode,
+	})
+	if code != ccd.ErrorSuccess {
+		panic(fmt.Sprintf("activate target %d: error %d", target, code))
+	}
+}
+
+// saveProfile saves the active configuration of m to a temporary file.
+func saveProfile(t *testing.T, m *ccdsim.Machine, opts SaveOptions) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.monitorprofile")
+	if err := New(m).SaveProfile(path, opts); err != nil {
+		t.Fatalf("save profile: %v", err)
+	}
+	return path
+}
+
+// layout maps each active target ID to the X position of its desktop.
+func layout(m *ccdsim.Machine) map[uint32]int32 {
+	positions := make(map[uint32]int32)
+	for _, active := range m.Active() {
+		positions[active.Path.TargetInfo.ID] = active.Source.Position.X
+	}
+	return positions
+}
+
+// quiet loads without printing to the test output.
+func quiet(opts LoadOptions) LoadOptions {
+	if opts.Output == nil {
+		opts.Output = io.Discard
+	}
+	if opts.Warnings == nil {
+		opts.Warnings = func(string) {}
+	}
+	return opts
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	m := twin(1, 100, 200, true)
+	path := saveProfile(t, m, SaveOptions{})
+
+	m.SetPresent(ccd.LUID{LowPart: 1}, 200, false)
+	m.SetPresent(ccd.LUID{LowPart: 1}, 200, true)
+	if got := layout(m); len(got) != 1 {
+		t.Fatalf("setup: %d active targets after unplugging one, want 1", len(got))
+	}
+
+	if err := New(m).LoadProfile(path, quiet(LoadOptions{})); err != nil {
+		t.Fatalf("LoadProfile() = %v", err)
+	}
+	want := map[uint32]int32{100: 0, 200: 1920}
+	if got := layout(m); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("layout = %v, want %v", got, want)
+	}
+	if m.Applied() != 1 {
+		t.Errorf("%d configurations applied, want 1", m.Applied())
+	}
+}
@@ -0,0 +1,234 @@
+package switcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/profile"
+)
+
+// ProfileStatus is how the current layout differs from one saved profile.
+type ProfileStatus struct {
+	Path string
+	// Differences has one line per change from the profile to the current
+	// layout, including profile monitors that are not connected.
+	Differences []string
+	// Err is set when the profile could not be read.
+	Err error
+}
+
+// Matches reports whether the current layout is exactly the profile's.
+func (p ProfileStatus) Matches() bool {
+	return p.Err == nil && len(p.Differences) == 0
+}
+
+// MarshalJSON writes the status for -format:json.
+func (p ProfileStatus) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Path        string   `json:"path"`
+		Matches     bool     `json:"matches"`
+		Differences []string `json:"differences"`
+		Error       string   `json:"error,omitempty"`
+	}{Path: p.Path, Matches: p.Matches(), Differences: p.Differences}
+	if out.Differences == nil {
+		out.Differences = []string{}
+	}
+	if p.Err != nil {
+		out.Error = p.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// StatusReport compares the current layout with every profile in Dir.
+type StatusReport struct {
+	Dir string `json:"dir"`
+	// Profiles is sorted closest first; unreadable profiles come last.
+	Profiles []ProfileStatus `json:"profiles"`
+}
+
+// Match returns the first profile the current layout matches exactly.
+func (r StatusReport) Match() (ProfileStatus, bool) {
+	if len(r.Profiles) > 0 && r.Profiles[0].Matches() {
+		return r.Profiles[0], true
+	}
+	return ProfileStatus{}, false
+}
+
+// Write prints the matching profile, or the closest one and how the current
+// layout differs from it, or "Unsaved layout" when no profile could be read.
+func (r StatusReport) Write(w io.Writer) error {
+	var b strings.Builder
+	if match, ok := r.Match(); ok {
+		fmt.Fprintf(&b, "Current layout matches %s\n", filepath.Base(match.Path))
+		for _, other := range r.Profiles[1:] {
+			if other.Matches() {
+				fmt.Fprintf(&b, "  (so does %s)\n", filepath.Base(other.Path))
+			}
+		}
+	} else if len(r.Profiles) > 0 && r.Profiles[0].Err == nil {
+		closest := r.Profiles[0]
+		fmt.Fprintf(&b, "Unsaved layout; closest profile is %s (%d difference(s)):\n", filepath.Base(closest.Path), len(closest.Differences))
+		for _, line := range closest.Differences {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	} else {
+		b.WriteString("Unsaved layout\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Status compares the current layout with every profile in dir. Monitors
+// are matched by identity, so adapter LUIDs, target IDs and status flags
+// that changed since the save do not count as differences.
+func (s *Switcher) Status(dir string) (StatusReport, error) {
+	profiles, err := ListProfiles(dir)
+	if err != nil {
+		return StatusReport{}, err
+	}
+	paths, modes, additional, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsOnlyActivePaths|ccd.QueryDisplayFlagsVirtualModeAware)
+	if err != nil {
+		paths, modes, additional, err = ccd.GetDisplaySettings(s.backend, true)
+		if err != nil {
+			return StatusReport{}, fmt.Errorf("get display settings: %w", err)
+		}
+	}
+	current := layoutsFromCCD(paths, modes, additional)
+	allPaths, _, _, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsAllPaths)
+	if err != nil {
+		return StatusReport{}, fmt.Errorf("get display settings: %w", err)
+	}
+	targets := currentTargets(s.backend, allPaths)
+
+	report := StatusReport{Dir: dir, Profiles: []ProfileStatus{}}
+	for _, path := range profiles {
+		status := ProfileStatus{Path: path}
+		prof, err := profile.Load(status.Path)
+		if err != nil {
+			status.Err = err
+		} else {
+			saved, missing := savedLayouts(prof, targets, allPaths)
+			status.Differences = append(missing, diffLayouts(saved, current)...)
+		}
+		report.Profiles = append(report.Profiles, status)
+	}
+	sort.SliceStable(report.Profiles, func(a, b int) bool {
+		pa, pb := report.Profiles[a], report.Profiles[b]
+		if (pa.Err == nil) != (pb.Err == nil) {
+			return pa.Err == nil
+		}
+		return len(pa.Differences) < len(pb.Differences)
+	})
+	return report, nil
+}
+
+// savedLayouts returns the layout a profile describes, moved onto the
+// connected targets its monitors match, and one line per monitor that is
+// not connected or cannot be given a source.
+func savedLayouts(prof profile.Profile, targets []currentTarget, currentPaths []ccd.DisplayConfigPathInfo) ([]monitorLayout, []string) {
+	if prof.IsLayout() {
+		return layoutsFromMonitors(prof.Monitors, targets)
+	}
+	paths, modes, additional := ccdFromProfile(prof)
+	var active []int
+	var identities []monitorIdentity
+	for i := range paths {
+		if paths[i].Flags&uint32(ccd.DisplayConfigFlagPathActive) != 0 {
+			active = append(active, i)
+			identities = append(identities, profileIdentity(&paths[i], modes, additional))
+		}
+	}
+	found := matchIdentities(identities, targets)
+	match := monitorMatch{targets: make(map[int]currentTarget)}
+	for i, target := range found.targets {
+		match.targets[active[i]] = target
+	}
+	var missing []string
+	for _, i := range found.unmatched {
+		missing = append(missing, fmt.Sprintf("%s: not connected", identities[i]))
+	}
+	if err := rebindToMatchedTargets(paths, modes, match, currentPaths); err != nil {
+		missing = append(missing, err.Error())
+	}
+
+	layouts := layoutsFromCCD(paths, modes, additional)
+	for i := range layouts {
+		if _, ok := match.targets[i]; !ok {
+			layouts[i].active = false
+		}
+	}
+	return layouts, missing
+}
+
+// layoutsFromMonitors is savedLayouts for a per-monitor profile. Positions
+// are shifted the way compileMonitors shifts them, so the primary monitor
+// is at (0,0).
+func layoutsFromMonitors(monitors []profile.Monitor, targets []currentTarget) ([]monitorLayout, []string) {
+	var enabled []profile.Monitor
+	var identities []monitorIdentity
+	for _, monitor := range monitors {
+		if monitor.Enabled {
+			enabled = append(enabled, monitor)
+			identities = append(identities, monitorIdentityOf(monitor))
+		}
+	}
+	match := matchIdentities(identities, targets)
+
+	var offset profile.PointL
+	for i, monitor := range enabled {
+		if _, ok := match.targets[i]; ok && monitor.Primary {
+			offset = profile.PointL{X: -monitor.Position.X, Y: -monitor.Position.Y}
+			break
+		}
+	}
+
+	var layouts []monitorLayout
+	for i, monitor := range enabled {
+		target, ok := match.targets[i]
+		if !ok {
+			continue
+		}
+		layout := layoutOfMonitor(monitor, offset)
+		layout.adapterID = target.adapterID
+		layout.targetID = target.identity.targetID
+		layouts = append(layouts, layout)
+	}
+	var missing []string
+	for _, i := range match.unmatched {
+		missing = append(missing, fmt.Sprintf("%s: not connected", identities[i]))
+	}
+	return layouts, missing
+}
+
+// layoutOfMonitor describes a per-monitor entry as compileMonitors would
+// apply it, shifted by offset. The adapter is left unset.
+func layoutOfMonitor(monitor profile.Monitor, offset profile.PointL) monitorLayout {
+	rotation, _ := parseRotation(monitor.Rotation)
+	scaling, _ := parseScaling(monitor.Scaling)
+	layout := monitorLayout{
+		targetID:   monitor.TargetID,
+		name:       monitor.Name,
+		active:     monitor.Enabled,
+		hasSource:  true,
+		width:      monitor.Width,
+		height:     monitor.Height,
+		position:   ccd.PointL{X: monitor.Position.X + offset.X, Y: monitor.Position.Y + offset.Y},
+		refresh:    rationalFromHz(monitor.RefreshHz),
+		anyRefresh: monitor.RefreshHz == 0,
+		rotation:   rotation,
+		scaling:    scaling,
+		identity:   monitorIdentityOf(monitor),
+		available:  true,
+		perMonitor: true,
+		cloneGroup: ccd.DisplayConfigPathPackedIdxInvalid,
+	}
+	if rotatesQuarter(rotation) {
+		layout.width, layout.height = monitor.Height, monitor.Width
+	}
+	return layout
+}
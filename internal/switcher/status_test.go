@@ -0,0 +1,126 @@
+package switcher
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/profile"
+)
+
+func TestStatus(t *testing.T) {
+	dir := profileDir(t, "both", "left")
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The same layout in the per-monitor format, without refresh rates.
+	desk := profile.Profile{Monitors: []profile.Monitor{dellMonitor("UID4352", 0, 1920, 1080), dellMonitor("UID4353", 1920, 1920, 1080)}}
+	desk.Monitors[0].Primary = true
+	desk.Monitors[0].RefreshHz, desk.Monitors[1].RefreshHz = 0, 0
+	if err := profile.Save(filepath.Join(dir, "desk.yaml"), desk, profile.SaveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a reboot: a new adapter LUID, the monitors on swapped target
+	// IDs, and the layout of "both" applied again.
+	m := twin(7, 200, 100, false)
+	if err := New(m).LoadProfile(filepath.Join(dir, "both.monitorprofile"), quiet(LoadOptions{})); err != nil {
+		t.Fatalf("LoadProfile() = %v", err)
+	}
+
+	report, err := New(m).Status(dir)
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	var order []string
+	for _, status := range report.Profiles {
+		order = append(order, filepath.Base(status.Path))
+	}
+	want := []string{"both.monitorprofile", "desk.yaml", "left.monitorprofile", "broken.json"}
+	if len(order) != len(want) {
+		t.Fatalf("profiles = %q, want %q", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("profiles = %q, want %q", order, want)
+		}
+	}
+	if match, ok := report.Match(); !ok || filepath.Base(match.Path) != "both.monitorprofile" {
+		t.Errorf("Match() = %s, %v, want both.monitorprofile", match.Path, ok)
+	}
+	if !report.Profiles[1].Matches() {
+		t.Errorf("desk.yaml differs: %q", report.Profiles[1].Differences)
+	}
+	if left := report.Profiles[2]; left.Matches() || len(left.Differences) == 0 {
+		t.Errorf("left.monitorprofile matches a layout with both monitors on")
+	}
+	if report.Profiles[3].Err == nil {
+		t.Error("broken.json has no error")
+	}
+
+	// Unplugging a monitor leaves "left" as the match; "both" misses one.
+	m.SetPresent(ccd.LUID{LowPart: 7}, 100, false)
+	report, err = New(m).Status(dir)
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	if match, ok := report.Match(); !ok || filepath.Base(match.Path) != "left.monitorprofile" {
+		t.Errorf("Match() = %s, %v, want left.monitorprofile", match.Path, ok)
+	}
+	for _, status := range report.Profiles {
+		if filepath.Base(status.Path) != "both.monitorprofile" {
+			continue
+		}
+		found := false
+		for _, line := range status.Differences {
+			found = found || strings.Contains(line, "not connected")
+		}
+		if !found {
+			t.Errorf("both.monitorprofile does not report the unplugged monitor: %q", status.Differences)
+		}
+	}
+}
+
+func TestStatusReportWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		report StatusReport
+		want   string
+	}{
+		{
+			name: "match",
+			report: StatusReport{Profiles: []ProfileStatus{
+				{Path: "/p/home.json"}, {Path: "/p/copy.json"}, {Path: "/p/work.json", Differences: []string{"x"}},
+			}},
+			want: "Current layout matches home.json\n  (so does copy.json)\n",
+		},
+		{
+			name: "closest",
+			report: StatusReport{Profiles: []ProfileStatus{
+				{Path: "/p/home.json", Differences: []string{"DELL P2419H (target id 100): position (0,0) -> (1920,0)"}},
+			}},
+			want: "Unsaved layout; closest profile is home.json (1 difference(s)):\n  DELL P2419H (target id 100): position (0,0) -> (1920,0)\n",
+		},
+		{
+			name:   "only unreadable profiles",
+			report: StatusReport{Profiles: []ProfileStatus{{Path: "/p/broken.json", Err: errors.New("parse profile")}}},
+			want:   "Unsaved layout\n",
+		},
+		{name: "no profiles", want: "Unsaved layout\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tt.report.Write(&b); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package switcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// Names of the ways LoadProfile can prepare a profile for SetDisplayConfig.
+const (
+	// StrategyIdentity re-binds every matched monitor to the target that
+	// reports the same monitor identity.
+	StrategyIdentity = "identity"
+	// StrategyAdapterID keeps the saved source and target IDs and takes the
+	// adapter LUIDs from the current configuration.
+	StrategyAdapterID = "adapter-id"
+	// StrategyAsSaved applies the profile exactly as stored.
+	StrategyAsSaved = "as-saved"
+	// StrategyVirtualMerge copies the profile's modes onto the current
+	// configuration, for virtual displays that only accept their own paths.
+	StrategyVirtualMerge = "virtual-merge"
+)
+
+// errNotApplicable is returned by a strategy that has nothing to try.
+var errNotApplicable = errors.New("not applicable")
+
+// loadState is everything a strategy prepares a profile from. The profile
+// arrays have already been pruned of absent monitors and must not be
+// modified.
+type loadState struct {
+	log           logger
+	virtualInject bool
+
+	paths      []ccd.DisplayConfigPathInfo
+	modes      []ccd.DisplayConfigModeInfo
+	additional []ccd.MonitorAdditionalInfo
+
+	currentPaths      []ccd.DisplayConfigPathInfo
+	currentModes      []ccd.DisplayConfigModeInfo
+	currentAdditional []ccd.MonitorAdditionalInfo
+
+	match monitorMatch
+}
+
+// profileCopy returns copies of the profile arrays a strategy may modify.
+func (st *loadState) profileCopy() ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo) {
+	return append([]ccd.DisplayConfigPathInfo(nil), st.paths...), append([]ccd.DisplayConfigModeInfo(nil), st.modes...)
+}
+
+// injectDesktopModes applies -v virtual desktop injection to prepared arrays.
+func (st *loadState) injectDesktopModes(paths *[]ccd.DisplayConfigPathInfo, modes *[]ccd.DisplayConfigModeInfo) {
+	if st.virtualInject && ensureDesktopImageModes(paths, modes, st.currentModes) {
+		st.log.debugf("Injected missing desktop image info from current configuration")
+	}
+}
+
+type strategy struct {
+	name    string
+	prepare func(st *loadState) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo, []ccd.MonitorAdditionalInfo, error)
+}
+
+var strategies = []strategy{
+	{name: StrategyIdentity, prepare: prepareIdentity},
+	{name: StrategyAdapterID, prepare: prepareAdapterID},
+	{name: StrategyAsSaved, prepare: prepareAsSaved},
+	{name: StrategyVirtualMerge, prepare: prepareVirtualMerge},
+}
+
+func prepareIdentity(st *loadState) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo, []ccd.MonitorAdditionalInfo, error) {
+	if len(st.match.targets) == 0 {
+		return nil, nil, nil, fmt.Errorf("%w: no monitor in the profile matched a connected target", errNotApplicable)
+	}
+	st.log.debugf("Matching monitors by identity")
+	paths, modes := st.profileCopy()
+	if err := rebindToMatchedTargets(paths, modes, st.match, st.currentPaths); err != nil {
+		return nil, nil, nil, err
+	}
+	st.injectDesktopModes(&paths, &modes)
+	return paths, modes, st.additional, nil
+}
+
+func prepareAdapterID(st *loadState) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo, []ccd.MonitorAdditionalInfo, error) {
+	paths, modes := st.profileCopy()
+	matchAdapterIDs(st.log, paths, modes, st.currentPaths)
+	st.injectDesktopModes(&paths, &modes)
+	return paths, modes, st.additional, nil
+}
+
+func prepareAsSaved(st *loadState) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo, []ccd.MonitorAdditionalInfo, error) {
+	paths, modes := st.profileCopy()
+	st.injectDesktopModes(&paths, &modes)
+	return paths, modes, st.additional, nil
+}
+
+func prepareVirtualMerge(st *loadState) ([]ccd.DisplayConfigPathInfo, []ccd.DisplayConfigModeInfo, []ccd.MonitorAdditionalInfo, error) {
+	paths, modes, ok := mergeProfileWithCurrent(st.paths, st.modes, st.currentPaths, st.currentModes)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("%w: the current configuration has no paths or modes to merge into", errNotApplicable)
+	}
+	st.log.debugf("Merging profile modes into the current configuration")
+	return paths, modes, st.currentAdditional, nil
+}
+
+func lookupStrategy(name string) (strategy, bool) {
+	for _, s := range strategies {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return strategy{}, false
+}
+
+// StrategyNames lists every strategy LoadProfile knows.
+func StrategyNames() []string {
+	names := make([]string, len(strategies))
+	for i, s := range strategies {
+		names[i] = s.name
+	}
+	return names
+}
+
+// ParseStrategies splits a comma-separated strategy list and checks every
+// name. Strategies left out are disabled; order is the order they are tried.
+func ParseStrategies(list string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := lookupStrategy(name); !ok {
+			return nil, fmt.Errorf("unknown strategy %q (known: %s)", name, strings.Join(StrategyNames(), ", "))
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("strategy list is empty")
+	}
+	return names, nil
+}
+
+// defaultStrategies is the order used when neither the invocation nor the
+// profile sets one. A complete, unambiguous identity match is trusted over
+// target IDs, which shift after driver updates and cannot tell identical
+// monitors apart; otherwise the saved IDs go first. The virtual-mode merge
+// is only worth trying for profiles with virtual displays.
+func defaultStrategies(noIDMatch bool, match monitorMatch, virtualAware bool) []string {
+	byID := StrategyAdapterID
+	if noIDMatch {
+		byID = StrategyAsSaved
+	}
+	order := []string{byID, StrategyIdentity}
+	if !noIDMatch && match.complete() {
+		order = []string{StrategyIdentity, byID}
+	}
+	if virtualAware {
+		order = append(order, StrategyVirtualMerge)
+	}
+	return order
+}
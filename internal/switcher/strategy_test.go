@@ -0,0 +1,87 @@
+package switcher
+
+import (
+	"fmt"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+)
+
+func TestParseStrategies(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{list: "identity", want: []string{StrategyIdentity}},
+		{list: "as-saved, Identity", want: []string{StrategyAsSaved, StrategyIdentity}},
+		{list: "virtual-merge,,adapter-id,", want: []string{StrategyVirtualMerge, StrategyAdapterID}},
+		{list: "identity,by-name", wantErr: true},
+		{list: "", wantErr: true},
+		{list: " , ", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStrategies(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStrategies(%q) error = %v, want error %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("ParseStrategies(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultStrategies(t *testing.T) {
+	complete := monitorMatch{targets: map[int]currentTarget{0: {}}}
+	partial := monitorMatch{targets: map[int]currentTarget{0: {}}, unmatched: []int{1}}
+	ambiguous := monitorMatch{targets: map[int]currentTarget{0: {}}, ambiguous: []string{"DELL P2419H"}}
+
+	tests := []struct {
+		name         string
+		noIDMatch    bool
+		match        monitorMatch
+		virtualAware bool
+		want         []string
+	}{
+		{name: "complete match", match: complete, want: []string{StrategyIdentity, StrategyAdapterID}},
+		{name: "partial match", match: partial, want: []string{StrategyAdapterID, StrategyIdentity}},
+		{name: "ambiguous match", match: ambiguous, want: []string{StrategyAdapterID, StrategyIdentity}},
+		{name: "no match", want: []string{StrategyAdapterID, StrategyIdentity}},
+		{name: "no ID match", noIDMatch: true, match: complete, want: []string{StrategyAsSaved, StrategyIdentity}},
+		{name: "virtual displays", match: complete, virtualAware: true, want: []string{StrategyIdentity, StrategyAdapterID, StrategyVirtualMerge}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaultStrategies(tt.noIDMatch, tt.match, tt.virtualAware); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("defaultStrategies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSkipsInapplicableStrategy(t *testing.T) {
+	path := saveProfile(t, twin(1, 100, 200, true), SaveOptions{})
+
+	// Other monitors on the same targets: nothing matches by identity, but
+	// the saved target IDs still do.
+	adapter := ccd.LUID{LowPart: 1}
+	m := ccdsim.New(ccdsim.Adapter{ID: adapter, Sources: []uint32{0, 1}, Targets: []ccdsim.Target{
+		target(100, lg()),
+		target(200, lg()),
+	}})
+	report, err := New(m).LoadProfileWithReport(path, quiet(LoadOptions{Strategies: []string{StrategyIdentity, StrategyAdapterID}}))
+	if err != nil {
+		t.Fatalf("LoadProfile() = %v", err)
+	}
+	if len(report.Attempts) != 2 || report.Attempts[0].Skipped == "" {
+		t.Fatalf("attempts = %+v, want identity skipped", report.Attempts)
+	}
+	if applied, _ := report.Applied(); applied.Strategy != StrategyAdapterID {
+		t.Errorf("applied %q, want %q", applied.Strategy, StrategyAdapterID)
+	}
+	if m.SetCalls() != 1 {
+		t.Errorf("%d SetDisplayConfig calls, want 1", m.SetCalls())
+	}
+}
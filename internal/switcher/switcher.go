@@ -7,8 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/confirm"
 	"monitor-profile-switcher/internal/profile"
 )
 
@@ -16,147 +18,375 @@ const (
 	applyFlags = ccd.SdcFlagsApply | ccd.SdcFlagsUseSuppliedDisplayConfig | ccd.SdcFlagsSaveToDatabase | ccd.SdcFlagsNoOptimization | ccd.SdcFlagsAllowChanges
 )
 
-func SaveProfile(path string, debug bool) error {
-	debugf(debug, "Saving profile to: %s", path)
+// Switcher saves, loads and summarizes profiles against a display backend.
+type Switcher struct {
+	backend ccd.DisplayBackend
+}
+
+// New returns a Switcher driving the given backend.
+func New(backend ccd.DisplayBackend) *Switcher {
+	return &Switcher{backend: backend}
+}
+
+// SaveOptions controls how SaveProfile writes a profile.
+type SaveOptions struct {
+	Debug bool
+	// DebugOutput receives the Debug output; nil means stdout.
+	DebugOutput io.Writer
+	// Warnings receives each warning; nil prints them to stderr.
+	Warnings func(message string)
+	// Monitors writes the per-monitor format instead of the raw CCD arrays.
+	Monitors bool
+	// Canonical writes a diff-friendly profile; see profile.Canonicalize.
+	Canonical bool
+	// Names writes enums and flags by name; see profile.SaveOptions.
+	Names bool
+}
+
+func SaveProfile(path string, opts SaveOptions) error {
+	return New(ccd.System).SaveProfile(path, opts)
+}
+
+// LoadOptions controls how LoadProfile applies a profile.
+type LoadOptions struct {
+	Debug         bool
+	NoIDMatch     bool
+	VirtualInject bool
+	// Strategies overrides the order of strategies tried (and leaves out the
+	// rest); see ParseStrategies. When empty, the profile's own list or the
+	// default order is used.
+	Strategies []string
+	// Plan runs the whole matching and fallback chain but validates with
+	// SdcFlagsValidate instead of applying, then writes the final
+	// configuration, its differences from the current one and the strategy
+	// that passed to Output.
+	Plan bool
+	// Wait, when positive, polls until every monitor in the profile is
+	// connected before applying, failing with ErrWaitTimeout after this long.
+	Wait time.Duration
+	// Confirm, when positive, snapshots the active configuration before
+	// applying and restores it unless Confirmer reports the new settings
+	// were kept within this long.
+	Confirm   time.Duration
+	Confirmer Confirmer
+	// Strict makes LoadProfile fail with a *DriftError when the configuration
+	// Windows applied differs from the profile. Differences are always
+	// reported as warnings.
+	Strict bool
+	Output io.Writer
+	// DebugOutput receives the Debug output; nil means stdout.
+	DebugOutput io.Writer
+	// Warnings receives each warning; nil prints them to stderr.
+	Warnings func(message string)
+}
+
+func (o LoadOptions) output() io.Writer {
+	if o.Output == nil {
+		return os.Stdout
+	}
+	return o.Output
+}
+
+func (o LoadOptions) logger() logger {
+	return logger{debug: o.Debug, debugOutput: o.DebugOutput, warn: o.Warnings}
+}
+
+func (o SaveOptions) logger() logger {
+	return logger{debug: o.Debug, debugOutput: o.DebugOutput, warn: o.Warnings}
+}
+
+func (o LoadOptions) confirmer() Confirmer {
+	if o.Confirmer == nil {
+		return confirm.Default()
+	}
+	return o.Confirmer
+}
 
-	paths, modes, additional, err := ccd.GetDisplaySettingsWithFlags(ccd.QueryDisplayFlagsOnlyActivePaths | ccd.QueryDisplayFlagsVirtualModeAware)
+func LoadProfile(path string, opts LoadOptions) error {
+	return New(ccd.System).LoadProfile(path, opts)
+}
+
+func PrintSummary(w io.Writer) error {
+	return New(ccd.System).PrintSummary(w)
+}
+
+func (s *Switcher) SaveProfile(path string, opts SaveOptions) error {
+	log := opts.logger()
+	log.debugf("Saving profile to: %s", path)
+
+	paths, modes, additional, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsOnlyActivePaths|ccd.QueryDisplayFlagsVirtualModeAware)
 	if err != nil {
-		debugf(debug, "VirtualModeAware query failed, falling back to standard query: %v", err)
-		paths, modes, additional, err = ccd.GetDisplaySettings(true)
+		log.debugf("VirtualModeAware query failed, falling back to standard query: %v", err)
+		paths, modes, additional, err = ccd.GetDisplaySettings(s.backend, true)
 		if err != nil {
 			return fmt.Errorf("get display settings: %w", err)
 		}
 	}
 
+	if len(paths) == 0 {
+		return errors.New("no active display paths to save")
+	}
+
 	prof := profileFromCCD(paths, modes, additional)
-	if err := profile.Save(path, prof); err != nil {
+	if opts.Monitors {
+		prof = profile.Profile{Monitors: monitorsFromCCD(paths, modes, additional)}
+	}
+	if fp, err := s.Fingerprint(); err != nil {
+		log.debugf("Fingerprint unavailable: %v", err)
+	} else {
+		prof.Fingerprint = fp.ID
+		log.debugf("Fingerprint: %s", fp.ID)
+	}
+	if err := profile.Save(path, prof, profile.SaveOptions{Canonical: opts.Canonical, Names: opts.Names}); err != nil {
 		return err
 	}
+	if opts.Canonical {
+		log.debugf("Layout hash: %s", profile.LayoutHash(prof))
+	}
 	return nil
 }
 
-func LoadProfile(path string, debug bool, noIDMatch bool, virtualInject bool) error {
-	debugf(debug, "Loading profile from: %s", path)
+func (s *Switcher) LoadProfile(path string, opts LoadOptions) error {
+	_, err := s.LoadProfileWithReport(path, opts)
+	return err
+}
+
+// LoadProfileWithReport loads a profile like LoadProfile and also returns
+// every strategy it tried, with the arrays and flags it passed to
+// SetDisplayConfig.
+func (s *Switcher) LoadProfileWithReport(path string, opts LoadOptions) (LoadReport, error) {
+	report := LoadReport{Profile: path, Succeeded: -1}
+	log := opts.logger()
+	log.debugf("Loading profile from: %s", path)
 
 	prof, err := profile.Load(path)
 	if err != nil {
-		return err
+		return report, err
 	}
 
-	paths, modes, additional := ccdFromProfile(prof)
-	origPaths := append([]ccd.DisplayConfigPathInfo(nil), paths...)
-	origModes := append([]ccd.DisplayConfigModeInfo(nil), modes...)
-
 	virtualAware := profileHasVirtualDisplay(prof)
 
-	currentPaths, currentModes, currentAdditional, err := ccd.GetDisplaySettingsWithFlags(queryFlagsForProfile(false, virtualAware))
-	if err != nil {
-		return fmt.Errorf("get current display settings: %w", err)
-	}
-
-	if !noIDMatch {
-		debugf(debug, "Matching adapter IDs for path info")
-		for i := range paths {
-			for j := range currentPaths {
-				if paths[i].SourceInfo.ID == currentPaths[j].SourceInfo.ID &&
-					paths[i].TargetInfo.ID == currentPaths[j].TargetInfo.ID {
-					paths[i].SourceInfo.AdapterID = currentPaths[j].SourceInfo.AdapterID
-					paths[i].TargetInfo.AdapterID = currentPaths[j].TargetInfo.AdapterID
-					break
-				}
-			}
+	// Wait before compiling: a per-monitor profile is bound to targets
+	// when compiled, and skips the monitors not connected yet.
+	if opts.Wait > 0 {
+		if err := s.waitForMonitors(opts, prof, virtualAware); err != nil {
+			return report, err
 		}
+	}
 
-		debugf(debug, "Matching adapter IDs for mode info")
-		for i := range modes {
-			for j := range paths {
-				if modes[i].ID == paths[j].TargetInfo.ID && modes[i].InfoType == ccd.DisplayConfigModeInfoTypeTarget {
-					for k := range modes {
-						if modes[k].ID == paths[j].SourceInfo.ID &&
-							modes[k].AdapterID.LowPart == modes[i].AdapterID.LowPart &&
-							modes[k].InfoType == ccd.DisplayConfigModeInfoTypeSource {
-							modes[k].AdapterID = paths[j].SourceInfo.AdapterID
-							break
-						}
-					}
-					modes[i].AdapterID = paths[j].TargetInfo.AdapterID
-					break
-				}
-			}
+	var paths []ccd.DisplayConfigPathInfo
+	var modes []ccd.DisplayConfigModeInfo
+	var additional []ccd.MonitorAdditionalInfo
+	if prof.IsLayout() {
+		log.debugf("Compiling %d monitors against the current configuration", len(prof.Monitors))
+		if paths, modes, additional, err = s.compileMonitors(log, prof.Monitors); err != nil {
+			return report, err
 		}
+	} else {
+		paths, modes, additional = ccdFromProfile(prof)
 	}
 
-	if virtualInject {
-		if ensureDesktopImageModes(&paths, &modes, currentModes) {
-			debugf(debug, "Injected missing desktop image info from current configuration")
+	currentPaths, currentModes, currentAdditional, err := ccd.GetDisplaySettingsWithFlags(s.backend, queryFlagsForProfile(false, virtualAware))
+	if err != nil {
+		return report, fmt.Errorf("get current display settings: %w", err)
+	}
+
+	targets := currentTargets(s.backend, currentPaths)
+	match := matchMonitors(paths, modes, additional, targets)
+
+	var dropped []monitorIdentity
+	paths, modes, additional, dropped = pruneAbsentTargets(paths, modes, additional, targetsPresent(paths, currentPaths, match))
+	for _, target := range dropped {
+		log.warnf("monitor %s is not connected; skipping it", target)
+	}
+	if len(paths) == 0 && len(dropped) > 0 {
+		return report, fmt.Errorf("none of the %d monitors in the profile are connected", len(dropped))
+	}
+	if len(dropped) > 0 {
+		match = matchMonitors(paths, modes, additional, targets)
+	}
+	match.report(log, paths, modes, additional)
+
+	order := opts.Strategies
+	if len(order) == 0 && len(prof.Strategies) > 0 {
+		if order, err = ParseStrategies(strings.Join(prof.Strategies, ",")); err != nil {
+			return report, fmt.Errorf("profile strategies: %w", err)
 		}
 	}
+	if len(order) == 0 {
+		order = defaultStrategies(opts.NoIDMatch, match, virtualAware)
+	}
+	report.Strategies = order
 
 	flags := applyFlags
 	if virtualAware {
 		flags |= ccd.SdcFlagsVirtualModeAware
 	}
+	planFlags := flags
+	if opts.Plan {
+		flags = flags&^(ccd.SdcFlagsApply|ccd.SdcFlagsSaveToDatabase) | ccd.SdcFlagsValidate
+	}
 
-	if err := ccd.SetDisplayConfig(paths, modes, flags); err != nil {
-		debugf(debug, "Primary SetDisplayConfig failed: %v", err)
-		if len(currentAdditional) > 0 && len(additional) > 0 {
-			debugf(debug, "Trying alternative matching method")
-			paths = append([]ccd.DisplayConfigPathInfo(nil), origPaths...)
-			modes = append([]ccd.DisplayConfigModeInfo(nil), origModes...)
+	confirming := opts.Confirm > 0 && !opts.Plan
+	var snap snapshot
+	if confirming {
+		if snap, err = s.takeSnapshot(); err != nil {
+			return report, err
+		}
+		log.debugf("Saved %d active paths to restore if the profile is not confirmed", len(snap.paths))
+	}
 
-			for i := range modes {
-				for j := range currentAdditional {
-					if currentAdditional[j].MonitorFriendlyDevice == "" || additional[i].MonitorFriendlyDevice == "" {
-						continue
-					}
-					if currentAdditional[j].MonitorFriendlyDevice == additional[i].MonitorFriendlyDevice {
-						originalID := modes[i].AdapterID
-						for p := range paths {
-							if paths[p].TargetInfo.AdapterID.LowPart == originalID.LowPart &&
-								paths[p].TargetInfo.AdapterID.HighPart == originalID.HighPart {
-								paths[p].TargetInfo.AdapterID = currentModes[j].AdapterID
-								paths[p].SourceInfo.AdapterID = currentModes[j].AdapterID
-								paths[p].TargetInfo.ID = currentModes[j].ID
-							}
-						}
-						for k := range modes {
-							if modes[k].AdapterID.LowPart == originalID.LowPart &&
-								modes[k].AdapterID.HighPart == originalID.HighPart {
-								modes[k].AdapterID = currentModes[j].AdapterID
-							}
-						}
-						modes[i].AdapterID = currentModes[j].AdapterID
-						modes[i].ID = currentModes[j].ID
-						break
-					}
-				}
+	state := &loadState{
+		log:               log,
+		virtualInject:     opts.VirtualInject,
+		paths:             paths,
+		modes:             modes,
+		additional:        additional,
+		currentPaths:      currentPaths,
+		currentModes:      currentModes,
+		currentAdditional: currentAdditional,
+		match:             match,
+	}
+	err = s.runStrategies(state, order, flags, &report)
+	if log.debug {
+		_ = report.Write(log.debugOutput, true)
+	}
+
+	if opts.Plan {
+		report.Plan = s.plan(report, planFlags, prof.IsLayout())
+		if writeErr := writePlan(opts.output(), report); writeErr != nil && err == nil {
+			err = writeErr
+		}
+		return report, err
+	}
+	if err != nil {
+		return report, err
+	}
+	applied, _ := report.Applied()
+	drift := s.verifyApplied(log, applied, prof.IsLayout())
+	if confirming {
+		if err := s.confirmOrRevert(opts, snap); err != nil {
+			return report, err
+		}
+	}
+	if opts.Strict {
+		return report, drift
+	}
+	return report, nil
+}
+
+// runStrategies tries each strategy in order until SetDisplayConfig accepts
+// one, recording every attempt in report. A failure that no re-binding can
+// fix ends the run early.
+func (s *Switcher) runStrategies(state *loadState, order []string, flags ccd.SdcFlags, report *LoadReport) error {
+	var lastErr error
+	for _, name := range order {
+		strat, ok := lookupStrategy(name)
+		if !ok {
+			return fmt.Errorf("unknown strategy %q", name)
+		}
+		paths, modes, additional, err := strat.prepare(state)
+		if err != nil {
+			state.log.debugf("Strategy %s skipped: %v", name, err)
+			report.Attempts = append(report.Attempts, Attempt{Strategy: name, Skipped: err.Error()})
+			continue
+		}
+
+		err = ccd.SetDisplayConfig(s.backend, paths, modes, flags)
+		report.Attempts = append(report.Attempts, Attempt{
+			Strategy:   name,
+			Flags:      flags,
+			Paths:      paths,
+			Modes:      modes,
+			Additional: additional,
+			Err:        err,
+		})
+		if err == nil {
+			report.Succeeded = len(report.Attempts) - 1
+			return nil
+		}
+		state.log.debugf("Strategy %s failed: %v", name, err)
+		lastErr = err
+		if !rebindMayHelp(err) {
+			state.log.debugf("Not trying other strategies: %v fails them all alike", errorCode(err))
+			return err
+		}
+	}
+	if lastErr == nil {
+		return errors.New("no strategy could prepare the profile")
+	}
+	return fmt.Errorf("every strategy failed, last: %w", lastErr)
+}
+
+// rebindMayHelp reports whether a failed SetDisplayConfig may succeed with
+// the profile bound to the current targets another way, that is whether
+// the remaining strategies run. This is the one place that decides it:
+//
+//   - ERROR_ACCESS_DENIED and ERROR_NOT_SUPPORTED stop the run: the
+//     session or the driver refuses every configuration alike.
+//   - ERROR_GEN_FAILURE continues: the driver rejected a mode, and the
+//     virtual-merge and as-saved strategies send other modes.
+//   - ERROR_INVALID_PARAMETER and ERROR_BAD_CONFIGURATION continue: another
+//     binding of adapters, sources and targets may be valid.
+//   - NOT_RECORDED continues, so a replay tries what the recorded run did.
+//   - Unknown codes continue.
+//
+// A strategy that cannot prepare its arrays is skipped and never stops the
+// run. Failures before the strategies run, such as reading the current
+// configuration (ERROR_INSUFFICIENT_BUFFER once its queries are used up),
+// end the load without trying any.
+func rebindMayHelp(err error) bool {
+	return !errors.Is(err, ccd.ErrAccessDenied) &&
+		!errors.Is(err, ccd.ErrNotSupported)
+}
+
+// errorCode returns the Win32 code carried by err, or err itself.
+func errorCode(err error) error {
+	var code ccd.Errno
+	if errors.As(err, &code) {
+		return code
+	}
+	return err
+}
+
+// matchAdapterIDs re-binds adapter LUIDs from the current configuration to
+// profile paths with the same source and target IDs.
+func matchAdapterIDs(log logger, paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, currentPaths []ccd.DisplayConfigPathInfo) {
+	log.debugf("Matching adapter IDs for path info")
+	for i := range paths {
+		for j := range currentPaths {
+			if paths[i].SourceInfo.ID == currentPaths[j].SourceInfo.ID &&
+				paths[i].TargetInfo.ID == currentPaths[j].TargetInfo.ID {
+				paths[i].SourceInfo.AdapterID = currentPaths[j].SourceInfo.AdapterID
+				paths[i].TargetInfo.AdapterID = currentPaths[j].TargetInfo.AdapterID
+				break
 			}
+		}
+	}
 
-			if err := ccd.SetDisplayConfig(paths, modes, flags); err != nil {
-				if virtualAware {
-					mergedPaths, mergedModes, ok := mergeProfileWithCurrent(origPaths, origModes, currentPaths, currentModes)
-					if ok {
-						debugf(debug, "Trying virtual-mode merge fallback")
-						if mergeErr := ccd.SetDisplayConfig(mergedPaths, mergedModes, flags); mergeErr == nil {
-							return nil
-						} else {
-							debugf(debug, "Merge fallback failed: %v", mergeErr)
-						}
+	log.debugf("Matching adapter IDs for mode info")
+	for i := range modes {
+		for j := range paths {
+			if modes[i].ID == paths[j].TargetInfo.ID && modes[i].InfoType == ccd.DisplayConfigModeInfoTypeTarget {
+				for k := range modes {
+					if modes[k].ID == paths[j].SourceInfo.ID &&
+						modes[k].AdapterID.LowPart == modes[i].AdapterID.LowPart &&
+						modes[k].InfoType == ccd.DisplayConfigModeInfoTypeSource {
+						modes[k].AdapterID = paths[j].SourceInfo.AdapterID
+						break
 					}
 				}
-				return fmt.Errorf("SetDisplayConfig failed (alternative): %w", err)
+				modes[i].AdapterID = paths[j].TargetInfo.AdapterID
+				break
 			}
-			return nil
 		}
-		return fmt.Errorf("SetDisplayConfig failed: %w", err)
 	}
-	return nil
 }
 
-func PrintSummary(w io.Writer) error {
-	paths, modes, additional, err := ccd.GetDisplaySettingsWithFlags(ccd.QueryDisplayFlagsOnlyActivePaths | ccd.QueryDisplayFlagsVirtualModeAware)
+func (s *Switcher) PrintSummary(w io.Writer) error {
+	paths, modes, additional, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsOnlyActivePaths|ccd.QueryDisplayFlagsVirtualModeAware)
 	if err != nil {
-		paths, modes, additional, err = ccd.GetDisplaySettings(true)
+		paths, modes, additional, err = ccd.GetDisplaySettings(s.backend, true)
 		if err != nil {
 			return err
 		}
@@ -167,16 +397,56 @@ func PrintSummary(w io.Writer) error {
 	return err
 }
 
-func debugf(enabled bool, format string, args ...any) {
-	if !enabled {
+// SummarizeProfile formats the profile at path the way PrintSummary formats
+// the live configuration. Per-monitor profiles are listed per monitor.
+func SummarizeProfile(path string) (string, error) {
+	prof, err := profile.Load(path)
+	if err != nil {
+		return "", err
+	}
+	if prof.IsLayout() {
+		return formatMonitorsSummary(prof.Monitors), nil
+	}
+	paths, modes, additional := ccdFromProfile(prof)
+	return formatSummary(paths, modes, additional), nil
+}
+
+// logger writes the debug output and warnings of one SaveProfile or
+// LoadProfile call, as its options direct.
+type logger struct {
+	debug       bool
+	debugOutput io.Writer
+	warn        func(message string)
+}
+
+func (l logger) debugf(format string, args ...any) {
+	if !l.debug {
+		return
+	}
+	w := l.debugOutput
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintf(w, format+"\n", args...)
+}
+
+func (l logger) warnf(format string, args ...any) {
+	if l.warn != nil {
+		l.warn(fmt.Sprintf(format, args...))
 		return
 	}
-	fmt.Printf(format+"\n", args...)
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
 }
 
 func formatSummary(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, additional []ccd.MonitorAdditionalInfo) string {
 	var builder strings.Builder
-	fmt.Fprintf(&builder, "Active display paths: %d\n", len(paths))
+	activeCount := 0
+	for _, path := range paths {
+		if path.Flags&uint32(ccd.DisplayConfigFlagPathActive) != 0 {
+			activeCount++
+		}
+	}
+	fmt.Fprintf(&builder, "Active display paths: %d\n", activeCount)
 
 	for i, path := range paths {
 		active := (path.Flags & uint32(ccd.DisplayConfigFlagPathActive)) != 0
@@ -186,30 +456,76 @@ func formatSummary(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigM
 		}
 		fmt.Fprintf(&builder, "Path %d (%s)\n", i+1, state)
 
-		targetIdx := int(path.TargetInfo.ModeInfoIdx)
+		targetIdx, hasTarget := path.TargetModeIdx()
 		targetName := "Unknown"
-		if targetIdx >= 0 && targetIdx < len(additional) {
-			if additional[targetIdx].Valid && additional[targetIdx].MonitorFriendlyDevice != "" {
-				targetName = additional[targetIdx].MonitorFriendlyDevice
-			}
+		if identity := profileIdentity(&path, modes, additional); identity.info.Valid && identity.info.MonitorFriendlyDevice != "" {
+			targetName = identity.info.MonitorFriendlyDevice
 		}
 
 		fmt.Fprintf(&builder, "  Target: %s (id %d, adapter %s)\n", targetName, path.TargetInfo.ID, formatAdapterID(path.TargetInfo.AdapterID))
 
-		if targetIdx >= 0 && targetIdx < len(modes) && modes[targetIdx].InfoType == ccd.DisplayConfigModeInfoTypeTarget {
+		if hasTarget && targetIdx < len(modes) && modes[targetIdx].InfoType == ccd.DisplayConfigModeInfoTypeTarget {
 			targetMode := modes[targetIdx].TargetMode()
 			refresh := formatRefreshRate(targetMode.TargetVideoSignalInfo.VSyncFreq)
 			fmt.Fprintf(&builder, "  Refresh: %s\n", refresh)
 			fmt.Fprintf(&builder, "  Active size: %dx%d\n", targetMode.TargetVideoSignalInfo.ActiveSize.Cx, targetMode.TargetVideoSignalInfo.ActiveSize.Cy)
+			fmt.Fprintf(&builder, "  Video standard: %s\n", targetMode.TargetVideoSignalInfo.VideoStandard)
 		}
 
-		sourceIdx := int(path.SourceInfo.ModeInfoIdx)
-		if sourceIdx >= 0 && sourceIdx < len(modes) && modes[sourceIdx].InfoType == ccd.DisplayConfigModeInfoTypeSource {
+		sourceIdx, hasSource := path.SourceModeIdx()
+		if hasSource && sourceIdx < len(modes) && modes[sourceIdx].InfoType == ccd.DisplayConfigModeInfoTypeSource {
 			sourceMode := modes[sourceIdx].SourceMode()
-			fmt.Fprintf(&builder, "  Source: %dx%d @ (%d,%d), pixel format %d\n", sourceMode.Width, sourceMode.Height, sourceMode.Position.X, sourceMode.Position.Y, sourceMode.PixelFormat)
+			fmt.Fprintf(&builder, "  Source: %dx%d @ (%d,%d), pixel format %s\n", sourceMode.Width, sourceMode.Height, sourceMode.Position.X, sourceMode.Position.Y, sourceMode.PixelFormat)
 		}
 
-		fmt.Fprintf(&builder, "  Rotation: %d, Scaling: %d, TargetAvailable: %t\n", path.TargetInfo.Rotation, path.TargetInfo.Scaling, path.TargetInfo.TargetAvailable != 0)
+		fmt.Fprintf(&builder, "  Output: %s, Scan line ordering: %s\n", path.TargetInfo.OutputTechnology, path.TargetInfo.ScanLineOrdering)
+		fmt.Fprintf(&builder, "  Rotation: %s, Scaling: %s, TargetAvailable: %t\n", path.TargetInfo.Rotation, path.TargetInfo.Scaling, path.TargetInfo.TargetAvailable != 0)
+		fmt.Fprintf(&builder, "  Flags: %s, Source status: %s, Target status: %s\n", ccd.DisplayConfigFlags(path.Flags), path.SourceInfo.StatusFlags, path.TargetInfo.StatusFlags)
+	}
+
+	return builder.String()
+}
+
+// formatMonitorsSummary lists the entries of a per-monitor profile in the
+// style of formatSummary.
+func formatMonitorsSummary(monitors []profile.Monitor) string {
+	var builder strings.Builder
+	enabled := 0
+	for _, monitor := range monitors {
+		if monitor.Enabled {
+			enabled++
+		}
+	}
+	fmt.Fprintf(&builder, "Enabled monitors: %d\n", enabled)
+
+	for i, monitor := range monitors {
+		state := "disabled"
+		if monitor.Enabled {
+			state = "enabled"
+		}
+		if monitor.Primary {
+			state += ", primary"
+		}
+		fmt.Fprintf(&builder, "Monitor %d (%s)\n", i+1, state)
+
+		name := monitor.Name
+		if name == "" {
+			name = "Unknown"
+		}
+		fmt.Fprintf(&builder, "  Target: %s (id %d)\n", name, monitor.TargetID)
+		if monitor.DevicePath != "" {
+			fmt.Fprintf(&builder, "  Device path: %s\n", monitor.DevicePath)
+		}
+		if !monitor.Enabled {
+			continue
+		}
+		fmt.Fprintf(&builder, "  Refresh: %s\n", formatRefreshRate(rationalFromHz(monitor.RefreshHz)))
+		fmt.Fprintf(&builder, "  Resolution: %dx%d @ (%d,%d)\n", monitor.Width, monitor.Height, monitor.Position.X, monitor.Position.Y)
+		scaling := monitor.Scaling
+		if scaling == "" {
+			scaling = "preferred"
+		}
+		fmt.Fprintf(&builder, "  Rotation: %d, Scaling: %s\n", monitor.Rotation, scaling)
 	}
 
 	return builder.String()
@@ -250,12 +566,10 @@ func ResolveProfilePath(input string, createDir bool) (string, error) {
 		return cleaned, nil
 	}
 
-	homeDir, err := os.UserHomeDir()
+	profileDir, err := ProfileDir()
 	if err != nil {
-		return "", fmt.Errorf("resolve home dir: %w", err)
+		return "", err
 	}
-
-	profileDir := filepath.Join(homeDir, "Monitor Profiles")
 	if createDir {
 		if err := os.MkdirAll(profileDir, 0755); err != nil {
 			return "", fmt.Errorf("create profile dir: %w", err)
@@ -265,6 +579,16 @@ func ResolveProfilePath(input string, createDir bool) (string, error) {
 	return filepath.Join(profileDir, cleaned), nil
 }
 
+// ProfileDir returns the directory bare profile names resolve to:
+// %USERPROFILE%\Monitor Profiles.
+func ProfileDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home dir: %w", err)
+	}
+	return filepath.Join(homeDir, "Monitor Profiles"), nil
+}
+
 func isExplicitPath(path string) bool {
 	if filepath.IsAbs(path) {
 		return true
@@ -361,20 +685,27 @@ func ensureDesktopImageModes(paths *[]ccd.DisplayConfigPathInfo, modes *[]ccd.Di
 		if !ok {
 			continue
 		}
+		path := &(*paths)[i]
+		if !path.VirtualModeAware() {
+			// The flag switches the source index to the packed layout as
+			// well; left plain, index N would read as source mode 0 in
+			// clone group N. Paths sharing a source mode share a clone group.
+			sourceIdx, hasSource := path.SourceModeIdx()
+			if !hasSource {
+				sourceIdx = -1
+			}
+			path.SourceInfo.ModeInfoIdx = ccd.PackSourceModeIdx(uint32(sourceIdx), sourceIdx)
+		}
 		desktopIdx := len(*modes)
 		*modes = append(*modes, desktopMode)
-		(*paths)[i].Flags |= uint32(ccd.DisplayConfigFlagPathSupportVirtualMode)
-		(*paths)[i].TargetInfo.ModeInfoIdx = packTargetModeIndices(targetIdx, desktopIdx)
+		path.Flags |= uint32(ccd.DisplayConfigFlagPathSupportVirtualMode)
+		path.TargetInfo.ModeInfoIdx = ccd.PackTargetModeIdx(targetIdx, desktopIdx)
 		changed = true
 	}
 
 	return changed
 }
 
-func packTargetModeIndices(targetIdx int, desktopIdx int) uint32 {
-	return uint32(uint32(uint16(desktopIdx)) | (uint32(uint16(targetIdx)) << 16))
-}
-
 type modeKey struct {
 	infoType ccd.DisplayConfigModeInfoType
 	id       uint32
@@ -0,0 +1,184 @@
+package switcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+)
+
+// virtualPair returns a machine with a DELL monitor as target 100 and a
+// virtual display as target 200, both active. Only with capable set do the
+// targets accept virtual-mode-aware paths and report desktop image modes.
+func virtualPair(capable bool) *ccdsim.Machine {
+	adapter := ccd.LUID{LowPart: 1}
+	virtual := target(200, ccdsim.Monitor{FriendlyName: "Virtual Display", DevicePath: `\\?\DISPLAY#MTT1337#1&2&0&UID256#{e6f07b5f}`})
+	monitor := target(100, dell("UID4352"))
+	virtual.VirtualModeCapable, monitor.VirtualModeCapable = capable, capable
+	m := ccdsim.New(ccdsim.Adapter{ID: adapter, Sources: []uint32{0, 1}, Targets: []ccdsim.Target{monitor, virtual}})
+	activate(m, adapter, 0, 100, 0, 1920, 1080)
+	activate(m, adapter, 1, 200, 1920, 1280, 720)
+	return m
+}
+
+func TestVirtualInjectPacksSourceModeIndex(t *testing.T) {
+	// Saved where the driver did not report desktop image modes, the
+	// profile uses plain mode indices.
+	path := saveProfile(t, virtualPair(false), SaveOptions{})
+
+	for _, strategy := range []string{StrategyIdentity, StrategyAdapterID, StrategyAsSaved} {
+		t.Run(strategy, func(t *testing.T) {
+			m := virtualPair(true)
+			report, err := New(m).LoadProfileWithReport(path, quiet(LoadOptions{VirtualInject: true, Strategies: []string{strategy}}))
+			if err != nil {
+				t.Fatalf("LoadProfile() = %v", err)
+			}
+			applied, _ := report.Applied()
+			for _, p := range applied.Paths {
+				if !p.VirtualModeAware() {
+					t.Errorf("path to target %d was not switched to virtual mode", p.TargetInfo.ID)
+					continue
+				}
+				idx, ok := p.SourceModeIdx()
+				if !ok || applied.Modes[idx].InfoType != ccd.DisplayConfigModeInfoTypeSource || applied.Modes[idx].ID != p.SourceInfo.ID {
+					t.Errorf("path to target %d: packed source mode index %d does not reference source %d", p.TargetInfo.ID, idx, p.SourceInfo.ID)
+				}
+			}
+			for _, active := range m.Active() {
+				if active.Desktop == nil {
+					t.Errorf("target %d was applied without a desktop image mode", active.Path.TargetInfo.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestRebindMayHelp(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{ccd.ErrInvalidParameter, true},
+		{ccd.ErrBadConfiguration, true},
+		{ccd.ErrGenFailure, true},
+		{ccd.ErrNotRecorded, true},
+		{ccd.Errno(1234), true},
+		{fmt.Errorf("SetDisplayConfig: %w", ccd.ErrGenFailure), true},
+		{ccd.ErrAccessDenied, false},
+		{ccd.ErrNotSupported, false},
+		{fmt.Errorf("SetDisplayConfig: %w", ccd.ErrAccessDenied), false},
+	}
+	for _, tt := range tests {
+		if got := rebindMayHelp(tt.err); got != tt.want {
+			t.Errorf("rebindMayHelp(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestLoadAfterFailedStrategy(t *testing.T) {
+	strategies := []string{StrategyIdentity, StrategyAdapterID, StrategyAsSaved}
+	tests := []struct {
+		name string
+		code uint32
+		// want is the number of SetDisplayConfig calls made, the last of
+		// which succeeds unless wantErr is set.
+		want    int
+		wantErr bool
+	}{
+		{name: "mode rejected by the driver", code: ccd.ErrorGenFailure, want: 2},
+		{name: "invalid parameter", code: ccd.ErrorInvalidParameter, want: 2},
+		{name: "access denied", code: ccd.ErrorAccessDenied, want: 1, wantErr: true},
+		{name: "not supported", code: ccd.ErrorNotSupported, want: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := twin(1, 100, 200, true)
+			path := saveProfile(t, m, SaveOptions{})
+			m.FailSetDisplayConfig(tt.code)
+
+			report, err := New(m).LoadProfileWithReport(path, quiet(LoadOptions{Strategies: strategies}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadProfile() = %v, want error %v", err, tt.wantErr)
+			}
+			if !errors.Is(report.Attempts[0].Err, ccd.Errno(tt.code)) {
+				t.Errorf("first attempt failed with %v, want %v", report.Attempts[0].Err, ccd.Errno(tt.code))
+			}
+			if m.SetCalls() != tt.want {
+				t.Errorf("%d SetDisplayConfig calls, want %d", m.SetCalls(), tt.want)
+			}
+			if applied, ok := report.Applied(); !tt.wantErr && (!ok || applied.Strategy != StrategyAdapterID) {
+				t.Errorf("applied %q, want %q", applied.Strategy, StrategyAdapterID)
+			}
+		})
+	}
+}
+
+func TestLoadOutputPerCall(t *testing.T) {
+	m := twin(1, 100, 200, true)
+	path := saveProfile(t, m, SaveOptions{})
+	m.SetPresent(ccd.LUID{LowPart: 1}, 200, false)
+
+	// Two loads with their own sinks, as two callers in one process.
+	type sinks struct {
+		debug    strings.Builder
+		warnings []string
+	}
+	var first, second sinks
+	for _, s := range []*sinks{&first, &second} {
+		opts := quiet(LoadOptions{Debug: true, DebugOutput: &s.debug, Warnings: func(message string) {
+			s.warnings = append(s.warnings, message)
+		}})
+		if err := New(m).LoadProfile(path, opts); err != nil {
+			t.Fatalf("LoadProfile() = %v", err)
+		}
+	}
+	for i, s := range []*sinks{&first, &second} {
+		if len(s.warnings) != 1 || !strings.Contains(s.warnings[0], "is not connected; skipping it") {
+			t.Errorf("load %d: warnings = %q, want the unplugged monitor once", i, s.warnings)
+		}
+		if strings.Count(s.debug.String(), "Loading profile from: ") != 1 {
+			t.Errorf("load %d: debug output =\n%s\nwant one load", i, s.debug.String())
+		}
+	}
+}
+
+func TestSummarizeProfile(t *testing.T) {
+	oneOff := twin(1, 100, 200, false)
+	activate(oneOff, ccd.LUID{LowPart: 1}, 0, 100, 0, 1920, 1080)
+
+	tests := []struct {
+		name    string
+		m       *ccdsim.Machine
+		virtual bool
+	}{
+		{name: "two monitors", m: twin(1, 100, 200, true)},
+		{name: "one monitor off", m: oneOff},
+		{name: "virtual mode aware", m: virtualPair(true), virtual: true},
+		{name: "plain mode indices", m: virtualPair(false)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := saveProfile(t, tt.m, SaveOptions{})
+			var live strings.Builder
+			if err := New(tt.m).PrintSummary(&live); err != nil {
+				t.Fatalf("PrintSummary() = %v", err)
+			}
+			got, err := SummarizeProfile(path)
+			if err != nil {
+				t.Fatalf("SummarizeProfile() = %v", err)
+			}
+			if got != live.String() {
+				t.Errorf("SummarizeProfile() =\n%s\nwant the live summary\n%s", got, live.String())
+			}
+			if strings.Count(got, "  Source: ") != strings.Count(got, " (active)") {
+				t.Errorf("a source mode was not resolved:\n%s", got)
+			}
+			if strings.Contains(got, "supportVirtualMode") != tt.virtual {
+				t.Errorf("summary with virtual mode %v:\n%s", tt.virtual, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package switcher
+
+import (
+	"fmt"
+
+	"monitor-profile-switcher/internal/ccd"
+)
+
+// DriftError reports that Windows applied a profile with different
+// resolutions, positions, refresh rates, rotation or scaling than requested.
+type DriftError struct {
+	// Changes lists each difference as "<monitor>: <property> <requested> -> <applied>".
+	Changes []string
+}
+
+func (e *DriftError) Error() string {
+	if len(e.Changes) == 1 {
+		return "applied configuration differs from the profile: " + e.Changes[0]
+	}
+	return fmt.Sprintf("applied configuration differs from the profile in %d places", len(e.Changes))
+}
+
+// verifyApplied re-queries the active configuration after a successful
+// SetDisplayConfig and compares it with what the applied attempt requested.
+// Differences are reported as warnings and returned as a *DriftError.
+func (s *Switcher) verifyApplied(log logger, applied Attempt, perMonitor bool) error {
+	appliedPaths, appliedModes, appliedAdditional, err := ccd.GetDisplaySettingsWithFlags(s.backend, ccd.QueryDisplayFlagsOnlyActivePaths|ccd.QueryDisplayFlagsVirtualModeAware)
+	if err != nil {
+		appliedPaths, appliedModes, appliedAdditional, err = ccd.GetDisplaySettings(s.backend, true)
+	}
+	if err != nil {
+		log.warnf("could not verify the applied configuration: %v", err)
+		return nil
+	}
+
+	changes := diffLayouts(requestedLayouts(applied, perMonitor), layoutsFromCCD(appliedPaths, appliedModes, appliedAdditional))
+	if len(changes) == 0 {
+		log.debugf("Verified applied configuration: matches the profile")
+		return nil
+	}
+	for _, change := range changes {
+		log.warnf("not applied as saved: %s", change)
+	}
+	return &DriftError{Changes: changes}
+}
@@ -0,0 +1,98 @@
+package switcher
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+)
+
+func TestVerifyApplied(t *testing.T) {
+	luid := ccd.LUID{LowPart: 1}
+	swapped := twin(1, 100, 200, false)
+	activate(swapped, luid, 0, 100, 1920, 1920, 1080)
+	activate(swapped, luid, 1, 200, 0, 1920, 1080)
+	path := saveProfile(t, swapped, SaveOptions{})
+
+	tests := []struct {
+		name   string
+		adjust func([]ccdsim.ActivePath)
+		strict bool
+		want   []string
+	}{
+		{
+			name:   "applied as saved",
+			strict: true,
+		},
+		{
+			name: "drift without strict",
+			adjust: func(active []ccdsim.ActivePath) {
+				active[0].Source.Position.X += 8
+			},
+		},
+		{
+			name: "position and refresh drift",
+			adjust: func(active []ccdsim.ActivePath) {
+				active[0].Source.Position.X += 8
+				active[1].Path.TargetInfo.RefreshRate = ccd.DisplayConfigRational{Numerator: 59940, Denominator: 1000}
+			},
+			strict: true,
+			want: []string{
+				"DELL P2419H (target id 100): position (1920,0) -> (1928,0)",
+				"DELL P2419H (target id 200): refresh 60.00 Hz (60/1) -> 59.94 Hz (59940/1000)",
+			},
+		},
+		{
+			name: "same refresh with another denominator",
+			adjust: func(active []ccdsim.ActivePath) {
+				active[0].Path.TargetInfo.RefreshRate = ccd.DisplayConfigRational{Numerator: 60000, Denominator: 1000}
+			},
+			strict: true,
+		},
+		{
+			name: "rotation and scaling",
+			adjust: func(active []ccdsim.ActivePath) {
+				active[0].Path.TargetInfo.Rotation = ccd.DisplayConfigRotationRotate180
+				active[0].Path.TargetInfo.Scaling = ccd.DisplayConfigScalingStretched
+			},
+			strict: true,
+			want: []string{
+				"DELL P2419H (target id 100): rotation identity -> rotate180",
+				"DELL P2419H (target id 100): scaling preferred -> stretched",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := twin(1, 100, 200, true)
+			m.AdjustApplied(tt.adjust)
+			err := New(m).LoadProfile(path, quiet(LoadOptions{Strict: tt.strict}))
+			var drift *DriftError
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("LoadProfile() = %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &drift) {
+				t.Fatalf("LoadProfile() = %v, want a *DriftError", err)
+			}
+			if fmt.Sprint(drift.Changes) != fmt.Sprint(tt.want) {
+				t.Errorf("changes = %q, want %q", drift.Changes, tt.want)
+			}
+		})
+	}
+}
+
+func TestDriftError(t *testing.T) {
+	one := &DriftError{Changes: []string{"a: rotation identity -> rotate90"}}
+	if got := one.Error(); got != "applied configuration differs from the profile: a: rotation identity -> rotate90" {
+		t.Errorf("Error() = %q", got)
+	}
+	two := &DriftError{Changes: []string{"a", "b"}}
+	if got := two.Error(); got != "applied configuration differs from the profile in 2 places" {
+		t.Errorf("Error() = %q", got)
+	}
+}
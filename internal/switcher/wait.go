@@ -0,0 +1,112 @@
+package switcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/profile"
+)
+
+// ErrWaitTimeout is returned by LoadProfile when LoadOptions.Wait expires
+// before every monitor in the profile is connected.
+var ErrWaitTimeout = errors.New("timed out waiting for monitors")
+
+const (
+	waitFirstDelay = 250 * time.Millisecond
+	waitMaxDelay   = 4 * time.Second
+)
+
+// waitForMonitors polls the full path list until every monitor in the
+// profile is connected: the enabled monitors of a per-monitor profile, or
+// the targets of a saved CCD configuration.
+func (s *Switcher) waitForMonitors(opts LoadOptions, prof profile.Profile, virtualAware bool) error {
+	if prof.IsLayout() {
+		var identities []monitorIdentity
+		for _, monitor := range prof.Monitors {
+			if monitor.Enabled {
+				identities = append(identities, monitorIdentityOf(monitor))
+			}
+		}
+		return waitFor(opts, len(identities), func() ([]string, error) {
+			return s.missingMonitors(identities)
+		})
+	}
+	paths, modes, additional := ccdFromProfile(prof)
+	return waitFor(opts, len(paths), func() ([]string, error) {
+		return s.missingTargets(paths, modes, additional, virtualAware)
+	})
+}
+
+// waitFor calls missing until it reports none of the total monitors
+// missing, backing off between polls and reporting progress.
+func waitFor(opts LoadOptions, total int, missing func() ([]string, error)) error {
+	start := time.Now()
+	deadline := start.Add(opts.Wait)
+	delay := waitFirstDelay
+	lastReport := ""
+	for {
+		absent, err := missing()
+		if err == nil && len(absent) == 0 {
+			if lastReport != "" {
+				fmt.Fprintf(opts.output(), "All %d monitors connected after %s\n", total, time.Since(start).Round(100*time.Millisecond))
+			}
+			return nil
+		}
+
+		status := fmt.Sprintf("%d of %d monitors not connected: %s", len(absent), total, strings.Join(absent, ", "))
+		if err != nil {
+			status = fmt.Sprintf("display query failed: %v", err)
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w after %s: %s", ErrWaitTimeout, opts.Wait, status)
+		}
+		if status != lastReport {
+			fmt.Fprintf(opts.output(), "Waiting up to %s: %s\n", remaining.Round(time.Second), status)
+			lastReport = status
+		}
+
+		if delay > remaining {
+			delay = remaining
+		}
+		time.Sleep(delay)
+		if delay *= 2; delay > waitMaxDelay {
+			delay = waitMaxDelay
+		}
+	}
+}
+
+// missingTargets lists the profile's monitors that no available target
+// matches, by identity or by saved target ID.
+func (s *Switcher) missingTargets(paths []ccd.DisplayConfigPathInfo, modes []ccd.DisplayConfigModeInfo, additional []ccd.MonitorAdditionalInfo, virtualAware bool) ([]string, error) {
+	currentPaths, _, _, err := ccd.GetDisplaySettingsWithFlags(s.backend, queryFlagsForProfile(false, virtualAware))
+	if err != nil {
+		return nil, err
+	}
+	match := matchMonitors(paths, modes, additional, currentTargets(s.backend, currentPaths))
+	var missing []string
+	for i, present := range targetsPresent(paths, currentPaths, match) {
+		if !present {
+			missing = append(missing, profileIdentity(&paths[i], modes, additional).String())
+		}
+	}
+	return missing, nil
+}
+
+// missingMonitors lists the per-monitor entries that no available target
+// matches by identity, as compileMonitors would match them.
+func (s *Switcher) missingMonitors(identities []monitorIdentity) ([]string, error) {
+	currentPaths, _, _, err := ccd.GetDisplaySettings(s.backend, false)
+	if err != nil {
+		return nil, err
+	}
+	match := matchIdentities(identities, currentTargets(s.backend, currentPaths))
+	var missing []string
+	for _, i := range match.unmatched {
+		missing = append(missing, identities[i].String())
+	}
+	return missing, nil
+}
@@ -0,0 +1,95 @@
+package switcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor-profile-switcher/internal/ccd"
+	"monitor-profile-switcher/internal/ccdsim"
+)
+
+// hotplug connects a target once the display configuration has been
+// queried a number of times, like a monitor that wakes up while
+// LoadProfile waits for it.
+type hotplug struct {
+	*ccdsim.Machine
+	adapter ccd.LUID
+	target  uint32
+	after   int
+	queries int
+}
+
+func (h *hotplug) GetDisplayConfigBufferSizes(flags ccd.QueryDisplayFlags, numPaths *uint32, numModes *uint32) uint32 {
+	if h.queries++; h.queries > h.after {
+		h.Machine.SetPresent(h.adapter, h.target, true)
+	}
+	return h.Machine.GetDisplayConfigBufferSizes(flags, numPaths, numModes)
+}
+
+func TestLoadWaitsForMonitors(t *testing.T) {
+	adapter := ccd.LUID{LowPart: 1}
+	tests := []struct {
+		name string
+		// after is the number of queries that find target 200 missing.
+		after   int
+		wait    time.Duration
+		wantErr error
+		// wantOutput are substrings of the progress output.
+		wantOutput []string
+	}{
+		{
+			name: "already connected",
+			wait: time.Second,
+		},
+		{
+			name:       "connected after two polls",
+			after:      2,
+			wait:       10 * time.Second,
+			wantOutput: []string{"1 of 2 monitors not connected: DELL P2419H (target id 200)", "All 2 monitors connected after"},
+		},
+		{
+			name:       "timeout",
+			after:      1 << 30,
+			wait:       50 * time.Millisecond,
+			wantErr:    ErrWaitTimeout,
+			wantOutput: []string{"Waiting up to"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := twin(1, 100, 200, true)
+			path := saveProfile(t, m, SaveOptions{})
+			backend := &hotplug{Machine: m, adapter: adapter, target: 200, after: tt.after}
+			if tt.after != 0 {
+				m.SetPresent(adapter, 200, false)
+			}
+
+			var out strings.Builder
+			err := New(backend).LoadProfile(path, LoadOptions{Wait: tt.wait, Output: &out})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LoadProfile() = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output %q does not contain %q", out.String(), want)
+				}
+			}
+			if len(tt.wantOutput) == 0 && out.Len() != 0 {
+				t.Errorf("unexpected output %q", out.String())
+			}
+			if tt.wantErr != nil {
+				if m.SetCalls() != 0 {
+					t.Errorf("%d SetDisplayConfig calls after the wait timed out", m.SetCalls())
+				}
+				return
+			}
+			want := map[uint32]int32{100: 0, 200: 1920}
+			if got := layout(m); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("layout = %v, want %v", got, want)
+			}
+		})
+	}
+}